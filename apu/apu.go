@@ -1,8 +1,6 @@
 package apu
 
 import (
-	"fmt"
-
 	"github.com/thara/gorones/util"
 )
 
@@ -10,6 +8,23 @@ type APU struct {
 	sampleRate  uint
 	framePeriod uint
 
+	// left and right, where only left is used for mono
+	blip [2]blipBuffer
+	// the last output of the channels put into blip
+	levels [5]uint8
+	level  [2]float32
+
+	mixer Mixer
+
+	// output of each channel for ChannelRenderer
+	channels   ChannelRenderer
+	stems      [channelCount]blipBuffer
+	stemLevels [channelCount]float32
+	stemOut    [channelCount]float32
+
+	filterConfig []Filter
+	filters      [2][]filterState
+
 	pulse1   pulseChannel
 	pulse2   pulseChannel
 	triangle triangleChannel
@@ -22,28 +37,83 @@ type APU struct {
 	frameSequenceStep   int
 	frameInterrupted    bool
 
-	audio AudioRenderer
+	audio     AudioRenderer
+	stereo    StereoAudioRenderer
+	expansion ExpansionAudio
 }
 
+const defaultSampleRate uint = 44100
+
+// NTSC CPU clock is 236.25 MHz / 11 / 12, which is CPUClockNum / CPUClockDen Hz.
+const (
+	CPUClockNum = 236_250_000
+	CPUClockDen = 132
+)
+
+// CPUClock is NTSC CPU clock in Hz.
+const CPUClock = float64(CPUClockNum) / CPUClockDen
+
 func New(audio AudioRenderer) *APU {
-	const (
-		samplingFrequency uint = 1_789_772
-		downSamplingRate  uint = 44100
-	)
-	return &APU{
-		sampleRate:  samplingFrequency / downSamplingRate,
+	a := &APU{
+		sampleRate:  defaultSampleRate,
 		framePeriod: 7458,
 		audio:       audio,
+		mixer:       newMixer(),
 		pulse1:      pulseChannel{carryMode: sweepOneComplement},
 		pulse2:      pulseChannel{carryMode: sweepTwoComplement},
 		noise:       noiseChannel{shiftRegister: 1},
 	}
+	a.stereo, _ = audio.(StereoAudioRenderer)
+	a.SetSampleRate(defaultSampleRate)
+	a.SetFilters(NESFilters...)
+	return a
+}
+
+// SampleRate returns the number of samples written into AudioRenderer per second.
+func (a *APU) SampleRate() float64 {
+	return float64(a.sampleRate)
+}
+
+// SetSampleRate changes the number of samples written into AudioRenderer per second.
+func (a *APU) SetSampleRate(rate uint) {
+	a.sampleRate = rate
+	for i := range a.blip {
+		a.blip[i] = newBlipBuffer(CPUClockNum, CPUClockDen, uint64(rate))
+	}
+	a.level = [2]float32{}
+	a.resetStems()
+	a.SetFilters(a.filterConfig...)
 }
 
 type AudioRenderer interface {
 	Write(float32)
 }
 
+// StereoAudioRenderer is an AudioRenderer which receives stereo samples by WriteStereo instead of Write.
+type StereoAudioRenderer interface {
+	AudioRenderer
+	WriteStereo(left, right float32)
+}
+
+// ExpansionAudio is a sound source on a cartridge, which is mixed with APU output.
+type ExpansionAudio interface {
+	// Sample returns the current output, scaled to the same level as APU output.
+	Sample() float32
+}
+
+// SetRenderer changes the renderer of the audio output.
+func (a *APU) SetRenderer(audio AudioRenderer) {
+	a.audio = audio
+	a.stereo, _ = audio.(StereoAudioRenderer)
+	// mix again for the number of channels
+	a.mixer.changed = true
+}
+
+// SetExpansionAudio connects the expansion audio to be mixed.
+func (a *APU) SetExpansionAudio(e ExpansionAudio) {
+	a.expansion = e
+}
+
 func (a *APU) frameSequenceMode() frameSequenceMode {
 	if util.IsSet(a.frameCounterControl, 7) {
 		return frameSequenceMode5Step
@@ -56,10 +126,6 @@ func (a *APU) frameInterruptInhibit() bool { return util.IsSet(a.frameCounterCon
 func (a *APU) Step(dmcMemoryReader DMCMemoryReader) bool {
 	a.cycles += 1
 
-	if a.cycles%a.sampleRate == 0 {
-		a.audio.Write(a.sample())
-	}
-
 	var cpuStall = false
 	if a.cycles%2 == 0 {
 		a.pulse1.clockTimer()
@@ -112,47 +178,78 @@ func (a *APU) Step(dmcMemoryReader DMCMemoryReader) bool {
 			a.frameSequenceStep = (a.frameSequenceStep + 1) % 5
 		}
 
-		if a.dmc.interrupted {
-			a.frameInterrupted = true
+	}
+
+	a.updateLevel()
+	if l, ok := a.blip[0].clock(); ok {
+		if a.stereo != nil {
+			r, _ := a.blip[1].clock()
+			a.stereo.WriteStereo(a.filter(0, l), a.filter(1, r))
+		} else {
+			a.audio.Write(a.filter(0, l))
+		}
+	} else if a.stereo != nil {
+		a.blip[1].clock()
+	}
+	if a.channels != nil {
+		var ok bool
+		for i := range a.stems {
+			a.stemOut[i], ok = a.stems[i].clock()
+		}
+		if ok {
+			a.channels.WriteChannels(a.stemOut[:])
 		}
 	}
 
 	return cpuStall
 }
 
-func (a *APU) sample() float32 {
-	p1 := float32(a.pulse1.output())
-	p2 := float32(a.pulse2.output())
-	triangle := float32(a.triangle.output())
-	noise := float32(a.noise.output())
-	dmc := float32(a.dmc.output())
-	// for debug
-	// triangle = 0
-	// noise = 0
-	// dmc = 0
-
-	// if 0 < triangle && 100000 < a.cycles && a.cycles < 120000 {
-	// 	fmt.Println(a.cycles, triangle)
-	// }
-	// fmt.Println(a.cycles, p1, p2, triangle)
-
-	var pulseOut float32
-	if p1 != 0.0 || p2 != 0.0 {
-		pulseOut = 95.88 / ((8128.0 / (p1 + p2)) + 100.0)
-	} else {
-		pulseOut = 0.0
+// updateLevel puts the change of output into blip buffer at this cycle.
+func (a *APU) updateLevel() {
+	levels := [5]uint8{a.pulse1.output(), a.pulse2.output(), a.triangle.output(), a.noise.output(), a.dmc.output()}
+	if levels == a.levels && a.expansion == nil && !a.mixer.changed {
+		return
 	}
+	a.levels = levels
+	a.mixer.changed = false
 
-	var tndOut float32
-	if triangle != 0.0 || noise != 0.0 || dmc != 0.0 {
-		tndOut = 159.79 / (1/(triangle/8227+noise/12241+dmc/22638) + 100)
-	} else {
-		tndOut = 0.0
+	var expansion float32
+	if a.expansion != nil {
+		expansion = a.expansion.Sample()
+	}
+	l, r := a.mixer.mix(levels, expansion, a.stereo != nil)
+	a.blip[0].addDelta(float64(l - a.level[0]))
+	if a.stereo != nil {
+		a.blip[1].addDelta(float64(r - a.level[1]))
 	}
+	a.level = [2]float32{l, r}
 
-	return pulseOut + tndOut
+	if a.channels != nil {
+		out := channelOutputs(levels, expansion)
+		for i, v := range out {
+			a.stems[i].addDelta(float64(v - a.stemLevels[i]))
+		}
+		a.stemLevels = out
+	}
 }
 
+// https://www.nesdev.org/wiki/APU_Mixer#Lookup_Table
+
+var (
+	pulseTable = func() (t [31]float32) {
+		for n := 1; n < len(t); n++ {
+			t[n] = 95.52 / (8128.0/float32(n) + 100)
+		}
+		return
+	}()
+	tndTable = func() (t [203]float32) {
+		for n := 1; n < len(t); n++ {
+			t[n] = 163.67 / (24329.0/float32(n) + 100)
+		}
+		return
+	}()
+)
+
 func (a *APU) Reset() {
 	a.Write(0x4017, 0) // frame irq enabled
 	a.Write(0x4015, 0) // all channels disabled
@@ -165,6 +262,11 @@ func (a *APU) Reset() {
 	}
 }
 
+// IRQ reports whether the frame counter or DMC is asserting IRQ.
+func (a *APU) IRQ() bool {
+	return a.frameInterrupted && !a.frameInterruptInhibit() || a.dmc.interrupted
+}
+
 func (a *APU) Read(addr uint16) uint8 {
 	switch addr {
 	case 0x4015:
@@ -193,7 +295,6 @@ func (a *APU) Read(addr uint16) uint8 {
 
 		a.frameInterrupted = false
 
-		fmt.Printf("%d read %04x, %08b\n", a.cycles, addr, value)
 		return value
 	default:
 		return 0x00
@@ -224,8 +325,12 @@ func (a *APU) Write(addr uint16, value uint8) {
 		a.noise.setEnabled(value&8 == 8)
 
 		a.dmc.enabled = value&16 == 16
+		a.dmc.interrupted = false
 	case addr == 0x4017:
 		a.frameCounterControl = value
+		if a.frameInterruptInhibit() {
+			a.frameInterrupted = false
+		}
 	default:
 		break
 	}
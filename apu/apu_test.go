@@ -0,0 +1,27 @@
+package apu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPU_IRQ(t *testing.T) {
+	a := New(nil)
+
+	a.frameInterrupted = true
+	assert.True(t, a.IRQ())
+	a.Write(0x4017, 0x40)
+	assert.False(t, a.IRQ(), "acknowledged by inhibit")
+
+	a.dmc.interrupted = true
+	assert.True(t, a.IRQ())
+	a.Write(0x4015, 0x00)
+	assert.False(t, a.IRQ(), "acknowledged by $4015")
+
+	a.dmc.interrupted = true
+	a.Write(0x4010, 0x80)
+	assert.True(t, a.IRQ())
+	a.Write(0x4010, 0x00)
+	assert.False(t, a.IRQ(), "IRQ disabled")
+}
@@ -0,0 +1,99 @@
+package apu
+
+import "math"
+
+// Band-limited step synthesis in the manner of blip_buffer.
+//
+// Each amplitude change is added as a windowed-sinc impulse at its exact fractional position between output samples,
+// and the output is the running sum of the impulses, so that harmonics above the Nyquist frequency are not aliased.
+// http://www.slack.net/~ant/bl-synth/
+
+const (
+	blipPhases = 64
+	blipWidth  = 32
+	// cutoff frequency relative to output sample rate
+	blipCutoff = 0.45
+
+	// must be a power of 2 larger than blipWidth
+	blipBufferSize = 64
+)
+
+// blipKernel is the differences of a band-limited step between samples for each phase.
+var blipKernel = func() (k [blipPhases][blipWidth]float64) {
+	// windowed sinc impulse at t samples from the step delayed to the center of the kernel
+	impulse := func(t float64) float64 {
+		x := 2 * blipCutoff * t
+		sinc := 1.0
+		if x != 0 {
+			sinc = math.Sin(math.Pi*x) / (math.Pi * x)
+		}
+		// Blackman window
+		w := (t + blipWidth/2 + 1) / (blipWidth + 1)
+		return sinc * (0.42 - 0.5*math.Cos(2*math.Pi*w) + 0.08*math.Cos(4*math.Pi*w))
+	}
+
+	const steps = 16
+	for p := range k {
+		var sum float64
+		for i := range k[p] {
+			// integral of the impulse between samples i-1 and i
+			start := float64(i-blipWidth/2-1) - float64(p)/blipPhases
+			for j := 0; j < steps; j++ {
+				k[p][i] += impulse(start + (float64(j)+0.5)/steps)
+			}
+			sum += k[p][i]
+		}
+		// a step must reach exactly to the delta
+		for i := range k[p] {
+			k[p][i] /= sum
+		}
+	}
+	return
+}()
+
+// blipBuffer resamples amplitude deltas at clock resolution to output samples at an arbitrary rate.
+type blipBuffer struct {
+	// output samples per clock is sampleRate * clockDen / clockNum exactly
+	clockNum, step uint64
+	// fractional position of the current clock in the current sample, in 1/clockNum
+	frac uint64
+
+	buf [blipBufferSize]float64
+	// index of the current sample
+	pos int
+
+	out float64
+}
+
+func newBlipBuffer(clockNum, clockDen, sampleRate uint64) blipBuffer {
+	return blipBuffer{clockNum: clockNum, step: sampleRate * clockDen}
+}
+
+// addDelta adds an amplitude change at the current clock.
+func (b *blipBuffer) addDelta(delta float64) {
+	if delta == 0 {
+		return
+	}
+	k := &blipKernel[b.frac*blipPhases/b.clockNum]
+	for i, v := range k {
+		b.buf[(b.pos+i)&(blipBufferSize-1)] += delta * v
+	}
+}
+
+// clock advances 1 clock, and returns the completed output sample if any.
+//
+// The output sample rate must be lower than the clock rate.
+func (b *blipBuffer) clock() (float32, bool) {
+	b.frac += b.step
+	if b.frac < b.clockNum {
+		return 0, false
+	}
+	b.frac -= b.clockNum
+
+	// no more deltas are added to the current sample
+	i := b.pos & (blipBufferSize - 1)
+	b.out += b.buf[i]
+	b.buf[i] = 0
+	b.pos++
+	return float32(b.out), true
+}
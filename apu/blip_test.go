@@ -0,0 +1,89 @@
+package apu
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_blipBuffer_rate(t *testing.T) {
+	b := newBlipBuffer(CPUClockNum, CPUClockDen, 44100)
+
+	// 11 seconds are exactly 19687500 clocks
+	var n int
+	for i := 0; i < 19_687_500; i++ {
+		if _, ok := b.clock(); ok {
+			n++
+		}
+	}
+	assert.Equal(t, 11*44100, n)
+}
+
+func Test_blipBuffer_step(t *testing.T) {
+	for _, frac := range []int{0, 13, 29} {
+		b := newBlipBuffer(CPUClockNum, CPUClockDen, 44100)
+		var out []float32
+		for i := 0; i < frac+40*blipWidth*2; i++ {
+			if i == frac {
+				b.addDelta(1)
+			}
+			if v, ok := b.clock(); ok {
+				out = append(out, v)
+			}
+		}
+		assert.InDelta(t, 1, out[len(out)-1], 1e-6)
+		// the step is delayed to the center of the kernel
+		assert.InDelta(t, 0, out[0], 0.01)
+		assert.InDelta(t, 1, out[blipWidth], 0.01)
+	}
+}
+
+// squareWave renders a square wave whose half period is the clocks by band-limited synthesis and naive decimation.
+func squareWave(halfPeriod, samples int) (blip, naive []float64) {
+	b := newBlipBuffer(CPUClockNum, CPUClockDen, 44100)
+	var level float64
+	for i := 0; len(blip) < samples; i++ {
+		if i%halfPeriod == 0 {
+			next := 1 - level
+			b.addDelta(next - level)
+			level = next
+		}
+		if v, ok := b.clock(); ok {
+			blip = append(blip, float64(v))
+			naive = append(naive, level)
+		}
+	}
+	// skip the beginning before the kernel is filled
+	return blip[blipWidth:], naive[blipWidth:]
+}
+
+func Test_blipBuffer_aliasing(t *testing.T) {
+	// 2982.95 Hz, whose 9th harmonic 26846.6 Hz is aliased to 17253.4 Hz by 44100 Hz sampling
+	const halfPeriod = 300
+	f0 := CPUClock / (2 * halfPeriod)
+	alias := 44100 - 9*f0
+
+	blip, naive := squareWave(halfPeriod, 44100)
+
+	db := func(x []float64) float64 {
+		return 10 * math.Log10(power(x, alias/44100)/power(x, f0/44100))
+	}
+	// 1/9 of the fundamental
+	assert.Greater(t, db(naive), -25.0)
+	assert.Less(t, db(blip), -70.0)
+
+	// harmonics below the cutoff are kept
+	assert.InDelta(t, 0, 10*math.Log10(power(blip, 3*f0/44100)/power(naive, 3*f0/44100)), 0.1)
+}
+
+// power returns the power of the signal at the frequency by Goertzel algorithm with Hann window.
+func power(x []float64, freq float64) float64 {
+	w := 2 * math.Pi * freq
+	var s1, s2 float64
+	for i, v := range x {
+		v *= 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(len(x)-1))
+		s1, s2 = v+2*math.Cos(w)*s1-s2, s1
+	}
+	return s1*s1 + s2*s2 - 2*math.Cos(w)*s1*s2
+}
@@ -45,6 +45,9 @@ func (c *dmc) write(addr uint16, value uint8) {
 	case 0x4010:
 		c.flags = value
 		c.irqEnabled = (value>>7)&1 == 1
+		if !c.irqEnabled {
+			c.interrupted = false
+		}
 		c.loopFlag = (value>>6)&1 == 1
 		c.rateIndex = value & 0b1111
 		c.timerPeriod = DMC_TIMER_TABLE[value&0xF] >> 1
@@ -112,7 +115,7 @@ func (c *dmc) clockTimer(memoryReader DMCMemoryReader) bool {
 
 		if !c.silence {
 			if c.shiftRegister&1 == 0 {
-				if c.outputLevel < 1 {
+				if 1 < c.outputLevel {
 					c.outputLevel -= 2
 				}
 			} else {
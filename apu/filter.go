@@ -0,0 +1,84 @@
+package apu
+
+import "math"
+
+// https://www.nesdev.org/wiki/APU_Mixer
+
+// FilterKind is a kind of first-order filter.
+type FilterKind uint8
+
+const (
+	HighPass FilterKind = iota
+	LowPass
+)
+
+// Filter is a first-order filter applied to the output.
+type Filter struct {
+	Kind FilterKind
+	// cutoff frequency in Hz
+	Cutoff float64
+}
+
+var (
+	// NESFilters are filters of NES (front-loader)
+	NESFilters = []Filter{{HighPass, 90}, {HighPass, 440}, {LowPass, 14000}}
+	// FamicomFilters are filters of Famicom, which has only high-pass filter at 37 Hz
+	FamicomFilters = []Filter{{HighPass, 37}}
+
+	// FilterPresets are filters by name, where "none" disables filtering.
+	FilterPresets = map[string][]Filter{
+		"nes":     NESFilters,
+		"famicom": FamicomFilters,
+		"none":    nil,
+	}
+)
+
+type filterState struct {
+	kind  FilterKind
+	alpha float32
+
+	x, y float32
+}
+
+func newFilterState(f Filter, sampleRate float64) filterState {
+	rc := 1 / (2 * math.Pi * f.Cutoff)
+	dt := 1 / sampleRate
+	s := filterState{kind: f.Kind}
+	switch f.Kind {
+	case HighPass:
+		s.alpha = float32(rc / (rc + dt))
+	case LowPass:
+		s.alpha = float32(dt / (rc + dt))
+	}
+	return s
+}
+
+func (s *filterState) step(x float32) float32 {
+	switch s.kind {
+	case HighPass:
+		s.y = s.alpha * (s.y + x - s.x)
+	case LowPass:
+		s.y += s.alpha * (x - s.y)
+	}
+	s.x = x
+	return s.y
+}
+
+// SetFilters changes the filter chain applied to the output in order. No filters disables filtering.
+func (a *APU) SetFilters(filters ...Filter) {
+	a.filterConfig = append([]Filter(nil), filters...)
+	for ch := range a.filters {
+		a.filters[ch] = make([]filterState, len(filters))
+		for i, f := range filters {
+			a.filters[ch][i] = newFilterState(f, float64(a.sampleRate))
+		}
+	}
+}
+
+// filter applies the filter chain of the side to the sample.
+func (a *APU) filter(side int, v float32) float32 {
+	for i := range a.filters[side] {
+		v = a.filters[side][i].step(v)
+	}
+	return v
+}
@@ -0,0 +1,55 @@
+package apu
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_mixerTables(t *testing.T) {
+	assert.Zero(t, pulseTable[0])
+	assert.Zero(t, tndTable[0])
+	// linear approximations by nesdev
+	assert.InDelta(t, 0.00752*30, pulseTable[30], 0.05)
+	assert.InDelta(t, 95.88/(8128.0/30+100), pulseTable[30], 0.002)
+}
+
+// gain returns the amplitude of filtered sine wave at the frequency.
+func gain(filters []Filter, freq float64) float64 {
+	const rate = 44100
+	var out []float32
+	a := New(audioFunc(nil))
+	a.SetFilters(filters...)
+	for i := 0; i < rate; i++ {
+		out = append(out, a.filter(0, float32(math.Sin(2*math.Pi*freq*float64(i)/rate))))
+	}
+	var peak float64
+	// after settled
+	for _, v := range out[rate/2:] {
+		peak = math.Max(peak, math.Abs(float64(v)))
+	}
+	return peak
+}
+
+type audioFunc func(float32)
+
+func (f audioFunc) Write(v float32) { f(v) }
+
+func TestAPU_SetFilters(t *testing.T) {
+	// -3dB at cutoff
+	assert.InDelta(t, math.Sqrt(0.5), gain([]Filter{{HighPass, 440}}, 440), 0.03)
+	assert.InDelta(t, math.Sqrt(0.5), gain([]Filter{{LowPass, 1000}}, 1000), 0.03)
+
+	assert.Less(t, gain(NESFilters, 50), 0.1)
+	assert.Greater(t, gain(FamicomFilters, 50), 0.7)
+	assert.InDelta(t, 1, gain(nil, 50), 0.001)
+
+	// DC is removed by high-pass
+	var out float32
+	a := New(audioFunc(nil))
+	for i := 0; i < 44100; i++ {
+		out = a.filter(0, 1)
+	}
+	assert.InDelta(t, 0, out, 0.001)
+}
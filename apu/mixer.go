@@ -0,0 +1,167 @@
+package apu
+
+// Channel is a sound channel mixed by Mixer.
+type Channel uint8
+
+const (
+	ChannelPulse1 Channel = iota
+	ChannelPulse2
+	ChannelTriangle
+	ChannelNoise
+	ChannelDMC
+	// all channels of the expansion audio on a cartridge
+	ChannelExpansion
+)
+
+const channelCount = 6
+
+// Channels are all channels in mixing order.
+var Channels = []Channel{ChannelPulse1, ChannelPulse2, ChannelTriangle, ChannelNoise, ChannelDMC, ChannelExpansion}
+
+var channelNames = [channelCount]string{"pulse1", "pulse2", "triangle", "noise", "dmc", "expansion"}
+
+func (c Channel) String() string {
+	if int(c) < len(channelNames) {
+		return channelNames[c]
+	}
+	return "unknown"
+}
+
+// Mixer controls mute, solo, volume and pan of each channel.
+type Mixer struct {
+	mute   [channelCount]bool
+	solo   [channelCount]bool
+	volume [channelCount]float32
+	pan    [channelCount]float32
+
+	// whether the output has to be mixed again
+	changed bool
+}
+
+func newMixer() Mixer {
+	m := Mixer{}
+	for i := range m.volume {
+		m.volume[i] = 1
+	}
+	return m
+}
+
+// Mixer returns the mixer of the output.
+func (a *APU) Mixer() *Mixer { return &a.mixer }
+
+// SetMute mutes or unmutes the channel.
+func (m *Mixer) SetMute(c Channel, mute bool) {
+	m.mute[c] = mute
+	m.changed = true
+}
+
+func (m *Mixer) Muted(c Channel) bool { return m.mute[c] }
+
+// SetSolo makes only soloed channels audible if any.
+func (m *Mixer) SetSolo(c Channel, solo bool) {
+	m.solo[c] = solo
+	m.changed = true
+}
+
+func (m *Mixer) Soloed(c Channel) bool { return m.solo[c] }
+
+// SetVolume changes the volume of the channel, where 1 is the original level.
+func (m *Mixer) SetVolume(c Channel, volume float32) {
+	m.volume[c] = volume
+	m.changed = true
+}
+
+func (m *Mixer) Volume(c Channel) float32 { return m.volume[c] }
+
+// SetPan changes the pan of the channel from -1 (left) to 1 (right), which is applied to stereo output only.
+func (m *Mixer) SetPan(c Channel, pan float32) {
+	if pan < -1 {
+		pan = -1
+	} else if 1 < pan {
+		pan = 1
+	}
+	m.pan[c] = pan
+	m.changed = true
+}
+
+func (m *Mixer) Pan(c Channel) float32 { return m.pan[c] }
+
+// Audible reports whether the channel is mixed into the output.
+func (m *Mixer) Audible(c Channel) bool {
+	if m.solo != [channelCount]bool{} {
+		return m.solo[c]
+	}
+	return !m.mute[c]
+}
+
+// mix returns the left and right output of the channel levels, which are the same if not stereo.
+func (m *Mixer) mix(levels [5]uint8, expansion float32, stereo bool) (float32, float32) {
+	for i := range levels {
+		if !m.Audible(Channel(i)) {
+			levels[i] = 0
+		}
+	}
+	if !m.Audible(ChannelExpansion) {
+		expansion = 0
+	}
+
+	var l, r float32
+	for c, v := range channelOutputs(levels, expansion) {
+		v *= m.volume[c]
+		if !stereo {
+			l += v
+			continue
+		}
+		// balance which keeps the level of the center same as mono
+		pan := m.pan[c]
+		if pan < 0 {
+			l += v
+			r += v * (1 + pan)
+		} else {
+			l += v * (1 - pan)
+			r += v
+		}
+	}
+	if !stereo {
+		return l, l
+	}
+	return l, r
+}
+
+// channelOutputs returns the share of each channel in the nonlinear output of its group.
+func channelOutputs(levels [5]uint8, expansion float32) (out [channelCount]float32) {
+	if n := int(levels[0]) + int(levels[1]); 0 < n {
+		g := pulseTable[n] / float32(n)
+		out[ChannelPulse1] = g * float32(levels[0])
+		out[ChannelPulse2] = g * float32(levels[1])
+	}
+	if n := 3*int(levels[2]) + 2*int(levels[3]) + int(levels[4]); 0 < n {
+		g := tndTable[n] / float32(n)
+		out[ChannelTriangle] = g * 3 * float32(levels[2])
+		out[ChannelNoise] = g * 2 * float32(levels[3])
+		out[ChannelDMC] = g * float32(levels[4])
+	}
+	out[ChannelExpansion] = expansion
+	return
+}
+
+// ChannelRenderer receives the output of each channel before mixing, indexed by Channel.
+type ChannelRenderer interface {
+	WriteChannels(samples []float32)
+}
+
+// SetChannelRenderer sets the renderer of each channel output, which is free from mixer settings and filters.
+// nil stops rendering.
+func (a *APU) SetChannelRenderer(r ChannelRenderer) {
+	a.channels = r
+	a.resetStems()
+}
+
+func (a *APU) resetStems() {
+	for i := range a.stems {
+		a.stems[i] = newBlipBuffer(CPUClockNum, CPUClockDen, uint64(a.sampleRate))
+	}
+	a.stemLevels = [channelCount]float32{}
+	// mix again to put the current levels
+	a.mixer.changed = true
+}
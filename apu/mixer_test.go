@@ -0,0 +1,79 @@
+package apu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMixer(t *testing.T) {
+	levels := [5]uint8{15, 10, 8, 4, 64}
+	full := pulseTable[25] + tndTable[3*8+2*4+64]
+
+	m := newMixer()
+	l, r := m.mix(levels, 0.1, false)
+	assert.InDelta(t, full+0.1, l, 1e-6)
+	assert.Equal(t, l, r)
+
+	// muted channel is removed from the nonlinear mix
+	m.SetMute(ChannelPulse1, true)
+	m.SetMute(ChannelExpansion, true)
+	assert.False(t, m.Audible(ChannelPulse1))
+	l, _ = m.mix(levels, 0.1, false)
+	assert.InDelta(t, pulseTable[10]+tndTable[3*8+2*4+64], l, 1e-6)
+
+	// solo precedes mute
+	m.SetSolo(ChannelPulse1, true)
+	assert.True(t, m.Audible(ChannelPulse1))
+	assert.False(t, m.Audible(ChannelPulse2))
+	l, _ = m.mix(levels, 0.1, false)
+	assert.InDelta(t, pulseTable[15], l, 1e-6)
+
+	m.SetVolume(ChannelPulse1, 0.5)
+	m.SetPan(ChannelPulse1, -2)
+	assert.EqualValues(t, -1, m.Pan(ChannelPulse1))
+	l, r = m.mix(levels, 0.1, true)
+	assert.InDelta(t, pulseTable[15]/2, l, 1e-6)
+	assert.Zero(t, r)
+}
+
+type stereoAudio struct {
+	mono        int
+	left, right []float32
+}
+
+func (a *stereoAudio) Write(float32) { a.mono++ }
+
+func (a *stereoAudio) WriteStereo(l, r float32) {
+	a.left = append(a.left, l)
+	a.right = append(a.right, r)
+}
+
+func TestAPU_stereo(t *testing.T) {
+	audio := &stereoAudio{}
+	a := New(audio)
+	a.SetFilters()
+	a.Mixer().SetPan(ChannelPulse1, 1)
+
+	a.Write(0x4015, 0x01)
+	a.Write(0x4000, 0xBF) // 50%, constant volume 15
+	a.Write(0x4002, 0xFD)
+	a.Write(0x4003, 0x00)
+	for i := 0; i < 17898; i++ {
+		a.Step(nil)
+	}
+	assert.Zero(t, audio.mono)
+	assert.InDelta(t, 441, len(audio.left), 1)
+
+	var l, r float32
+	for i := range audio.left {
+		if l < audio.left[i] {
+			l = audio.left[i]
+		}
+		if r < audio.right[i] {
+			r = audio.right[i]
+		}
+	}
+	assert.InDelta(t, 0, l, 0.01)
+	assert.InDelta(t, pulseTable[15], r, 0.02)
+}
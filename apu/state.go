@@ -0,0 +1,98 @@
+package apu
+
+import "github.com/thara/gorones/state"
+
+// Serialize saves or loads the state of channels and frame counter, but not the audio output and mixer settings.
+func (a *APU) Serialize(s *state.Serializer) {
+	s.Section("APU")
+	a.pulse1.serialize(s)
+	a.pulse2.serialize(s)
+	a.triangle.serialize(s)
+	a.noise.serialize(s)
+	a.dmc.serialize(s)
+
+	s.Uint(&a.cycles)
+	s.Uint8(&a.frameCounterControl)
+	s.Int(&a.frameSequenceStep)
+	s.Bool(&a.frameInterrupted)
+
+	if s.Loading() {
+		// mix again to put the loaded levels
+		a.mixer.changed = true
+	}
+}
+
+func (c *pulseChannel) serialize(s *state.Serializer) {
+	s.Bool(&c.enabled)
+	s.Uint8(&c.volume)
+	s.Uint8(&c.dutyCycle)
+	s.Bool(&c.envelopeLoop)
+	s.Bool(&c.useConstantVolume)
+	s.Uint8(&c.envelopePeriod)
+	s.Bool(&c.sweepEnabled)
+	s.Uint8(&c.sweepPeriod)
+	s.Bool(&c.sweepNegate)
+	s.Uint8(&c.sweepShift)
+	s.Uint8(&c.lengthCounter)
+	s.Bool(&c.lengthCounterHalt)
+	s.Uint16(&c.timerCounter)
+	s.Int(&c.timerSequencer)
+	s.Uint16(&c.timerPeriod)
+	s.Uint8(&c.envelopeCounter)
+	s.Uint8(&c.envelopeDecayLevelCounter)
+	s.Bool(&c.envelopeStart)
+	s.Uint8(&c.sweepCounter)
+	s.Bool(&c.sweepReload)
+}
+
+func (c *triangleChannel) serialize(s *state.Serializer) {
+	s.Bool(&c.enabled)
+	s.Bool(&c.controlFlag)
+	s.Uint8(&c.linearCounterReload)
+	s.Uint16(&c.timerPeriod)
+	s.Bool(&c.linearCounterReloadFlag)
+	s.Uint16(&c.timerCounter)
+	s.Uint8(&c.sequencer)
+	s.Uint8(&c.linearCounter)
+	s.Uint8(&c.lengthCounter)
+	s.Bool(&c.lengthCounterHalt)
+}
+
+func (c *noiseChannel) serialize(s *state.Serializer) {
+	s.Bool(&c.enabled)
+	s.Bool(&c.envelopeLoop)
+	s.Bool(&c.useConstantVolume)
+	s.Uint8(&c.envelopePeriod)
+	s.Bool(&c.modeFlag)
+	s.Uint8(&c.envelopeCounter)
+	s.Uint8(&c.envelopeDecayLevelCounter)
+	s.Bool(&c.envelopeStart)
+	s.Uint16(&c.shiftRegister)
+	s.Uint16(&c.timerCounter)
+	s.Uint16(&c.timerPeriod)
+	s.Uint8(&c.lengthCounter)
+	s.Bool(&c.lengthCounterHalt)
+}
+
+func (c *dmc) serialize(s *state.Serializer) {
+	s.Bool(&c.enabled)
+	s.Uint8(&c.flags)
+	s.Bool(&c.irqEnabled)
+	s.Bool(&c.loopFlag)
+	s.Uint8(&c.rateIndex)
+	s.Uint8(&c.direct)
+	s.Uint8(&c.address)
+	s.Uint8(&c.length)
+	s.Uint16(&c.timerCounter)
+	s.Uint16(&c.timerPeriod)
+	s.Uint8(&c.remainingBytesCounter)
+	s.Uint8(&c.sampleBuffer)
+	s.Uint16(&c.addressCounter)
+	s.Uint16(&c.bytesRemainingCounter)
+	s.Uint8(&c.outputLevel)
+	s.Bool(&c.silence)
+	s.Bool(&c.sampleBufferEmpty)
+	s.Uint8(&c.shiftRegister)
+	s.Uint8(&c.remainingBitsCounter)
+	s.Bool(&c.interrupted)
+}
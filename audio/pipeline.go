@@ -0,0 +1,133 @@
+package audio
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// maxRateDelta is the maximum adjustment of resampling ratio, which is inaudible as pitch change.
+//
+// https://github.com/libretro/docs/blob/master/archive/ratecontrol.pdf
+const maxRateDelta = 0.005
+
+// Pipeline resamples samples from the emulator into a ring buffer read by an audio device.
+//
+// The resampling ratio is adjusted by the fill level of the buffer to keep the latency at the target,
+// absorbing the difference of clocks between the emulator and the audio device.
+type Pipeline struct {
+	// stats accessed atomically
+	underruns uint64
+	overflows uint64
+	ratioBits uint64
+
+	ring *Ring
+
+	outputRate float64
+	target     float64 // frames
+	// input samples per output frame without adjustment
+	baseStep float64
+
+	// resampler state owned by the producer
+	pos        float64
+	prev, last Frame
+}
+
+// Stats is the statistics of a pipeline.
+type Stats struct {
+	// audio buffered in the pipeline
+	Latency time.Duration
+	// the number of reads from the device with insufficient frames
+	Underruns uint64
+	// the number of frames dropped because the buffer was full
+	Overflows uint64
+	// the current resampling ratio relative to nominal
+	Ratio float64
+}
+
+// NewPipeline creates a pipeline converting samples at inputRate into outputRate, which targets the latency.
+func NewPipeline(inputRate, outputRate float64, latency time.Duration) *Pipeline {
+	target := latency.Seconds() * outputRate
+	p := &Pipeline{
+		// room to absorb jitter over the target
+		ring:       NewRing(int(target * 4)),
+		outputRate: outputRate,
+		target:     target,
+		baseStep:   inputRate / outputRate,
+	}
+	atomic.StoreUint64(&p.ratioBits, math.Float64bits(1))
+	return p
+}
+
+// Write writes a mono sample from the emulator.
+func (p *Pipeline) Write(v float32) {
+	p.WriteStereo(v, v)
+}
+
+// WriteStereo writes a stereo sample from the emulator.
+func (p *Pipeline) WriteStereo(l, r float32) {
+	cur := Frame{l, r}
+	step := p.baseStep * p.adjustment()
+	// output frames between the previous and the current input sample by linear interpolation
+	for p.pos < 1 {
+		var f Frame
+		for i := range f {
+			f[i] = p.prev[i] + (cur[i]-p.prev[i])*float32(p.pos)
+		}
+		if !p.ring.Push(f) {
+			atomic.AddUint64(&p.overflows, 1)
+		}
+		p.pos += step
+	}
+	p.pos -= 1
+	p.prev = cur
+}
+
+// adjustment returns the ratio of the input consumed per output frame, which increases if the buffer is over the target.
+func (p *Pipeline) adjustment() float64 {
+	d := (float64(p.ring.Len()) - p.target) / p.target
+	if d < -1 {
+		d = -1
+	} else if 1 < d {
+		d = 1
+	}
+	ratio := 1 + maxRateDelta*d
+	atomic.StoreUint64(&p.ratioBits, math.Float64bits(ratio))
+	return ratio
+}
+
+// Read fills out with interleaved frames for the channels, which is called by an audio device.
+//
+// Only first 2 channels have samples, and the last frame is repeated on underrun.
+func (p *Pipeline) Read(out []float32, channels int) {
+	underrun := false
+	for i := 0; i+channels <= len(out); i += channels {
+		f, ok := p.ring.Pop()
+		if ok {
+			p.last = f
+		} else {
+			underrun = true
+			f = p.last
+		}
+		out[i] = f[0]
+		for c := 1; c < channels; c++ {
+			out[i+c] = 0
+		}
+		if 1 < channels {
+			out[i+1] = f[1]
+		}
+	}
+	if underrun {
+		atomic.AddUint64(&p.underruns, 1)
+	}
+}
+
+// Stats returns the current statistics.
+func (p *Pipeline) Stats() Stats {
+	return Stats{
+		Latency:   time.Duration(float64(p.ring.Len()) / p.outputRate * float64(time.Second)),
+		Underruns: atomic.LoadUint64(&p.underruns),
+		Overflows: atomic.LoadUint64(&p.overflows),
+		Ratio:     math.Float64frombits(atomic.LoadUint64(&p.ratioBits)),
+	}
+}
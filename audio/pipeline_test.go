@@ -0,0 +1,53 @@
+package audio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipeline_rate(t *testing.T) {
+	p := NewPipeline(44100, 48000, 100*time.Millisecond)
+
+	// filled up to the target
+	for i := 0; i < 4410; i++ {
+		p.Write(0.5)
+	}
+	s := p.Stats()
+	assert.InDelta(t, 100*time.Millisecond, s.Latency, float64(time.Millisecond))
+	assert.InDelta(t, 1, s.Ratio, 0.001)
+
+	out := make([]float32, 2*480)
+	p.Read(out, 2)
+	assert.EqualValues(t, 0.5, out[len(out)-2])
+	assert.EqualValues(t, 0.5, out[len(out)-1])
+	assert.Zero(t, p.Stats().Underruns)
+}
+
+func TestPipeline_rateControl(t *testing.T) {
+	p := NewPipeline(44100, 44100, 50*time.Millisecond)
+	out := make([]float32, 441)
+
+	// the emulator runs faster than the device
+	for i := 0; i < 300; i++ {
+		for j := 0; j < 450; j++ {
+			p.WriteStereo(0, 0)
+		}
+		p.Read(out, 1)
+	}
+	s := p.Stats()
+	assert.Greater(t, s.Ratio, 1.0)
+	assert.Less(t, s.Ratio, 1+maxRateDelta+1e-9)
+	assert.Zero(t, s.Overflows)
+
+	// buffer is drained
+	before := s.Underruns
+	for i := 0; i < 100; i++ {
+		p.Read(out, 1)
+	}
+	s = p.Stats()
+	assert.Greater(t, s.Underruns, before)
+	assert.Zero(t, s.Latency)
+	assert.InDelta(t, 1-maxRateDelta, func() float64 { p.Write(0); return p.Stats().Ratio }(), 1e-6)
+}
@@ -0,0 +1,141 @@
+package audio
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/thara/gorones/apu"
+)
+
+// Recorder is an audio renderer which records samples into WAV while passing them through to the next renderer.
+//
+// It also records the output of each channel into separate WAV files as stems if Stems is set,
+// which requires to be set as apu.ChannelRenderer.
+type Recorder struct {
+	next   apu.AudioRenderer
+	stereo apu.StereoAudioRenderer
+
+	sampleRate int
+	// format of files recorded after this is changed
+	Format WAVFormat
+	// whether to record stems on Start
+	Stems bool
+
+	mix   *wavFile
+	stems []*wavFile
+	err   error
+}
+
+type wavFile struct {
+	f *os.File
+	w *WAVWriter
+}
+
+func createWAV(path string, sampleRate, channels int, format WAVFormat) (*wavFile, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s", path)
+	}
+	w, err := NewWAVWriter(f, sampleRate, channels, format)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &wavFile{f: f, w: w}, nil
+}
+
+func (f *wavFile) close() error {
+	err := f.w.Close()
+	if cerr := f.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// NewRecorder creates a recorder passing samples through to next, which may be nil.
+func NewRecorder(next apu.AudioRenderer, sampleRate int) *Recorder {
+	r := &Recorder{next: next, sampleRate: sampleRate}
+	r.stereo, _ = next.(apu.StereoAudioRenderer)
+	return r
+}
+
+// StemPath returns the path of the stem for the channel, like "out.pulse1.wav" for "out.wav".
+func StemPath(path string, c apu.Channel) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + c.String() + ext
+}
+
+// Start starts recording into the path in stereo.
+func (r *Recorder) Start(path string) error {
+	if r.Recording() {
+		return errors.New("already recording")
+	}
+	mix, err := createWAV(path, r.sampleRate, 2, r.Format)
+	if err != nil {
+		return err
+	}
+	r.mix, r.err = mix, nil
+
+	if r.Stems {
+		for _, c := range apu.Channels {
+			stem, err := createWAV(StemPath(path, c), r.sampleRate, 1, r.Format)
+			if err != nil {
+				r.Stop()
+				return err
+			}
+			r.stems = append(r.stems, stem)
+		}
+	}
+	return nil
+}
+
+// Recording reports whether it's recording.
+func (r *Recorder) Recording() bool { return r.mix != nil }
+
+// Stop stops recording and completes the files, returning the first error while recording if any.
+func (r *Recorder) Stop() error {
+	if r.mix == nil {
+		return nil
+	}
+	err := r.err
+	for _, f := range append([]*wavFile{r.mix}, r.stems...) {
+		if cerr := f.close(); err == nil {
+			err = cerr
+		}
+	}
+	r.mix, r.stems = nil, nil
+	return err
+}
+
+func (r *Recorder) Write(v float32) {
+	r.WriteStereo(v, v)
+}
+
+func (r *Recorder) WriteStereo(left, right float32) {
+	switch {
+	case r.stereo != nil:
+		r.stereo.WriteStereo(left, right)
+	case r.next != nil:
+		r.next.Write((left + right) / 2)
+	}
+	if r.mix != nil && r.err == nil {
+		r.err = r.mix.w.WriteFrame(left, right)
+	}
+}
+
+// WriteChannels records the output of each channel as apu.ChannelRenderer.
+func (r *Recorder) WriteChannels(samples []float32) {
+	if r.err != nil {
+		return
+	}
+	for i, f := range r.stems {
+		if i < len(samples) {
+			if err := f.w.WriteFrame(samples[i]); err != nil {
+				r.err = err
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thara/gorones/apu"
+)
+
+type countAudio struct{ n int }
+
+func (a *countAudio) Write(float32) { a.n++ }
+
+// wavSamples returns float samples of WAV.
+func wavSamples(t *testing.T, path string) []float32 {
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Less(t, wavHeaderSize-1, len(b))
+	var s []float32
+	for i := wavHeaderSize; i+4 <= len(b); i += 4 {
+		s = append(s, math.Float32frombits(binary.LittleEndian.Uint32(b[i:])))
+	}
+	return s
+}
+
+func TestRecorder(t *testing.T) {
+	next := &countAudio{}
+	r := NewRecorder(next, 44100)
+	r.Format = WAVFloat32
+	r.Stems = true
+
+	a := apu.New(r)
+	a.SetChannelRenderer(r)
+	a.SetFilters()
+	// pulse1 and triangle
+	a.Write(0x4015, 0x05)
+	a.Write(0x4000, 0xBF)
+	a.Write(0x4002, 0xFD)
+	a.Write(0x4003, 0x00)
+	a.Write(0x4008, 0xFF)
+	a.Write(0x400A, 0x7F)
+	a.Write(0x400B, 0x00)
+
+	path := filepath.Join(t.TempDir(), "out.wav")
+	for i := 0; i < 4000; i++ {
+		a.Step(nil)
+	}
+	require.NoError(t, r.Start(path))
+	assert.True(t, r.Recording())
+	assert.Error(t, r.Start(path))
+	for i := 0; i < 17898; i++ {
+		a.Step(nil)
+	}
+	require.NoError(t, r.Stop())
+	assert.False(t, r.Recording())
+	for i := 0; i < 4000; i++ {
+		a.Step(nil)
+	}
+
+	// passed through as mono
+	assert.InDelta(t, (4000*2+17898)*44100/1789773, next.n, 2)
+
+	mix := wavSamples(t, path)
+	assert.InDelta(t, 2*441, len(mix), 2)
+
+	stems := map[apu.Channel][]float32{}
+	for _, c := range apu.Channels {
+		stems[c] = wavSamples(t, StemPath(path, c))
+		assert.Len(t, stems[c], len(mix)/2, c.String())
+	}
+	peak := func(s []float32) (p float32) {
+		for _, v := range s {
+			if p < v {
+				p = v
+			}
+		}
+		return
+	}
+	assert.Greater(t, peak(stems[apu.ChannelPulse1]), float32(0.1))
+	assert.Greater(t, peak(stems[apu.ChannelTriangle]), float32(0.1))
+	assert.Zero(t, peak(stems[apu.ChannelNoise]))
+
+	// stems are summed up to the mix
+	for i := 0; i < len(mix)/2; i += 10 {
+		var sum float32
+		for _, c := range apu.Channels {
+			sum += stems[c][i]
+		}
+		assert.InDelta(t, mix[i*2], sum, 1e-4)
+	}
+}
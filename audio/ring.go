@@ -0,0 +1,55 @@
+// Package audio provides the pipeline of audio samples from the emulator to an audio device.
+package audio
+
+import "sync/atomic"
+
+// Frame is a stereo sample.
+type Frame [2]float32
+
+// Ring is a lock-free ring buffer of frames for a single producer and a single consumer.
+type Ring struct {
+	// positions increased monotonically, accessed atomically
+	read, write uint64
+
+	buf  []Frame
+	mask uint64
+}
+
+// NewRing creates a ring buffer which holds frames at least the size.
+func NewRing(size int) *Ring {
+	n := 1
+	for n < size {
+		n <<= 1
+	}
+	return &Ring{buf: make([]Frame, n), mask: uint64(n - 1)}
+}
+
+// Len returns the number of frames in the buffer.
+func (r *Ring) Len() int {
+	return int(atomic.LoadUint64(&r.write) - atomic.LoadUint64(&r.read))
+}
+
+// Cap returns the capacity of the buffer.
+func (r *Ring) Cap() int { return len(r.buf) }
+
+// Push adds the frame, and returns false if the buffer is full. Only a producer can call it.
+func (r *Ring) Push(f Frame) bool {
+	w := atomic.LoadUint64(&r.write)
+	if w-atomic.LoadUint64(&r.read) == uint64(len(r.buf)) {
+		return false
+	}
+	r.buf[w&r.mask] = f
+	atomic.StoreUint64(&r.write, w+1)
+	return true
+}
+
+// Pop removes the oldest frame, and returns false if the buffer is empty. Only a consumer can call it.
+func (r *Ring) Pop() (Frame, bool) {
+	rd := atomic.LoadUint64(&r.read)
+	if rd == atomic.LoadUint64(&r.write) {
+		return Frame{}, false
+	}
+	f := r.buf[rd&r.mask]
+	atomic.StoreUint64(&r.read, rd+1)
+	return f, true
+}
@@ -0,0 +1,65 @@
+package audio
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRing(t *testing.T) {
+	r := NewRing(3)
+	assert.Equal(t, 4, r.Cap())
+
+	for i := 0; i < 4; i++ {
+		assert.True(t, r.Push(Frame{float32(i), 0}))
+	}
+	assert.False(t, r.Push(Frame{4, 0}))
+	assert.Equal(t, 4, r.Len())
+
+	// wrap around
+	for i := 0; i < 10; i++ {
+		f, ok := r.Pop()
+		require.True(t, ok)
+		assert.EqualValues(t, i, f[0])
+		assert.True(t, r.Push(Frame{float32(i + 4), 0}))
+	}
+	assert.Equal(t, 4, r.Len())
+
+	for i := 0; i < 4; i++ {
+		_, ok := r.Pop()
+		assert.True(t, ok)
+	}
+	_, ok := r.Pop()
+	assert.False(t, ok)
+}
+
+func TestRing_concurrent(t *testing.T) {
+	const n = 100000
+	r := NewRing(64)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; {
+			if r.Push(Frame{float32(i), -float32(i)}) {
+				i++
+			} else {
+				runtime.Gosched()
+			}
+		}
+	}()
+
+	for i := 0; i < n; {
+		if f, ok := r.Pop(); ok {
+			require.Equal(t, Frame{float32(i), -float32(i)}, f)
+			i++
+		} else {
+			runtime.Gosched()
+		}
+	}
+	wg.Wait()
+}
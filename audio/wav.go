@@ -0,0 +1,133 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// http://soundfile.sapp.org/doc/WaveFormat/
+
+// WAVFormat is a sample format of WAV.
+type WAVFormat uint8
+
+const (
+	// 16-bit signed integer PCM
+	WAVPCM16 WAVFormat = iota
+	// 32-bit IEEE float
+	WAVFloat32
+)
+
+// ParseWAVFormat returns the format by name, "pcm16" or "float32".
+func ParseWAVFormat(name string) (WAVFormat, error) {
+	switch name {
+	case "pcm16":
+		return WAVPCM16, nil
+	case "float32":
+		return WAVFloat32, nil
+	}
+	return 0, errors.Errorf("unknown WAV format: %s", name)
+}
+
+const wavHeaderSize = 44
+
+// WAVWriter streams samples into WAV, whose header is completed on Close.
+type WAVWriter struct {
+	w  io.WriteSeeker
+	bw *bufio.Writer
+
+	format     WAVFormat
+	channels   int
+	sampleRate int
+
+	frames int
+	buf    []byte
+}
+
+// NewWAVWriter writes the header of WAV into w.
+func NewWAVWriter(w io.WriteSeeker, sampleRate, channels int, format WAVFormat) (*WAVWriter, error) {
+	wav := &WAVWriter{
+		w:          w,
+		bw:         bufio.NewWriter(w),
+		format:     format,
+		channels:   channels,
+		sampleRate: sampleRate,
+	}
+	if _, err := wav.bw.Write(wav.header()); err != nil {
+		return nil, errors.Wrap(err, "failed to write WAV header")
+	}
+	return wav, nil
+}
+
+func (w *WAVWriter) sampleSize() int {
+	if w.format == WAVFloat32 {
+		return 4
+	}
+	return 2
+}
+
+func (w *WAVWriter) header() []byte {
+	size := uint32(w.frames * w.channels * w.sampleSize())
+	formatTag, bits := uint16(1), uint16(16)
+	if w.format == WAVFloat32 {
+		formatTag, bits = 3, 32
+	}
+	blockAlign := uint16(w.channels * w.sampleSize())
+
+	h := make([]byte, wavHeaderSize)
+	copy(h[0:], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], 36+size)
+	copy(h[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(h[16:], 16)
+	binary.LittleEndian.PutUint16(h[20:], formatTag)
+	binary.LittleEndian.PutUint16(h[22:], uint16(w.channels))
+	binary.LittleEndian.PutUint32(h[24:], uint32(w.sampleRate))
+	binary.LittleEndian.PutUint32(h[28:], uint32(w.sampleRate)*uint32(blockAlign))
+	binary.LittleEndian.PutUint16(h[32:], blockAlign)
+	binary.LittleEndian.PutUint16(h[34:], bits)
+	copy(h[36:], "data")
+	binary.LittleEndian.PutUint32(h[40:], size)
+	return h
+}
+
+// WriteFrame writes a sample for each channel.
+func (w *WAVWriter) WriteFrame(samples ...float32) error {
+	if len(samples) != w.channels {
+		return errors.Errorf("expected %d samples but %d", w.channels, len(samples))
+	}
+	w.buf = w.buf[:0]
+	var b [4]byte
+	for _, v := range samples {
+		switch w.format {
+		case WAVFloat32:
+			binary.LittleEndian.PutUint32(b[:], math.Float32bits(v))
+		default:
+			v := math.Max(-1, math.Min(1, float64(v)))
+			binary.LittleEndian.PutUint16(b[:], uint16(int16(math.Round(v*math.MaxInt16))))
+		}
+		w.buf = append(w.buf, b[:w.sampleSize()]...)
+	}
+	if _, err := w.bw.Write(w.buf); err != nil {
+		return errors.Wrap(err, "failed to write WAV samples")
+	}
+	w.frames++
+	return nil
+}
+
+// Close completes the header with the size of samples, but does not close the underlying writer.
+func (w *WAVWriter) Close() error {
+	if err := w.bw.Flush(); err != nil {
+		return errors.Wrap(err, "failed to write WAV samples")
+	}
+	if _, err := w.w.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "failed to seek WAV header")
+	}
+	if _, err := w.w.Write(w.header()); err != nil {
+		return errors.Wrap(err, "failed to write WAV header")
+	}
+	_, err := w.w.Seek(0, io.SeekEnd)
+	return err
+}
@@ -0,0 +1,62 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWAVWriter(t *testing.T) {
+	for _, tt := range []struct {
+		format     WAVFormat
+		formatTag  uint16
+		bits       uint16
+		sampleSize int
+	}{
+		{WAVPCM16, 1, 16, 2},
+		{WAVFloat32, 3, 32, 4},
+	} {
+		path := filepath.Join(t.TempDir(), "out.wav")
+		f, err := os.Create(path)
+		require.NoError(t, err)
+
+		w, err := NewWAVWriter(f, 44100, 2, tt.format)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteFrame(0.5, -2))
+		require.NoError(t, w.WriteFrame(0, 1))
+		assert.Error(t, w.WriteFrame(0))
+		require.NoError(t, w.Close())
+		require.NoError(t, f.Close())
+
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		size := 2 * 2 * tt.sampleSize
+		require.Len(t, b, wavHeaderSize+size)
+		assert.Equal(t, "RIFF", string(b[0:4]))
+		assert.EqualValues(t, 36+size, binary.LittleEndian.Uint32(b[4:]))
+		assert.Equal(t, "WAVEfmt ", string(b[8:16]))
+		assert.Equal(t, tt.formatTag, binary.LittleEndian.Uint16(b[20:]))
+		assert.EqualValues(t, 2, binary.LittleEndian.Uint16(b[22:]))
+		assert.EqualValues(t, 44100, binary.LittleEndian.Uint32(b[24:]))
+		assert.EqualValues(t, 44100*2*tt.sampleSize, binary.LittleEndian.Uint32(b[28:]))
+		assert.Equal(t, tt.bits, binary.LittleEndian.Uint16(b[34:]))
+		assert.EqualValues(t, size, binary.LittleEndian.Uint32(b[40:]))
+
+		data := b[wavHeaderSize:]
+		switch tt.format {
+		case WAVPCM16:
+			assert.EqualValues(t, 16384, int16(binary.LittleEndian.Uint16(data[0:])))
+			// clipped
+			assert.EqualValues(t, -math.MaxInt16, int16(binary.LittleEndian.Uint16(data[2:])))
+			assert.EqualValues(t, math.MaxInt16, int16(binary.LittleEndian.Uint16(data[6:])))
+		case WAVFloat32:
+			assert.EqualValues(t, 0.5, math.Float32frombits(binary.LittleEndian.Uint32(data[0:])))
+			assert.EqualValues(t, -2, math.Float32frombits(binary.LittleEndian.Uint32(data[4:])))
+		}
+	}
+}
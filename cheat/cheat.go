@@ -0,0 +1,166 @@
+// Package cheat applies Game Genie and Pro Action Replay codes, and reads and writes FCEUX's .cht files.
+package cheat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Code replaces the value at the address, only if the value is Compare when HasCompare is set.
+type Code struct {
+	Address    uint16
+	Value      uint8
+	Compare    uint8
+	HasCompare bool
+}
+
+// Parse parses a Game Genie code or a raw code in "address:value[:compare]" in hex.
+func Parse(code string) (Code, error) {
+	s := strings.TrimSpace(code)
+	if !strings.Contains(s, ":") {
+		return DecodeGameGenie(s)
+	}
+
+	fields := strings.Split(s, ":")
+	if 3 < len(fields) {
+		return Code{}, errors.Errorf("invalid code: %s", code)
+	}
+	var v [3]uint64
+	for i, f := range fields {
+		bits := 8
+		if i == 0 {
+			bits = 16
+		}
+		n, err := strconv.ParseUint(strings.TrimPrefix(f, "$"), 16, bits)
+		if err != nil {
+			return Code{}, errors.Wrapf(err, "invalid code: %s", code)
+		}
+		v[i] = n
+	}
+	return Code{Address: uint16(v[0]), Value: uint8(v[1]), Compare: uint8(v[2]), HasCompare: len(fields) == 3}, nil
+}
+
+// String returns the raw code in "address:value[:compare]".
+func (c Code) String() string {
+	if c.HasCompare {
+		return fmt.Sprintf("%04X:%02X:%02X", c.Address, c.Value, c.Compare)
+	}
+	return fmt.Sprintf("%04X:%02X", c.Address, c.Value)
+}
+
+// Cheat is a named code, which is a line of .cht files.
+type Cheat struct {
+	Code
+	Name    string
+	Enabled bool
+	// whether the code substitutes reads like Game Genie, otherwise it writes RAM every frame like Pro Action Replay
+	Substitute bool
+}
+
+// New returns an enabled cheat of the code given to Parse, which substitutes reads of ROM at $8000-$FFFF.
+func New(name, code string) (Cheat, error) {
+	c, err := Parse(code)
+	if err != nil {
+		return Cheat{}, err
+	}
+	return Cheat{Code: c, Name: name, Enabled: true, Substitute: 0x8000 <= c.Address}, nil
+}
+
+// Reader reads CPU memory.
+type Reader interface {
+	ReadCPU(addr uint16) uint8
+}
+
+// Memory is CPU memory which cheats write into.
+type Memory interface {
+	Reader
+	WriteCPU(addr uint16, value uint8)
+}
+
+// Engine applies enabled cheats.
+type Engine struct {
+	cheats []Cheat
+
+	// enabled substitutes by address
+	reads map[uint16][]Code
+	// enabled writes
+	writes []Code
+}
+
+// NewEngine returns an engine of the cheats.
+func NewEngine(cheats ...Cheat) *Engine {
+	e := new(Engine)
+	e.cheats = append(e.cheats, cheats...)
+	e.update()
+	return e
+}
+
+// Cheats returns a copy of the cheats.
+func (e *Engine) Cheats() []Cheat {
+	return append([]Cheat(nil), e.cheats...)
+}
+
+// Add adds a cheat.
+func (e *Engine) Add(c Cheat) {
+	e.cheats = append(e.cheats, c)
+	e.update()
+}
+
+// Remove removes the i-th cheat.
+func (e *Engine) Remove(i int) {
+	e.cheats = append(e.cheats[:i], e.cheats[i+1:]...)
+	e.update()
+}
+
+// SetEnabled enables or disables the i-th cheat.
+func (e *Engine) SetEnabled(i int, enabled bool) {
+	e.cheats[i].Enabled = enabled
+	e.update()
+}
+
+func (e *Engine) update() {
+	e.reads, e.writes = nil, nil
+	for _, c := range e.cheats {
+		switch {
+		case !c.Enabled:
+		case c.Substitute:
+			if e.reads == nil {
+				e.reads = map[uint16][]Code{}
+			}
+			e.reads[c.Address] = append(e.reads[c.Address], c.Code)
+		default:
+			e.writes = append(e.writes, c.Code)
+		}
+	}
+}
+
+// Read returns the value read from the address, substituted by the first matched cheat.
+func (e *Engine) Read(addr uint16, value uint8) uint8 {
+	if e.reads == nil {
+		return value
+	}
+	for _, c := range e.reads[addr] {
+		if !c.HasCompare || c.Compare == value {
+			return c.Value
+		}
+	}
+	return value
+}
+
+// Poke writes values of cheats into RAM, which is called every frame.
+//
+// Only the internal RAM at $0000-$1FFF and PRG-RAM at $6000-$7FFF are written.
+func (e *Engine) Poke(m Memory) {
+	for _, c := range e.writes {
+		if !(c.Address < 0x2000 || 0x6000 <= c.Address && c.Address < 0x8000) {
+			continue
+		}
+		if c.HasCompare && m.ReadCPU(c.Address) != c.Compare {
+			continue
+		}
+		m.WriteCPU(c.Address, c.Value)
+	}
+}
@@ -0,0 +1,87 @@
+package cheat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	got, err := Parse("SXIOPO")
+	require.NoError(t, err)
+	assert.Equal(t, Code{Address: 0x91D9, Value: 0xAD}, got)
+
+	got, err = Parse("075A:09")
+	require.NoError(t, err)
+	assert.Equal(t, Code{Address: 0x075A, Value: 0x09}, got)
+	assert.Equal(t, "075A:09", got.String())
+
+	got, err = Parse(" $c000:ea:4C ")
+	require.NoError(t, err)
+	assert.Equal(t, Code{Address: 0xC000, Value: 0xEA, Compare: 0x4C, HasCompare: true}, got)
+	assert.Equal(t, "C000:EA:4C", got.String())
+
+	for _, code := range []string{"075A", "075A:100", "10000:00", "075A:00:00:00", "ZZ:00"} {
+		_, err := Parse(code)
+		assert.Error(t, err, code)
+	}
+}
+
+func TestNew(t *testing.T) {
+	c, err := New("lives", "075A:09")
+	require.NoError(t, err)
+	assert.Equal(t, Cheat{Code: Code{Address: 0x075A, Value: 0x09}, Name: "lives", Enabled: true}, c)
+
+	c, err = New("invincible", "SXIOPO")
+	require.NoError(t, err)
+	assert.True(t, c.Substitute)
+}
+
+type memory [0x10000]uint8
+
+func (m *memory) ReadCPU(addr uint16) uint8 { return m[addr] }
+
+func (m *memory) WriteCPU(addr uint16, value uint8) { m[addr] = value }
+
+func TestEngine(t *testing.T) {
+	e := NewEngine(
+		Cheat{Code: Code{Address: 0x8000, Value: 0xEA}, Enabled: true, Substitute: true},
+		Cheat{Code: Code{Address: 0x9000, Value: 0xEA, Compare: 0x4C, HasCompare: true}, Enabled: true, Substitute: true},
+		Cheat{Code: Code{Address: 0x0010, Value: 0x09}, Enabled: true},
+		Cheat{Code: Code{Address: 0x0011, Value: 0x09, Compare: 0x01, HasCompare: true}, Enabled: true},
+		Cheat{Code: Code{Address: 0x6000, Value: 0x09}, Enabled: true},
+		Cheat{Code: Code{Address: 0x2000, Value: 0x09}, Enabled: true},
+		Cheat{Code: Code{Address: 0x0012, Value: 0x09}},
+	)
+
+	assert.EqualValues(t, 0xEA, e.Read(0x8000, 0x00))
+	assert.EqualValues(t, 0xEA, e.Read(0x9000, 0x4C))
+	assert.EqualValues(t, 0x20, e.Read(0x9000, 0x20), "compare value is not matched")
+	assert.EqualValues(t, 0x20, e.Read(0x8001, 0x20))
+
+	var m memory
+	m[0x0011] = 0x02
+	e.Poke(&m)
+	assert.EqualValues(t, 0x09, m[0x0010])
+	assert.EqualValues(t, 0x02, m[0x0011], "compare value is not matched")
+	assert.EqualValues(t, 0x09, m[0x6000])
+	assert.EqualValues(t, 0x00, m[0x2000], "registers are not written")
+	assert.EqualValues(t, 0x00, m[0x0012], "disabled")
+
+	m[0x0011] = 0x01
+	e.SetEnabled(6, true)
+	e.Poke(&m)
+	assert.EqualValues(t, 0x09, m[0x0011])
+	assert.EqualValues(t, 0x09, m[0x0012])
+
+	e.SetEnabled(0, false)
+	assert.EqualValues(t, 0x00, e.Read(0x8000, 0x00))
+
+	e.Remove(1)
+	assert.EqualValues(t, 0x4C, e.Read(0x9000, 0x4C))
+	assert.Len(t, e.Cheats(), 6)
+
+	e.Add(Cheat{Code: Code{Address: 0x8000, Value: 0x60}, Enabled: true, Substitute: true})
+	assert.EqualValues(t, 0x60, e.Read(0x8000, 0x00))
+}
@@ -0,0 +1,127 @@
+package cheat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FCEUX's .cht file has a cheat per line in "[S][C][:]AAAA:VV[:CC]:name",
+// where S is for substitutes, C is for codes with the compare value, and ":" before the address disables the cheat.
+
+// ReadCHT reads cheats in FCEUX's .cht format.
+func ReadCHT(r io.Reader) ([]Cheat, error) {
+	var cheats []Cheat
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		c, err := parseCHTLine(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", n)
+		}
+		cheats = append(cheats, c)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read cheats")
+	}
+	return cheats, nil
+}
+
+func parseCHTLine(line string) (Cheat, error) {
+	c := Cheat{Enabled: true}
+	s := line
+	if strings.HasPrefix(s, "S") {
+		c.Substitute = true
+		s = s[1:]
+	}
+	if strings.HasPrefix(s, "C") {
+		c.HasCompare = true
+		s = s[1:]
+	}
+	if strings.HasPrefix(s, ":") {
+		c.Enabled = false
+		s = s[1:]
+	}
+
+	n := 3
+	if c.HasCompare {
+		n = 4
+	}
+	fields := strings.SplitN(s, ":", n)
+	if len(fields) < n {
+		return Cheat{}, errors.Errorf("invalid cheat: %s", line)
+	}
+	for i, f := range fields[:n-1] {
+		bits := 8
+		if i == 0 {
+			bits = 16
+		}
+		v, err := strconv.ParseUint(f, 16, bits)
+		if err != nil {
+			return Cheat{}, errors.Wrapf(err, "invalid cheat: %s", line)
+		}
+		switch i {
+		case 0:
+			c.Address = uint16(v)
+		case 1:
+			c.Value = uint8(v)
+		case 2:
+			c.Compare = uint8(v)
+		}
+	}
+	c.Name = fields[n-1]
+	return c, nil
+}
+
+// WriteCHT writes cheats in FCEUX's .cht format.
+func WriteCHT(w io.Writer, cheats []Cheat) error {
+	bw := bufio.NewWriter(w)
+	for _, c := range cheats {
+		if c.Substitute {
+			bw.WriteString("S")
+		}
+		if c.HasCompare {
+			bw.WriteString("C")
+		}
+		if !c.Enabled {
+			bw.WriteString(":")
+		}
+		if c.HasCompare {
+			fmt.Fprintf(bw, "%04x:%02x:%02x:%s\n", c.Address, c.Value, c.Compare, c.Name)
+		} else {
+			fmt.Fprintf(bw, "%04x:%02x:%s\n", c.Address, c.Value, c.Name)
+		}
+	}
+	return errors.Wrap(bw.Flush(), "failed to write cheats")
+}
+
+// LoadFile reads a .cht file.
+func LoadFile(path string) ([]Cheat, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s", path)
+	}
+	defer f.Close()
+	return ReadCHT(f)
+}
+
+// SaveFile writes a .cht file.
+func SaveFile(path string, cheats []Cheat) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create %s", path)
+	}
+	if err := WriteCHT(f, cheats); err != nil {
+		f.Close()
+		return err
+	}
+	return errors.Wrapf(f.Close(), "failed to write %s", path)
+}
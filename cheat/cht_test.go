@@ -0,0 +1,50 @@
+package cheat
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCHT = `075a:09:Infinite lives
+:0079:10:Disabled
+S91d9:ad:Invincible
+SC8b03:00:01:Compare: with colon
+SC:94a7:02:03:
+`
+
+func TestReadCHT(t *testing.T) {
+	got, err := ReadCHT(strings.NewReader(strings.ReplaceAll(testCHT, "\n", "\r\n") + "\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []Cheat{
+		{Code: Code{Address: 0x075A, Value: 0x09}, Name: "Infinite lives", Enabled: true},
+		{Code: Code{Address: 0x0079, Value: 0x10}, Name: "Disabled"},
+		{Code: Code{Address: 0x91D9, Value: 0xAD}, Name: "Invincible", Enabled: true, Substitute: true},
+		{Code: Code{Address: 0x8B03, Value: 0x00, Compare: 0x01, HasCompare: true}, Name: "Compare: with colon", Enabled: true, Substitute: true},
+		{Code: Code{Address: 0x94A7, Value: 0x02, Compare: 0x03, HasCompare: true}, Substitute: true},
+	}, got)
+
+	for _, line := range []string{"075a:09", "C075a:09:name", "75a0a:09:name", "075a:zz:name"} {
+		_, err := ReadCHT(strings.NewReader(line))
+		assert.Error(t, err, line)
+	}
+}
+
+func TestWriteCHT(t *testing.T) {
+	cheats, err := ReadCHT(strings.NewReader(testCHT))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteCHT(&buf, cheats))
+	assert.Equal(t, testCHT, buf.String())
+
+	path := filepath.Join(t.TempDir(), "test.cht")
+	require.NoError(t, SaveFile(path, cheats))
+	got, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, cheats, got)
+}
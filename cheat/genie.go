@@ -0,0 +1,69 @@
+package cheat
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// https://www.nesdev.org/wiki/Game_Genie
+
+const genieLetters = "APZLGITYEOXUKSVN"
+
+// DecodeGameGenie decodes a 6- or 8-letter Game Genie code, where 8-letter codes have the compare value.
+func DecodeGameGenie(code string) (Code, error) {
+	s := strings.ToUpper(code)
+	if len(s) != 6 && len(s) != 8 {
+		return Code{}, errors.Errorf("invalid length of Game Genie code: %s", code)
+	}
+	var n [8]uint16
+	for i := 0; i < len(s); i++ {
+		v := strings.IndexByte(genieLetters, s[i])
+		if v < 0 {
+			return Code{}, errors.Errorf("invalid letter of Game Genie code: %s", code)
+		}
+		n[i] = uint16(v)
+	}
+
+	c := Code{
+		Address: 0x8000 | (n[3]&7)<<12 | (n[5]&7)<<8 | (n[4]&8)<<8 | (n[2]&7)<<4 | (n[1]&8)<<4 | n[4]&7 | n[3]&8,
+	}
+	data := (n[1]&7)<<4 | (n[0]&8)<<4 | n[0]&7
+	if len(s) == 6 {
+		c.Value = uint8(data | n[5]&8)
+	} else {
+		c.Value = uint8(data | n[7]&8)
+		c.Compare = uint8((n[7]&7)<<4 | (n[6]&8)<<4 | n[6]&7 | n[5]&8)
+		c.HasCompare = true
+	}
+	return c, nil
+}
+
+// GameGenie encodes the code into Game Genie, which is only for $8000-$FFFF.
+func (c Code) GameGenie() (string, bool) {
+	if c.Address < 0x8000 {
+		return "", false
+	}
+	a, v, cmp := c.Address, uint16(c.Value), uint16(c.Compare)
+
+	n := []uint16{
+		v&7 | v>>4&8,
+		v>>4&7 | a>>4&8,
+		a >> 4 & 7,
+		a>>12&7 | a&8,
+		a&7 | a>>8&8,
+		a>>8&7 | v&8,
+	}
+	if c.HasCompare {
+		// the 3rd letter tells the length
+		n[2] |= 8
+		n[5] = a>>8&7 | cmp&8
+		n = append(n, cmp&7|cmp>>4&8, cmp>>4&7|v&8)
+	}
+
+	b := make([]byte, len(n))
+	for i, v := range n {
+		b[i] = genieLetters[v]
+	}
+	return string(b), true
+}
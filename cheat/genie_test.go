@@ -0,0 +1,51 @@
+package cheat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeGameGenie(t *testing.T) {
+	tests := []struct {
+		code string
+		want Code
+	}{
+		{"GOSSIP", Code{Address: 0xD1DD, Value: 0x14}},
+		{"SXIOPO", Code{Address: 0x91D9, Value: 0xAD}},
+		{"sxiopo", Code{Address: 0x91D9, Value: 0xAD}},
+		{"AAEAULPA", Code{Address: 0x8B03, Value: 0x00, Compare: 0x01, HasCompare: true}},
+		{"ZEXPYGLA", Code{Address: 0x94A7, Value: 0x02, Compare: 0x03, HasCompare: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			got, err := DecodeGameGenie(tt.code)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	for _, code := range []string{"", "GOSSI", "GOSSIPA", "GOSSIB"} {
+		_, err := DecodeGameGenie(code)
+		assert.Error(t, err, code)
+	}
+}
+
+func TestCode_GameGenie(t *testing.T) {
+	for _, code := range []string{"SXIOPO", "AAEAULPA", "ZEXPYGLA"} {
+		c, err := DecodeGameGenie(code)
+		require.NoError(t, err)
+		got, ok := c.GameGenie()
+		assert.True(t, ok)
+		assert.Equal(t, code, got)
+	}
+
+	// the 3rd letter of 6-letter codes is encoded without the length bit
+	c, _ := DecodeGameGenie("GOSSIP")
+	got, _ := c.GameGenie()
+	assert.Equal(t, "GOISIP", got)
+
+	_, ok := Code{Address: 0x0075, Value: 0x09}.GameGenie()
+	assert.False(t, ok)
+}
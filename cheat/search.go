@@ -0,0 +1,233 @@
+package cheat
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Region is a range of CPU memory to search.
+type Region struct {
+	Start uint16
+	Size  int
+}
+
+var (
+	// WRAM is the internal RAM.
+	WRAM = Region{Start: 0x0000, Size: 0x0800}
+	// PRGRAM is RAM on cartridges.
+	PRGRAM = Region{Start: 0x6000, Size: 0x2000}
+)
+
+// View is how values in memory are interpreted, where 16-bit values are little endian.
+type View struct {
+	// 8 or 16
+	Bits   int
+	Signed bool
+}
+
+func (v View) value(lo, hi uint8) int64 {
+	switch {
+	case v.Bits == 16 && v.Signed:
+		return int64(int16(uint16(lo) | uint16(hi)<<8))
+	case v.Bits == 16:
+		return int64(uint16(lo) | uint16(hi)<<8)
+	case v.Signed:
+		return int64(int8(lo))
+	}
+	return int64(lo)
+}
+
+// width returns the number of bytes of a value.
+func (v View) width() int {
+	if v.Bits == 16 {
+		return 2
+	}
+	return 1
+}
+
+func (v View) String() string {
+	bits := 8
+	if v.Bits == 16 {
+		bits = 16
+	}
+	if v.Signed {
+		return fmt.Sprintf("int%d", bits)
+	}
+	return fmt.Sprintf("uint%d", bits)
+}
+
+// Comparison narrows candidates by comparing current values with previous ones in the last snapshot.
+type Comparison uint8
+
+const (
+	// current value is N
+	Equal Comparison = iota
+	// current value is not N
+	NotEqual
+	Changed
+	Unchanged
+	// current value is previous + N
+	IncreasedBy
+	// current value is previous - N
+	DecreasedBy
+	// current value is greater than previous
+	Greater
+	// current value is less than previous
+	Less
+)
+
+var comparisonNames = []string{"eq", "ne", "changed", "unchanged", "inc", "dec", "gt", "lt"}
+
+func (c Comparison) String() string {
+	if int(c) < len(comparisonNames) {
+		return comparisonNames[c]
+	}
+	return fmt.Sprintf("Comparison(%d)", c)
+}
+
+// ParseComparison returns the comparison by name of String.
+func ParseComparison(name string) (Comparison, error) {
+	for i, n := range comparisonNames {
+		if n == name {
+			return Comparison(i), nil
+		}
+	}
+	return 0, errors.Errorf("unknown comparison: %s", name)
+}
+
+// NeedsOperand reports whether the comparison uses N.
+func (c Comparison) NeedsOperand() bool {
+	switch c {
+	case Equal, NotEqual, IncreasedBy, DecreasedBy:
+		return true
+	}
+	return false
+}
+
+func (c Comparison) match(prev, cur, n int64) bool {
+	switch c {
+	case Equal:
+		return cur == n
+	case NotEqual:
+		return cur != n
+	case Changed:
+		return cur != prev
+	case Unchanged:
+		return cur == prev
+	case IncreasedBy:
+		return cur-prev == n
+	case DecreasedBy:
+		return prev-cur == n
+	case Greater:
+		return prev < cur
+	case Less:
+		return cur < prev
+	}
+	return false
+}
+
+// Candidate is an address which matched all comparisons so far.
+type Candidate struct {
+	Address uint16
+	// value in the last snapshot
+	Previous int64
+	Current  int64
+}
+
+// Search narrows addresses of RAM down by comparisons, like finding a variable of games to make cheats.
+type Search struct {
+	mem     Reader
+	regions []Region
+	view    View
+
+	// address of each byte in snapshot
+	addrs []uint16
+	// memory in regions at the last comparison
+	snapshot []uint8
+	// indices of snapshot
+	candidates []int
+}
+
+// NewSearch starts a search in the regions, where all addresses are candidates.
+func NewSearch(mem Reader, view View, regions ...Region) *Search {
+	s := &Search{mem: mem, regions: regions, view: view}
+	for _, r := range regions {
+		for i := 0; i < r.Size; i++ {
+			s.addrs = append(s.addrs, r.Start+uint16(i))
+		}
+	}
+	s.Reset()
+	return s
+}
+
+// View returns how values are interpreted.
+func (s *Search) View() View {
+	return s.view
+}
+
+// Reset takes a snapshot and makes all addresses candidates again.
+func (s *Search) Reset() {
+	s.snapshot = s.read(s.snapshot)
+	s.candidates = s.candidates[:0]
+	w := s.view.width()
+	for i := range s.addrs {
+		// the whole value must be in the same region
+		if w == 2 && (len(s.addrs) <= i+1 || s.addrs[i+1] != s.addrs[i]+1) {
+			continue
+		}
+		s.candidates = append(s.candidates, i)
+	}
+}
+
+func (s *Search) read(b []uint8) []uint8 {
+	b = b[:0]
+	for _, a := range s.addrs {
+		b = append(b, s.mem.ReadCPU(a))
+	}
+	return b
+}
+
+func (s *Search) value(mem []uint8, i int) int64 {
+	if s.view.width() == 2 {
+		return s.view.value(mem[i], mem[i+1])
+	}
+	return s.view.value(mem[i], 0)
+}
+
+// Filter keeps candidates matching the comparison with n, takes a snapshot, and returns the number of candidates.
+func (s *Search) Filter(c Comparison, n int64) int {
+	cur := s.read(nil)
+	matched := s.candidates[:0]
+	for _, i := range s.candidates {
+		if c.match(s.value(s.snapshot, i), s.value(cur, i), n) {
+			matched = append(matched, i)
+		}
+	}
+	s.candidates = matched
+	s.snapshot = cur
+	return len(matched)
+}
+
+// Len returns the number of candidates.
+func (s *Search) Len() int {
+	return len(s.candidates)
+}
+
+// Candidates returns up to limit candidates in order of address, or all if limit is 0 or less.
+func (s *Search) Candidates(limit int) []Candidate {
+	n := len(s.candidates)
+	if 0 < limit && limit < n {
+		n = limit
+	}
+	cs := make([]Candidate, n)
+	for j, i := range s.candidates[:n] {
+		var hi uint8
+		if s.view.width() == 2 {
+			hi = s.mem.ReadCPU(s.addrs[i+1])
+		}
+		cur := s.view.value(s.mem.ReadCPU(s.addrs[i]), hi)
+		cs[j] = Candidate{Address: s.addrs[i], Previous: s.value(s.snapshot, i), Current: cur}
+	}
+	return cs
+}
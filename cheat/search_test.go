@@ -0,0 +1,69 @@
+package cheat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch(t *testing.T) {
+	var m memory
+	s := NewSearch(&m, View{Bits: 8}, WRAM, PRGRAM)
+	assert.Equal(t, 0x0800+0x2000, s.Len())
+
+	// lives
+	m[0x0075] = 3
+	m[0x6010] = 3
+	assert.Equal(t, 2, s.Filter(Equal, 3))
+
+	m[0x0075] = 2
+	assert.Equal(t, 1, s.Filter(DecreasedBy, 1))
+	assert.Equal(t, []Candidate{{Address: 0x0075, Previous: 2, Current: 2}}, s.Candidates(0))
+
+	assert.Equal(t, 1, s.Filter(Unchanged, 0))
+	m[0x0075] = 5
+	assert.Equal(t, 1, s.Filter(Greater, 0))
+	assert.Equal(t, 0, s.Filter(Changed, 0))
+
+	s.Reset()
+	assert.Equal(t, 0x2800, s.Len())
+	assert.Len(t, s.Candidates(10), 10)
+	assert.Equal(t, 0x2800-1, s.Filter(NotEqual, 5))
+}
+
+func TestSearch_View(t *testing.T) {
+	var m memory
+	s := NewSearch(&m, View{Bits: 16, Signed: true}, WRAM)
+	// the last byte has no high byte
+	assert.Equal(t, 0x07FF, s.Len())
+
+	// score in little endian
+	m[0x0100], m[0x0101] = 0xFF, 0xFF
+	assert.Equal(t, 2, s.Filter(Less, 0))
+	assert.Equal(t, []Candidate{{Address: 0x00FF, Previous: -256, Current: -256}, {Address: 0x0100, Previous: -1, Current: -1}}, s.Candidates(0))
+
+	m[0x0100], m[0x0101] = 0x2C, 0x01
+	assert.Equal(t, 1, s.Filter(IncreasedBy, 301))
+	assert.EqualValues(t, 0x0100, s.Candidates(0)[0].Address)
+
+	s = NewSearch(&m, View{Bits: 8, Signed: true}, Region{Start: 0x0100, Size: 1})
+	m[0x0100] = 0x80
+	assert.Equal(t, 1, s.Filter(Equal, -128))
+
+	assert.Equal(t, "int8", View{Bits: 8, Signed: true}.String())
+	assert.Equal(t, "uint16", View{Bits: 16}.String())
+}
+
+func TestParseComparison(t *testing.T) {
+	for c := Equal; c <= Less; c++ {
+		got, err := ParseComparison(c.String())
+		require.NoError(t, err)
+		assert.Equal(t, c, got)
+	}
+	assert.True(t, IncreasedBy.NeedsOperand())
+	assert.False(t, Greater.NeedsOperand())
+
+	_, err := ParseComparison("foo")
+	assert.Error(t, err)
+}
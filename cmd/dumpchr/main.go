@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"image"
@@ -9,6 +10,7 @@ import (
 	"os"
 
 	"github.com/thara/gorones/mapper"
+	"github.com/thara/gorones/romfile"
 )
 
 func main() {
@@ -22,20 +24,19 @@ func main() {
 		os.Exit(1)
 	}
 
-	path := os.Args[1]
+	path := flag.Arg(0)
 	if err := run(path); err != nil {
 		log.Fatal(err)
 	}
 }
 
 func run(path string) error {
-	f, err := os.Open(path)
+	file, err := romfile.Load(path)
 	if err != nil {
 		return fmt.Errorf("fail to open %s: %v", path, err)
 	}
-	defer f.Close()
 
-	rom, err := mapper.ParseROM(f)
+	rom, _, err := mapper.LoadROM(bytes.NewReader(file.Data))
 	if err != nil {
 		return fmt.Errorf("fail to open %s: %v", path, err)
 	}
@@ -54,7 +55,7 @@ func run(path string) error {
 		p.write(i, img)
 	}
 
-	f, _ = os.Create("chr.png")
+	f, err := os.Create("chr.png")
 	if err != nil {
 		return fmt.Errorf("fail to create file: %v", err)
 	}
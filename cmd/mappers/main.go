@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/thara/gorones/mapper"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Printf("Usage: %s\n", os.Args[0])
+		fmt.Println("List supported mappers")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "MAPPER\tSUBMAPPER\tNAME")
+	for _, r := range mapper.Registered() {
+		sub := "*"
+		if r.Submapper != mapper.AnySubmapper {
+			sub = fmt.Sprint(r.Submapper)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", r.MapperNo, sub, r.Name)
+	}
+	w.Flush()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/thara/gorones/cheat"
+)
+
+// cheatMenu toggles cheats saved in <rom>.cht, which pauses the emulation while shown
+type cheatMenu struct {
+	engine *cheat.Engine
+	path   string
+
+	shown   bool
+	cursor  int
+	changed bool
+	// while recording or playing a movie
+	disabled bool
+}
+
+// newCheatMenu loads cheats of the ROM if exists, and adds codes given by flags
+func newCheatMenu(romPath string, codes []string) (*cheatMenu, error) {
+	m := &cheatMenu{path: strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".cht"}
+
+	cheats, err := cheat.LoadFile(m.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	m.engine = cheat.NewEngine(cheats...)
+
+	for _, code := range codes {
+		c, err := cheat.New(code, code)
+		if err != nil {
+			return nil, err
+		}
+		m.engine.Add(c)
+		m.changed = true
+	}
+	if n := len(m.engine.Cheats()); 0 < n {
+		fmt.Printf("cheats: %d in %s\n", n, m.path)
+	}
+	return m, nil
+}
+
+// update handles keys, and reports whether the menu is shown
+func (m *cheatMenu) update() bool {
+	if m.disabled {
+		if inpututil.IsKeyJustPressed(ebiten.KeyF11) {
+			fmt.Println("cheats are disabled while recording or playing a movie")
+		}
+		return false
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF11) {
+		m.shown = !m.shown
+		if !m.shown {
+			if err := m.save(); err != nil {
+				fmt.Println("fail to save cheats:", err)
+			}
+		}
+	}
+	if !m.shown {
+		return false
+	}
+
+	n := len(m.engine.Cheats())
+	switch {
+	case n == 0:
+	case inpututil.IsKeyJustPressed(ebiten.KeyUp):
+		m.cursor = (m.cursor + n - 1) % n
+	case inpututil.IsKeyJustPressed(ebiten.KeyDown):
+		m.cursor = (m.cursor + 1) % n
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter), inpututil.IsKeyJustPressed(ebiten.KeySpace):
+		m.engine.SetEnabled(m.cursor, !m.engine.Cheats()[m.cursor].Enabled)
+		m.changed = true
+	case inpututil.IsKeyJustPressed(ebiten.KeyDelete):
+		m.engine.Remove(m.cursor)
+		m.changed = true
+		if m.cursor == n-1 && 0 < m.cursor {
+			m.cursor--
+		}
+	}
+	return true
+}
+
+// save writes cheats into the file if changed
+func (m *cheatMenu) save() error {
+	if !m.changed {
+		return nil
+	}
+	if err := cheat.SaveFile(m.path, m.engine.Cheats()); err != nil {
+		return err
+	}
+	m.changed = false
+	return nil
+}
+
+func (m *cheatMenu) String() string {
+	var b strings.Builder
+	b.WriteString("cheats (F11: close, Enter: toggle, Delete: remove)\n")
+	for i, c := range m.engine.Cheats() {
+		cursor, enabled := " ", " "
+		if i == m.cursor {
+			cursor = ">"
+		}
+		if c.Enabled {
+			enabled = "x"
+		}
+		code := c.Code.String()
+		if g, ok := c.GameGenie(); ok && c.Substitute {
+			code = g
+		}
+		fmt.Fprintf(&b, "%s[%s] %-10s %s\n", cursor, enabled, code, c.Name)
+	}
+	return b.String()
+}
@@ -1,14 +1,27 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"image/png"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/thara/gorones"
+	"github.com/thara/gorones/apu"
+	"github.com/thara/gorones/audio"
 	"github.com/thara/gorones/mapper"
+	"github.com/thara/gorones/movie"
+	"github.com/thara/gorones/patch"
 	"github.com/thara/gorones/ppu"
+	"github.com/thara/gorones/romfile"
+	"github.com/thara/gorones/video"
 )
 
 type Emulator struct {
@@ -18,19 +31,67 @@ type Emulator struct {
 	ctrl2 *kbStdCtrl
 
 	renderer *renderer
+	audio    *audio.Pipeline
+	recorder *audio.Recorder
+	video    *video.Recorder
+	// path to the ROM to name recorded files
+	romPath string
+
+	// mapper's data saved into savePath
+	persistent mapper.Persistent
+	savePath   string
+
+	fds mapper.FDS
+
+	cheats *cheatMenu
+
+	// nil if rewinding is disabled
+	rewinder *gorones.Rewinder
+
+	// movie being recorded or played, nil if not
+	movieRecorder *movie.Recorder
+	moviePlayer   *movie.Player
+
+	// state saved by PageUp, nil if not saved
+	quickState []byte
 }
 
-func newEmulator(path string, audio *Audio) (*Emulator, error) {
-	f, err := os.Open(path)
+func newEmulator(path string, pipeline *audio.Pipeline) (*Emulator, error) {
+	file, err := romfile.Load(path)
 	if err != nil {
 		return nil, fmt.Errorf("fail to open %s: %v", path, err)
 	}
-	defer f.Close()
+	fmt.Println("load:", file.Name())
+	data, err := applyPatches(file.ROMPath(), file.Data)
+	if err != nil {
+		return nil, err
+	}
 
-	rom, err := mapper.ParseROM(f)
+	dbs := []*mapper.GameDB{mapper.DefaultGameDB()}
+	if gameDB != "" {
+		db, err := loadGameDB(gameDB)
+		if err != nil {
+			return nil, err
+		}
+		dbs = append([]*mapper.GameDB{db}, dbs...)
+	}
+	rom, g, err := mapper.LoadROM(bytes.NewReader(data), dbs...)
 	if err != nil {
 		return nil, fmt.Errorf("fail to open %s: %v", path, err)
 	}
+	if g != nil {
+		fmt.Println("game:", g.Title)
+	}
+
+	if rom.IsFDS() {
+		bios, err := os.ReadFile(fdsBIOS)
+		if err != nil {
+			return nil, fmt.Errorf("fail to read FDS BIOS: %v", err)
+		}
+		if err := rom.SetFDSBIOS(bios); err != nil {
+			return nil, err
+		}
+	}
 
 	m, err := rom.Mapper()
 	if err != nil {
@@ -38,6 +99,10 @@ func newEmulator(path string, audio *Audio) (*Emulator, error) {
 	}
 	fmt.Println(m)
 
+	if n, ok := m.(mapper.Namco163); ok {
+		n.SetCleanMixing(n163Clean)
+	}
+
 	ctrl1 := newKbStdCtrl()
 	ctrl2 := newKbStdCtrl()
 
@@ -45,11 +110,61 @@ func newEmulator(path string, audio *Audio) (*Emulator, error) {
 
 	var emu Emulator
 	emu.renderer = renderer
+	emu.audio = pipeline
 	emu.ctrl1 = ctrl1
 	emu.ctrl2 = ctrl2
 
-	emu.nes = gorones.NewNES(m, ctrl1.ctrl, ctrl2.ctrl, renderer, audio)
+	if d, ok := m.(mapper.FDS); ok {
+		emu.fds = d
+	}
+	if p, ok := m.(mapper.Persistent); ok {
+		emu.persistent = p
+		emu.savePath = strings.TrimSuffix(file.Path, filepath.Ext(file.Path)) + ".sav"
+		if err := emu.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	format, err := audio.ParseWAVFormat(recordFormat)
+	if err != nil {
+		return nil, err
+	}
+	emu.romPath = file.Path
+	emu.recorder = audio.NewRecorder(pipeline, 44100)
+	emu.recorder.Format = format
+	emu.recorder.Stems = recordStems
+
+	emu.video = video.NewRecorder(renderer, emu.recorder)
+
+	emu.nes = gorones.NewNES(m, ctrl1.ctrl, ctrl2.ctrl, emu.video, emu.video)
+	if recordStems {
+		emu.nes.SetChannelRenderer(emu.recorder)
+	}
+	if recordAudio != "" {
+		if err := emu.recorder.Start(recordAudio); err != nil {
+			return nil, err
+		}
+		fmt.Println("recording audio:", recordAudio)
+	}
+	if recordVideo != "" {
+		if err := emu.startVideo(recordVideo); err != nil {
+			return nil, err
+		}
+	}
+	filters, ok := apu.FilterPresets[audioFilter]
+	if !ok {
+		return nil, fmt.Errorf("unknown audio filter: %s", audioFilter)
+	}
+	emu.nes.SetAudioFilters(filters...)
+	emu.cheats, err = newCheatMenu(file.Path, cheatCodes)
+	if err != nil {
+		return nil, err
+	}
+	emu.nes.SetCheats(emu.cheats.engine)
 	emu.nes.PowerOn()
+	if 0 < rewindMemory {
+		emu.rewinder = gorones.NewRewinder(emu.nes, gorones.RewindOptions{MemoryLimit: rewindMemory << 20})
+	}
 
 	if nestest {
 		fmt.Println("init for nestest")
@@ -59,19 +174,362 @@ func newEmulator(path string, audio *Audio) (*Emulator, error) {
 		emu.nes.Reset()
 	}
 
+	if err := emu.initMovie(file.Name(), m); err != nil {
+		return nil, err
+	}
 	return &emu, nil
 }
 
+// initMovie starts recording or playing a movie given by flags, which disables rewinding, cheats and flipping disk sides
+func (e *Emulator) initMovie(romName string, m mapper.Mapper) error {
+	switch {
+	case playMovie != "":
+		mv, err := movie.Load(playMovie)
+		if err != nil {
+			return err
+		}
+		if mv.ROMChecksum != movie.Checksum(m) {
+			fmt.Println("warning: movie is recorded with another ROM")
+		}
+		e.moviePlayer = movie.NewPlayer(e.nes, mv)
+		fmt.Println("playing movie:", playMovie)
+	case recordMovie != "":
+		mv := &movie.Movie{ROMFilename: strings.TrimSuffix(romName, filepath.Ext(romName)), ROMChecksum: movie.Checksum(m), FDS: e.fds != nil}
+		e.movieRecorder = movie.NewRecorder(e.nes, mv)
+		fmt.Println("recording movie:", recordMovie)
+	default:
+		return nil
+	}
+	e.rewinder = nil
+	// cheats are not recorded into movies
+	e.nes.SetCheats(nil)
+	e.cheats.disabled = true
+	return nil
+}
+
+// saveMovie saves the movie being recorded
+func (e *Emulator) saveMovie() error {
+	if e.movieRecorder == nil {
+		return nil
+	}
+	return e.movieRecorder.Movie().Save(recordMovie)
+}
+
+// applyPatches applies patches given by flags, or <rom>.ips/.ups/.bps next to the ROM if no flags
+func applyPatches(path string, data []byte) ([]byte, error) {
+	paths := patchPaths
+	if len(paths) == 0 {
+		base := strings.TrimSuffix(path, filepath.Ext(path))
+		for _, ext := range patch.Extensions {
+			if _, err := os.Stat(base + ext); err == nil {
+				paths = append(paths, base+ext)
+			}
+		}
+	}
+
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("fail to read patch %s: %v", p, err)
+		}
+		data, err = patch.Apply(data, b)
+		if err != nil {
+			return nil, fmt.Errorf("fail to apply patch %s: %v", p, err)
+		}
+		fmt.Println("patched:", p)
+	}
+	return data, nil
+}
+
+func loadGameDB(path string) (*mapper.GameDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("fail to open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	db, err := mapper.LoadGameDB(f)
+	if err != nil {
+		return nil, fmt.Errorf("fail to load %s: %v", path, err)
+	}
+	return db, nil
+}
+
+// load restores the mapper's data from the save file if exists
+func (e *Emulator) load() error {
+	b, err := os.ReadFile(e.savePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("fail to read %s: %v", e.savePath, err)
+	}
+	if err := e.persistent.LoadSaveData(b); err != nil {
+		return fmt.Errorf("fail to load %s: %v", e.savePath, err)
+	}
+	return nil
+}
+
+// save writes the mapper's data into the save file
+func (e *Emulator) save() error {
+	if e.persistent == nil {
+		return nil
+	}
+	b := e.persistent.SaveData()
+	if b == nil {
+		return nil
+	}
+	if err := os.WriteFile(e.savePath, b, 0644); err != nil {
+		return fmt.Errorf("fail to write %s: %v", e.savePath, err)
+	}
+	return nil
+}
+
 func (e *Emulator) Update() error {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF1) {
+		switch {
+		case e.fds == nil:
+		case e.movieRecorder != nil || e.moviePlayer != nil:
+			// flipping sides is not recorded into movies
+			fmt.Println("disk sides can not be flipped while recording or playing a movie")
+		default:
+			e.fds.FlipSide()
+		}
+	}
+
+	e.updateMixer()
+	if inpututil.IsKeyJustPressed(ebiten.KeyF8) {
+		if err := e.toggleRecording(); err != nil {
+			fmt.Println("fail to record audio:", err)
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF12) {
+		if err := e.screenshot(); err != nil {
+			fmt.Println("fail to take screenshot:", err)
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF9) {
+		if err := e.toggleVideo(); err != nil {
+			fmt.Println("fail to record video:", err)
+		}
+	}
+
+	if e.cheats.update() {
+		return nil
+	}
+
+	if e.rewinder != nil && ebiten.IsKeyPressed(ebiten.KeyBackspace) {
+		if _, err := e.rewinder.StepBack(); err != nil {
+			fmt.Println("fail to rewind:", err)
+			e.rewinder = nil
+		}
+		return nil
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyF10) {
+		e.reset(ebiten.IsKeyPressed(ebiten.KeyShift))
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyPageUp) {
+		if err := e.saveQuickState(); err != nil {
+			fmt.Println("fail to save state:", err)
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyPageDown) {
+		if err := e.loadQuickState(); err != nil {
+			fmt.Println("fail to load state:", err)
+		}
+	}
+
 	e.ctrl1.update()
 	e.ctrl2.update()
-	e.nes.RunFrame()
+	e.runFrame()
+	return nil
+}
+
+// reset resets NES, or power-cycles with shift
+func (e *Emulator) reset(power bool) {
+	switch {
+	case e.moviePlayer != nil:
+		return
+	case e.movieRecorder != nil && power:
+		e.movieRecorder.PowerOn()
+	case e.movieRecorder != nil:
+		e.movieRecorder.Reset()
+	case power:
+		e.nes.PowerOn()
+		e.nes.Reset()
+	default:
+		e.nes.Reset()
+	}
+	fmt.Println("reset")
+}
+
+// saveQuickState saves the state in memory, with the frames of the movie being recorded
+func (e *Emulator) saveQuickState() error {
+	var (
+		b   []byte
+		err error
+	)
+	switch {
+	case e.moviePlayer != nil:
+		return errors.New("playing a movie")
+	case e.movieRecorder != nil:
+		b, err = e.movieRecorder.SaveState()
+	default:
+		b, err = e.nes.SaveState()
+	}
+	if err != nil {
+		return err
+	}
+	e.quickState = b
+	fmt.Println("state saved")
+	return nil
+}
+
+// loadQuickState loads the state saved by saveQuickState, which is counted as a rerecord of the movie being recorded
+func (e *Emulator) loadQuickState() error {
+	switch {
+	case e.quickState == nil:
+		return errors.New("no state saved")
+	case e.moviePlayer != nil:
+		return errors.New("playing a movie")
+	case e.movieRecorder != nil:
+		if err := e.movieRecorder.LoadState(e.quickState); err != nil {
+			return err
+		}
+	default:
+		if err := e.nes.LoadState(e.quickState); err != nil {
+			return err
+		}
+		if e.rewinder != nil {
+			// snapshots are of the timeline before loading
+			e.rewinder = gorones.NewRewinder(e.nes, gorones.RewindOptions{MemoryLimit: rewindMemory << 20})
+		}
+	}
+	fmt.Println("state loaded")
 	return nil
 }
 
+// runFrame runs a frame by the movie, or with recording for rewind if enabled
+func (e *Emulator) runFrame() {
+	switch {
+	case e.moviePlayer != nil:
+		if e.moviePlayer.RunFrame() {
+			return
+		}
+		fmt.Println("movie finished")
+		e.moviePlayer = nil
+	case e.movieRecorder != nil:
+		e.movieRecorder.RunFrame()
+		return
+	}
+
+	if e.rewinder == nil {
+		e.nes.RunFrame()
+		return
+	}
+	if err := e.rewinder.RunFrame(); err != nil {
+		fmt.Println("rewind disabled:", err)
+		e.rewinder = nil
+		e.nes.RunFrame()
+	}
+}
+
+// channelKeys toggle mute of each audio channel, or solo with shift
+var channelKeys = []ebiten.Key{ebiten.KeyF2, ebiten.KeyF3, ebiten.KeyF4, ebiten.KeyF5, ebiten.KeyF6, ebiten.KeyF7}
+
+func (e *Emulator) updateMixer() {
+	m := e.nes.AudioMixer()
+	shift := ebiten.IsKeyPressed(ebiten.KeyShift)
+	for i, k := range channelKeys {
+		if !inpututil.IsKeyJustPressed(k) {
+			continue
+		}
+		c := apu.Channels[i]
+		if shift {
+			m.SetSolo(c, !m.Soloed(c))
+			fmt.Printf("%s: solo %v\n", c, m.Soloed(c))
+		} else {
+			m.SetMute(c, !m.Muted(c))
+			fmt.Printf("%s: mute %v\n", c, m.Muted(c))
+		}
+	}
+}
+
+// toggleRecording starts recording audio into the file given by flag, or named after the ROM and time
+func (e *Emulator) toggleRecording() error {
+	if e.recorder.Recording() {
+		fmt.Println("audio recording stopped")
+		return e.recorder.Stop()
+	}
+	path := recordAudio
+	if path == "" {
+		path = e.recordPath(".wav")
+	}
+	if err := e.recorder.Start(path); err != nil {
+		return err
+	}
+	fmt.Println("recording audio:", path)
+	return nil
+}
+
+// toggleVideo starts recording video into the file given by flag, or named after the ROM and time in AVI
+func (e *Emulator) toggleVideo() error {
+	if e.video.Recording() {
+		fmt.Println("video recording stopped")
+		return e.video.Stop()
+	}
+	path := recordVideo
+	if path == "" {
+		path = e.recordPath(".avi")
+	}
+	return e.startVideo(path)
+}
+
+func (e *Emulator) startVideo(path string) error {
+	sink, err := video.Create(path, video.Options{AVICodec: aviCodec, SampleRate: 44100})
+	if err != nil {
+		return err
+	}
+	if err := e.video.Start(sink); err != nil {
+		sink.Close()
+		return err
+	}
+	fmt.Println("recording video:", path)
+	return nil
+}
+
+// screenshot writes the current frame into PNG named after the ROM and time
+func (e *Emulator) screenshot() error {
+	path := e.recordPath(".png")
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, e.nes.Screenshot()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Println("screenshot:", path)
+	return nil
+}
+
+// recordPath returns the path of a recorded file named after the ROM and time
+func (e *Emulator) recordPath(ext string) string {
+	base := strings.TrimSuffix(e.romPath, filepath.Ext(e.romPath))
+	return base + "-" + time.Now().Format("20060102-150405") + ext
+}
+
 func (e *Emulator) Draw(screen *ebiten.Image) {
 	screen.ReplacePixels(e.renderer.pixels())
-	ebitenutil.DebugPrint(screen, fmt.Sprintf("tps: %f", ebiten.CurrentTPS()))
+	if e.cheats.shown {
+		ebitenutil.DebugPrint(screen, e.cheats.String())
+		return
+	}
+	s := e.audio.Stats()
+	ebitenutil.DebugPrint(screen, fmt.Sprintf("tps: %f\naudio: %dms underruns: %d ratio: %.4f", ebiten.CurrentTPS(), s.Latency.Milliseconds(), s.Underruns, s.Ratio))
 }
 
 func (e *Emulator) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
@@ -5,16 +5,59 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/gordonklaus/portaudio"
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/thara/gorones/audio"
 	"github.com/thara/gorones/ppu"
 )
 
 var nestest bool
+var n163Clean bool
+var fdsBIOS string
+var gameDB string
+var patchPaths stringsFlag
+var audioFilter string
+var audioLatency time.Duration
+var recordAudio string
+var recordFormat string
+var recordStems bool
+var recordVideo string
+var aviCodec string
+var rewindMemory int
+var playMovie string
+var recordMovie string
+var cheatCodes stringsFlag
 
 func init() {
 	flag.BoolVar(&nestest, "nestest", false, "init for nestest")
+	flag.BoolVar(&n163Clean, "n163-clean", false, "mix Namco 163 audio channels without time-multiplexing")
+	flag.Var(&patchPaths, "patch", "path to IPS/UPS/BPS patch applied to ROM; can be repeated (default: <rom>.ips/.ups/.bps if exists)")
+	flag.StringVar(&gameDB, "gamedb", "", "path to game database in NES 2.0 XML format, which overrides the embedded one")
+	flag.StringVar(&fdsBIOS, "fds-bios", "disksys.rom", "path to Famicom Disk System BIOS ROM")
+	flag.StringVar(&audioFilter, "audio-filter", "nes", "audio output filters: nes, famicom or none")
+	flag.DurationVar(&audioLatency, "audio-latency", 50*time.Millisecond, "target latency of audio output")
+	flag.StringVar(&recordAudio, "record-audio", "", "path to WAV file to record audio from start; F8 toggles recording")
+	flag.StringVar(&recordFormat, "record-format", "pcm16", "sample format of recorded WAV: pcm16 or float32")
+	flag.BoolVar(&recordStems, "record-stems", false, "also record each audio channel into <file>.<channel>.wav")
+	flag.StringVar(&recordVideo, "record-video", "", "path to .png (numbered frames), .gif or .avi file to record video from start; F9 toggles recording")
+	flag.StringVar(&aviCodec, "avi-codec", "raw", "video codec of recorded AVI: raw or png")
+	flag.StringVar(&playMovie, "movie", "", "path to movie to play; .fm2 for FCEUX's FM2, otherwise the native format")
+	flag.StringVar(&recordMovie, "record-movie", "", "path to movie to record until exit; .fm2 for FCEUX's FM2, otherwise the native format")
+	flag.Var(&cheatCodes, "cheat", "Game Genie or address:value[:compare] code added to <rom>.cht; can be repeated; F11 toggles cheats")
+	flag.IntVar(&rewindMemory, "rewind-memory", 64, "max MB of snapshots to rewind by holding Backspace; 0 disables rewinding")
+}
+
+// stringsFlag is a flag which can be given multiple times
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
 }
 
 func main() {
@@ -28,7 +71,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	path := os.Args[1]
+	path := flag.Arg(0)
 
 	if err := portaudio.Initialize(); err != nil {
 		log.Fatalln(err)
@@ -42,22 +85,13 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
-	audio := &Audio{channel: make(chan float32, 44100)}
 	param := portaudio.HighLatencyParameters(nil, host.DefaultOutputDevice)
 	outChan := param.Output.Channels
+	pipeline := audio.NewPipeline(44100, param.SampleRate, audioLatency)
 	stream, err := portaudio.OpenStream(
 		param,
 		func(out []float32) {
-			for i := range out {
-				if i%outChan == 0 {
-					select {
-					case sample := <-audio.channel:
-						out[i] = sample
-					default:
-						out[i] = 0
-					}
-				}
-			}
+			pipeline.Read(out, outChan)
 		},
 	)
 	if err != nil {
@@ -66,10 +100,9 @@ func main() {
 	if err := stream.Start(); err != nil {
 		log.Fatalln(err)
 	}
-	audio.stream = stream
-	defer audio.stream.Close()
+	defer stream.Close()
 
-	emu, err := newEmulator(path, audio)
+	emu, err := newEmulator(path, pipeline)
 	if err != nil {
 		log.Fatalf("fail to initialize emulator for %s: %v", path, err)
 	}
@@ -78,19 +111,20 @@ func main() {
 
 	ebiten.SetWindowSize(ppu.WIDTH*scale, ppu.HEIGHT*scale)
 	ebiten.SetWindowTitle("gorones")
+	failed := false
 	if err := ebiten.RunGame(emu); err != nil {
-		log.Fatal(err)
+		log.Println(err)
+		failed = true
 	}
-}
-
-type Audio struct {
-	stream  *portaudio.Stream
-	channel chan float32
-}
-
-func (a *Audio) Write(v float32) {
-	select {
-	case a.channel <- v:
-	default:
+	// all steps run even if some fail, not to lose saved data and recorded files
+	for _, finish := range []func() error{emu.save, emu.cheats.save, emu.saveMovie, emu.recorder.Stop, emu.video.Stop} {
+		if err := finish(); err != nil {
+			log.Println(err)
+			failed = true
+		}
+	}
+	if failed {
+		stream.Close()
+		os.Exit(1)
 	}
 }
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"image/color"
+
 	"github.com/thara/gorones/ppu"
 )
 
@@ -16,23 +18,14 @@ func newRenderer() *renderer {
 
 func (r *renderer) UpdateFrame(buf *[ppu.WIDTH * ppu.HEIGHT]uint8) {
 	for i, v := range buf {
-		r.px[i*4] = byte(palette[v] >> 24 & 0xFF)
-		r.px[i*4+1] = byte(palette[v] >> 16 & 0xFF)
-		r.px[i*4+2] = byte(palette[v] >> 8 & 0xFF)
-		r.px[i*4+3] = byte(palette[v] & 0xFF)
+		c := ppu.Palette[v&0x3F].(color.RGBA)
+		r.px[i*4] = c.R
+		r.px[i*4+1] = c.G
+		r.px[i*4+2] = c.B
+		r.px[i*4+3] = c.A
 	}
 }
 
 func (r *renderer) pixels() []byte {
 	return r.px
 }
-
-var palette [255]uint32 = [255]uint32{
-	0x7C7C7CFF, 0x0000FCFF, 0x0000BCFF, 0x4428BCFF, 0x940084FF, 0xA80020FF, 0xA81000FF, 0x881400FF,
-	0x503000FF, 0x007800FF, 0x006800FF, 0x005800FF, 0x004058FF, 0x000000FF, 0x000000FF, 0x000000FF,
-	0xBCBCBCFF, 0x0078F8FF, 0x0058F8FF, 0x6844FCFF, 0xD800CCFF, 0xE40058FF, 0xF83800FF, 0xE45C10FF,
-	0xAC7C00FF, 0x00B800FF, 0x00A800FF, 0x00A844FF, 0x008888FF, 0x000000FF, 0x000000FF, 0x000000FF,
-	0xF8F8F8FF, 0x3CBCFCFF, 0x6888FCFF, 0x9878F8FF, 0xF878F8FF, 0xF85898FF, 0xF87858FF, 0xFCA044FF,
-	0xF8B800FF, 0xB8F818FF, 0x58D854FF, 0x58F898FF, 0x00E8D8FF, 0x787878FF, 0x000000FF, 0x000000FF,
-	0xFCFCFCFF, 0xA4E4FCFF, 0xB8B8F8FF, 0xD8B8F8FF, 0xF8B8F8FF, 0xF8A4C0FF, 0xF0D0B0FF, 0xFCE0A8FF,
-	0xF8D878FF, 0xD8F878FF, 0xB8F8B8FF, 0xB8F8D8FF, 0x00FCFCFF, 0xF8D8F8FF, 0x000000FF, 0x000000FF}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/png"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/thara/gorones"
+	"github.com/thara/gorones/headless"
+)
+
+var frames int
+var untilPC string
+var untilMem string
+var blargg bool
+var printHash bool
+var pngPath string
+
+func init() {
+	flag.IntVar(&frames, "frames", 3600, "max frames to run")
+	flag.StringVar(&untilPC, "until-pc", "", "stop when PC reaches the address like $C000 or 0xC000")
+	flag.StringVar(&untilMem, "until-mem", "", "stop when the memory equals the value like $00F0=$01")
+	flag.BoolVar(&blargg, "blargg", false, "stop by blargg's test ROM protocol: status at $6000 and text at $6004")
+	flag.BoolVar(&printHash, "hash", false, "print hashes of the last frame and RAM")
+	flag.StringVar(&pngPath, "png", "", "path to PNG file to save the last frame")
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Printf("Usage: %s [OPTIONS] ROM\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	path := flag.Arg(0)
+
+	c := headless.Config{Frames: frames, Blargg: blargg}
+	if untilPC != "" {
+		pc, err := parseUint(untilPC, 16)
+		if err != nil {
+			log.Fatalf("invalid -until-pc: %v", err)
+		}
+		v := uint16(pc)
+		c.PC = &v
+	}
+	if untilMem != "" {
+		m, err := parseMemory(untilMem)
+		if err != nil {
+			log.Fatalf("invalid -until-mem: %v", err)
+		}
+		c.Memory = m
+	}
+
+	nes, err := headless.Load(path)
+	if err != nil {
+		log.Fatalf("fail to initialize emulator for %s: %v", path, err)
+	}
+	r := headless.Run(nes, c)
+	fmt.Println(r)
+
+	if printHash {
+		fmt.Println("frame:", nes.FrameHash())
+		fmt.Println("ram:", nes.RAMHash())
+	}
+	if pngPath != "" {
+		if err := savePNG(nes, pngPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if !r.Passed {
+		os.Exit(1)
+	}
+}
+
+// parseUint parses a number in decimal, or in hex with "$" or "0x" prefix
+func parseUint(s string, bits int) (uint64, error) {
+	if strings.HasPrefix(s, "$") {
+		return strconv.ParseUint(s[1:], 16, bits)
+	}
+	return strconv.ParseUint(s, 0, bits)
+}
+
+func parseMemory(s string) (*headless.Memory, error) {
+	addr, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return nil, fmt.Errorf("expected ADDR=VALUE: %s", s)
+	}
+	a, err := parseUint(addr, 16)
+	if err != nil {
+		return nil, err
+	}
+	v, err := parseUint(value, 8)
+	if err != nil {
+		return nil, err
+	}
+	return &headless.Memory{Addr: uint16(a), Value: uint8(v)}, nil
+}
+
+func savePNG(nes *gorones.NES, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("fail to create %s: %v", path, err)
+	}
+	if err := png.Encode(f, nes.Screenshot()); err != nil {
+		f.Close()
+		return fmt.Errorf("fail to write %s: %v", path, err)
+	}
+	return f.Close()
+}
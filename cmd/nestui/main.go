@@ -1,22 +1,41 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
+	"image/png"
 	"log"
 	"os"
 
 	"github.com/thara/gorones"
 	"github.com/thara/gorones/input"
 	"github.com/thara/gorones/mapper"
+	"github.com/thara/gorones/movie"
 	"github.com/thara/gorones/ppu"
+	"github.com/thara/gorones/romfile"
 )
 
 var nestest bool
+var screenshot string
+var screenshotFrame int
+var moviePath string
+var verifyRAM string
+var verifyFrame string
+var search bool
+var scale int
+var fps int
 
 func init() {
 	flag.BoolVar(&nestest, "nestest", false, "init for nestest")
+	flag.StringVar(&screenshot, "screenshot", "", "path to PNG file to save the screenshot of the frame given by -screenshot-frame, and exit")
+	flag.IntVar(&screenshotFrame, "screenshot-frame", 60, "number of frames to run before the screenshot")
+	flag.StringVar(&moviePath, "movie", "", "path to movie (.fm2 or native) to play to the end, and print hashes of RAM and frame")
+	flag.StringVar(&verifyRAM, "verify-ram", "", "expected RAM hash at the end of -movie; exit with 1 if not matched")
+	flag.StringVar(&verifyFrame, "verify-frame", "", "expected frame hash at the end of -movie; exit with 1 if not matched")
+	flag.BoolVar(&search, "search", false, "search RAM by commands from stdin")
+	flag.IntVar(&scale, "scale", 2, "pixels per character of the terminal UI in width; 1 needs 256 columns")
+	flag.IntVar(&fps, "fps", 30, "frame rate to draw the terminal UI")
 }
 
 func main() {
@@ -30,9 +49,14 @@ func main() {
 		os.Exit(1)
 	}
 
-	path := os.Args[1]
+	path := flag.Arg(0)
 
-	nes, err := newNES(path)
+	ui := newTUI(os.Stdout, scale, fps)
+	var r ppu.FrameRenderer = ui
+	if screenshot != "" || moviePath != "" || search {
+		r = new(nopRenderer)
+	}
+	nes, m, err := newNES(path, r)
 	if err != nil {
 		log.Fatalf("fail to initialize emulator for %s: %v", path, err)
 	}
@@ -46,62 +70,118 @@ func main() {
 		nes.Reset()
 	}
 
-	sc := bufio.NewScanner(os.Stdin)
-	for sc.Scan() {
-		nes.RunFrame()
+	if moviePath != "" {
+		if err := playMovie(nes, m); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if screenshot != "" {
+		for i := 0; i < screenshotFrame; i++ {
+			nes.RunFrame()
+		}
+		if err := saveScreenshot(nes, screenshot); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("screenshot:", screenshot)
+		return
+	}
+
+	if search {
+		if err := newSearchShell(nes, os.Stdout).run(os.Stdin); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	ui.nes = nes
+	if err := ui.run(os.Stdin); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// playMovie plays the movie, and verifies the hashes at the end if given
+func playMovie(nes *gorones.NES, m mapper.Mapper) error {
+	mv, err := movie.Load(moviePath)
+	if err != nil {
+		return err
+	}
+	if mv.ROMChecksum != movie.Checksum(m) {
+		fmt.Println("warning: movie is recorded with another ROM")
+	}
+	p := movie.NewPlayer(nes, mv)
+	for p.RunFrame() {
+	}
+	if screenshot != "" {
+		if err := saveScreenshot(nes, screenshot); err != nil {
+			return err
+		}
+	}
+
+	ram, frame := nes.RAMHash(), nes.FrameHash()
+	fmt.Println("frames:", p.Frame())
+	fmt.Println("ram:", ram)
+	fmt.Println("frame:", frame)
+
+	ok := true
+	if verifyRAM != "" && verifyRAM != ram {
+		fmt.Println("RAM hash mismatch: expected", verifyRAM)
+		ok = false
+	}
+	if verifyFrame != "" && verifyFrame != frame {
+		fmt.Println("frame hash mismatch: expected", verifyFrame)
+		ok = false
+	}
+	if !ok {
+		os.Exit(1)
 	}
+	return nil
 }
 
-func newNES(path string) (*gorones.NES, error) {
-	f, err := os.Open(path)
+func newNES(path string, r ppu.FrameRenderer) (*gorones.NES, mapper.Mapper, error) {
+	f, err := romfile.Load(path)
 	if err != nil {
-		return nil, fmt.Errorf("fail to open %s: %v", path, err)
+		return nil, nil, fmt.Errorf("fail to open %s: %v", path, err)
 	}
-	defer f.Close()
 
-	rom, err := mapper.ParseROM(f)
+	rom, g, err := mapper.LoadROM(bytes.NewReader(f.Data))
 	if err != nil {
-		return nil, fmt.Errorf("fail to open %s: %v", path, err)
+		return nil, nil, fmt.Errorf("fail to open %s: %v", path, err)
+	}
+	if g != nil {
+		fmt.Println("game:", g.Title)
 	}
 
 	m, err := rom.Mapper()
 	if err != nil {
-		return nil, fmt.Errorf("fail to get mapper %s: %v", path, err)
+		return nil, nil, fmt.Errorf("fail to get mapper %s: %v", path, err)
 	}
 	fmt.Println(m)
 
-	ctrl := new(input.StandardController)
+	ctrl1 := new(input.StandardController)
+	ctrl2 := new(input.StandardController)
 
-	nes := gorones.NewNES(m, ctrl, ctrl, new(renderer), new(nopAudio))
-	return nes, nil
+	nes := gorones.NewNES(m, ctrl1, ctrl2, r, new(nopAudio))
+	return nes, m, nil
 }
 
-type renderer struct{}
+type nopRenderer struct{}
 
-func (r *renderer) UpdateFrame(buf *[ppu.WIDTH * ppu.HEIGHT]uint8) {
-	for i, v := range buf {
-		if i%ppu.WIDTH == 0 {
-			fmt.Printf("\n%03d", i/ppu.WIDTH)
-		}
-		c := "."
-		if palette[v] != 0x000000FF {
-			c = "*"
-		}
-		fmt.Print(c)
+func (r *nopRenderer) UpdateFrame(*[ppu.WIDTH * ppu.HEIGHT]uint8) {}
+
+func saveScreenshot(nes *gorones.NES, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("fail to create %s: %v", path, err)
 	}
-	fmt.Print("\n=================================================\n")
+	if err := png.Encode(f, nes.Screenshot()); err != nil {
+		f.Close()
+		return fmt.Errorf("fail to write %s: %v", path, err)
+	}
+	return f.Close()
 }
 
 type nopAudio struct{}
 
 func (a *nopAudio) Write(float32) {}
-
-var palette [255]uint32 = [255]uint32{
-	0x7C7C7CFF, 0x0000FCFF, 0x0000BCFF, 0x4428BCFF, 0x940084FF, 0xA80020FF, 0xA81000FF, 0x881400FF,
-	0x503000FF, 0x007800FF, 0x006800FF, 0x005800FF, 0x004058FF, 0x000000FF, 0x000000FF, 0x000000FF,
-	0xBCBCBCFF, 0x0078F8FF, 0x0058F8FF, 0x6844FCFF, 0xD800CCFF, 0xE40058FF, 0xF83800FF, 0xE45C10FF,
-	0xAC7C00FF, 0x00B800FF, 0x00A800FF, 0x00A844FF, 0x008888FF, 0x000000FF, 0x000000FF, 0x000000FF,
-	0xF8F8F8FF, 0x3CBCFCFF, 0x6888FCFF, 0x9878F8FF, 0xF878F8FF, 0xF85898FF, 0xF87858FF, 0xFCA044FF,
-	0xF8B800FF, 0xB8F818FF, 0x58D854FF, 0x58F898FF, 0x00E8D8FF, 0x787878FF, 0x000000FF, 0x000000FF,
-	0xFCFCFCFF, 0xA4E4FCFF, 0xB8B8F8FF, 0xD8B8F8FF, 0xF8B8F8FF, 0xF8A4C0FF, 0xF0D0B0FF, 0xFCE0A8FF,
-	0xF8D878FF, 0xD8F878FF, 0xB8F8B8FF, 0xB8F8D8FF, 0x00FCFCFF, 0xF8D8F8FF, 0x000000FF, 0x000000FF}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/thara/gorones"
+	"github.com/thara/gorones/cheat"
+)
+
+const searchHelp = `commands:
+  run [N]                      run N frames (default 1)
+  hold [BUTTONS]               hold buttons of controller 1 in RLDUTSBA (T: start, S: select), or release all
+  new [wram|prgram|all] [u8|s8|u16|s16]
+                               start a new search (default: all u8)
+  eq N, ne N                   keep values equal or not equal to N
+  inc N, dec N                 keep values increased or decreased by N since the last comparison
+  changed, unchanged, gt, lt   compare values with the last comparison
+  list [N]                     print N candidates (default 20)
+  cheat CODE [NAME]            apply Game Genie or address:value[:compare] code
+  help                         print this help
+  quit                         exit
+`
+
+// searchShell runs commands to search RAM for cheats or variables of games
+type searchShell struct {
+	nes    *gorones.NES
+	search *cheat.Search
+	cheats *cheat.Engine
+	// buttons held on controller 1
+	buttons uint8
+
+	out io.Writer
+}
+
+// peekMemory reads memory by NES.Peek, so that cheats being tested do not change values to search
+type peekMemory struct {
+	nes *gorones.NES
+}
+
+func (m peekMemory) ReadCPU(addr uint16) uint8 { return m.nes.Peek(addr) }
+
+func newSearchShell(nes *gorones.NES, out io.Writer) *searchShell {
+	s := &searchShell{nes: nes, cheats: cheat.NewEngine(), out: out}
+	s.search = cheat.NewSearch(peekMemory{nes}, cheat.View{Bits: 8}, cheat.WRAM, cheat.PRGRAM)
+	nes.SetCheats(s.cheats)
+	return s
+}
+
+// run reads commands until quit or EOF
+func (s *searchShell) run(r io.Reader) error {
+	fmt.Fprint(s.out, searchHelp)
+	sc := bufio.NewScanner(r)
+	for {
+		fmt.Fprintf(s.out, "[%d frames, %d candidates] > ", s.nes.Frames(), s.search.Len())
+		if !sc.Scan() {
+			return sc.Err()
+		}
+		quit, err := s.exec(sc.Text())
+		if err != nil {
+			fmt.Fprintln(s.out, "error:", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// exec runs a command, and reports whether to quit
+func (s *searchShell) exec(line string) (bool, error) {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return false, nil
+	}
+	cmd, args := args[0], args[1:]
+
+	switch cmd {
+	case "quit", "exit":
+		return true, nil
+	case "help":
+		fmt.Fprint(s.out, searchHelp)
+	case "run":
+		n, err := optionalNumber(args, 1)
+		if err != nil {
+			return false, err
+		}
+		for i := int64(0); i < n; i++ {
+			s.nes.SetInput(gorones.Input{s.buttons, 0})
+			s.nes.RunFrame()
+		}
+	case "hold":
+		b, err := parseButtons(strings.Join(args, ""))
+		if err != nil {
+			return false, err
+		}
+		s.buttons = b
+	case "new":
+		return false, s.newSearch(args)
+	case "list":
+		n, err := optionalNumber(args, 20)
+		if err != nil {
+			return false, err
+		}
+		s.list(int(n))
+	case "cheat":
+		if len(args) == 0 {
+			return false, fmt.Errorf("no code")
+		}
+		c, err := cheat.New(strings.Join(args[1:], " "), args[0])
+		if err != nil {
+			return false, err
+		}
+		s.cheats.Add(c)
+		fmt.Fprintln(s.out, "cheat:", c.Code)
+	default:
+		c, err := cheat.ParseComparison(cmd)
+		if err != nil {
+			return false, fmt.Errorf("unknown command: %s", cmd)
+		}
+		var n int64
+		if c.NeedsOperand() {
+			if len(args) == 0 {
+				return false, fmt.Errorf("%s needs a number", cmd)
+			}
+			if n, err = parseNumber(args[0]); err != nil {
+				return false, err
+			}
+		}
+		fmt.Fprintln(s.out, "candidates:", s.search.Filter(c, n))
+		if s.search.Len() <= 10 {
+			s.list(10)
+		}
+	}
+	return false, nil
+}
+
+func (s *searchShell) newSearch(args []string) error {
+	regions := []cheat.Region{cheat.WRAM, cheat.PRGRAM}
+	view := cheat.View{Bits: 8}
+	for _, a := range args {
+		switch a {
+		case "wram":
+			regions = []cheat.Region{cheat.WRAM}
+		case "prgram":
+			regions = []cheat.Region{cheat.PRGRAM}
+		case "all":
+		case "u8", "s8", "u16", "s16":
+			view = cheat.View{Bits: 8, Signed: a[0] == 's'}
+			if strings.HasSuffix(a, "16") {
+				view.Bits = 16
+			}
+		default:
+			return fmt.Errorf("unknown argument: %s", a)
+		}
+	}
+	s.search = cheat.NewSearch(peekMemory{s.nes}, view, regions...)
+	fmt.Fprintf(s.out, "new search of %s: %d candidates\n", view, s.search.Len())
+	return nil
+}
+
+// list prints the first n candidates, or all if n <= 0
+func (s *searchShell) list(n int) {
+	if n <= 0 || s.search.Len() < n {
+		n = s.search.Len()
+	}
+	for _, c := range s.search.Candidates(n) {
+		fmt.Fprintf(s.out, "$%04X  %6d -> %6d\n", c.Address, c.Previous, c.Current)
+	}
+	if n < s.search.Len() {
+		fmt.Fprintf(s.out, "... %d more\n", s.search.Len()-n)
+	}
+}
+
+// parseNumber parses a decimal, or hex with "$" or "0x"
+func parseNumber(s string) (int64, error) {
+	if strings.HasPrefix(s, "$") {
+		s = "0x" + s[1:]
+	}
+	n, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number: %s", s)
+	}
+	return n, nil
+}
+
+func optionalNumber(args []string, def int64) (int64, error) {
+	if len(args) == 0 {
+		return def, nil
+	}
+	return parseNumber(args[0])
+}
+
+// parseButtons parses buttons in letters of FM2, whose bits are the same as input.StandardControllerButton
+func parseButtons(s string) (uint8, error) {
+	const letters = "RLDUTSBA"
+	var b uint8
+	for _, r := range strings.ToUpper(s) {
+		i := strings.IndexRune(letters, r)
+		if i < 0 {
+			return 0, fmt.Errorf("unknown button: %c", r)
+		}
+		b |= 1 << (7 - i)
+	}
+	return b, nil
+}
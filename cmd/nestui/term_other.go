@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package main
+
+import "errors"
+
+func makeRaw(fd int) (func() error, error) {
+	return nil, errors.New("raw mode of terminal is not supported on this platform")
+}
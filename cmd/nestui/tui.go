@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/thara/gorones"
+	"github.com/thara/gorones/cpu"
+	"github.com/thara/gorones/input"
+	"github.com/thara/gorones/ppu"
+	"github.com/thara/gorones/video"
+)
+
+// frames to hold a button for a key press, since terminals don't tell key releases.
+// Holding a key keeps the button pressed by key repeat.
+const holdFrames = 12
+
+// escape sequences to draw a pixel in the foreground or background color
+var fgColors, bgColors = func() (fg, bg [64]string) {
+	for i, c := range ppu.Palette {
+		r, g, b, _ := c.RGBA()
+		fg[i] = fmt.Sprintf("\x1b[38;2;%d;%d;%dm", r>>8, g>>8, b>>8)
+		bg[i] = fmt.Sprintf("\x1b[48;2;%d;%d;%dm", r>>8, g>>8, b>>8)
+	}
+	return
+}()
+
+// tui is a full-screen terminal frontend, which draws 2 rows of pixels per line by half blocks in 24-bit color
+type tui struct {
+	nes   *gorones.NES
+	frame [ppu.WIDTH * ppu.HEIGHT]uint8
+
+	out   *bufio.Writer
+	buf   bytes.Buffer
+	scale int
+	fps   int
+
+	paused bool
+	debug  bool
+	// address of the memory pane
+	memAddr uint16
+
+	// remaining frames to hold each button of controller 1
+	held [8]int
+}
+
+func newTUI(out io.Writer, scale, fps int) *tui {
+	if scale < 1 {
+		scale = 1
+	}
+	if fps < 1 {
+		fps = 1
+	}
+	return &tui{out: bufio.NewWriterSize(out, 1<<16), scale: scale, fps: fps, debug: true}
+}
+
+func (t *tui) UpdateFrame(buf *[ppu.WIDTH * ppu.HEIGHT]uint8) {
+	t.frame = *buf
+}
+
+// run draws at the fixed frame rate while running the emulator at the speed of NES until quit
+func (t *tui) run(in *os.File) error {
+	restore, err := makeRaw(int(in.Fd()))
+	if err != nil {
+		return fmt.Errorf("fail to make terminal raw: %v", err)
+	}
+	defer restore()
+
+	// alternate screen without cursor
+	t.out.WriteString("\x1b[?1049h\x1b[?25l\x1b[2J")
+	defer func() {
+		t.out.WriteString("\x1b[0m\x1b[?25h\x1b[?1049l")
+		t.out.Flush()
+	}()
+
+	keys := make(chan []byte)
+	go func() {
+		defer close(keys)
+		for {
+			b := make([]byte, 64)
+			n, err := in.Read(b)
+			if err != nil {
+				return
+			}
+			keys <- b[:n]
+		}
+	}()
+
+	ticker := time.NewTicker(time.Second / time.Duration(t.fps))
+	defer ticker.Stop()
+
+	// frames run since start, to keep the speed of NES
+	start, frames := time.Now(), 0
+	for {
+		select {
+		case b, ok := <-keys:
+			if !ok || t.handleKeys(b) {
+				return nil
+			}
+		case now := <-ticker.C:
+			if t.paused {
+				start, frames = now, 0
+			} else {
+				target := int(now.Sub(start).Seconds() * video.FrameRateNum / video.FrameRateDen)
+				// give up catching up if too slow
+				if frames+4 < target {
+					start, frames = now, 0
+					target = 1
+				}
+				for ; frames < target; frames++ {
+					t.runFrame()
+				}
+			}
+			if err := t.draw(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (t *tui) runFrame() {
+	var buttons uint8
+	for i, n := range t.held {
+		if 0 < n {
+			buttons |= 1 << i
+			t.held[i]--
+		}
+	}
+	t.nes.SetInput(gorones.Input{buttons, 0})
+	t.nes.RunFrame()
+}
+
+func (t *tui) press(b input.StandardControllerButton) {
+	for i := range t.held {
+		if b&(1<<i) != 0 {
+			t.held[i] = holdFrames
+		}
+	}
+}
+
+// handleKeys handles keys read from the terminal, and reports whether to quit
+func (t *tui) handleKeys(b []byte) bool {
+	for i := 0; i < len(b); i++ {
+		// arrow keys in normal or application mode
+		if b[i] == 0x1b && i+2 < len(b) && (b[i+1] == '[' || b[i+1] == 'O') {
+			switch b[i+2] {
+			case 'A':
+				t.press(input.StandardUp)
+			case 'B':
+				t.press(input.StandardDown)
+			case 'C':
+				t.press(input.StandardRight)
+			case 'D':
+				t.press(input.StandardLeft)
+			}
+			i += 2
+			continue
+		}
+
+		switch b[i] {
+		case 'w', 'W':
+			t.press(input.StandardUp)
+		case 'a', 'A':
+			t.press(input.StandardLeft)
+		case 's', 'S':
+			t.press(input.StandardDown)
+		case 'd', 'D':
+			t.press(input.StandardRight)
+		case 'j', 'J':
+			t.press(input.StandardB)
+		case 'k', 'K':
+			t.press(input.StandardA)
+		case ' ':
+			t.press(input.StandardStart)
+		case '\r', '\n':
+			t.press(input.StandardSelect)
+
+		case 'p':
+			t.paused = !t.paused
+		case 'n':
+			if t.paused {
+				t.runFrame()
+			}
+		case 'r':
+			t.nes.Reset()
+		case '\t':
+			t.debug = !t.debug
+			t.out.WriteString("\x1b[0m\x1b[2J")
+		case '[':
+			t.memAddr -= 0x80
+		case ']':
+			t.memAddr += 0x80
+		case 'q', 0x03:
+			return true
+		}
+	}
+	return false
+}
+
+func (t *tui) draw() error {
+	t.buf.Reset()
+	t.drawFrame()
+	if t.debug {
+		t.drawPanes()
+	}
+	t.buf.WriteString("\x1b[0m")
+	if _, err := t.out.Write(t.buf.Bytes()); err != nil {
+		return err
+	}
+	return t.out.Flush()
+}
+
+// drawFrame draws the frame by "▀" whose foreground is the upper pixel and background is the lower one
+func (t *tui) drawFrame() {
+	s := t.scale
+	for y, line := 0, 1; y+s < ppu.HEIGHT; y, line = y+2*s, line+1 {
+		fmt.Fprintf(&t.buf, "\x1b[%d;1H", line)
+		fg, bg := -1, -1
+		for x := 0; x < ppu.WIDTH; x += s {
+			top := int(t.frame[y*ppu.WIDTH+x] & 0x3F)
+			bottom := int(t.frame[(y+s)*ppu.WIDTH+x] & 0x3F)
+			if top != fg {
+				t.buf.WriteString(fgColors[top])
+				fg = top
+			}
+			if bottom != bg {
+				t.buf.WriteString(bgColors[bottom])
+				bg = bottom
+			}
+			t.buf.WriteString("▀")
+		}
+	}
+}
+
+// drawPanes draws registers, disassembly and memory on the right of the frame
+func (t *tui) drawPanes() {
+	col := ppu.WIDTH/t.scale + 3
+	line := 1
+	print := func(format string, args ...interface{}) {
+		fmt.Fprintf(&t.buf, "\x1b[0m\x1b[%d;%dH", line, col)
+		fmt.Fprintf(&t.buf, format, args...)
+		t.buf.WriteString("\x1b[K")
+		line++
+	}
+
+	tr := t.nes.Trace()
+	state := "running"
+	if t.paused {
+		state = "paused"
+	}
+	print("%s", tr)
+	print("CYC:%d  frame:%d  %s", tr.Cycles, t.nes.Frames(), state)
+	print("")
+
+	pc := tr.PC
+	for i := 0; i < 12; i++ {
+		code, next := cpu.Disassemble(t.nes.Peek, pc)
+		cursor := " "
+		if i == 0 {
+			cursor = ">"
+		}
+		print("%s %04X  %s", cursor, pc, code)
+		pc = next
+	}
+	print("")
+
+	for row := 0; row < 16; row++ {
+		addr := t.memAddr + uint16(row*8)
+		var b [8]uint8
+		for i := range b {
+			b[i] = t.nes.Peek(addr + uint16(i))
+		}
+		print("%04X: % X", addr, b)
+	}
+	print("")
+
+	print("WASD/arrows J:B K:A Space:start Enter:select")
+	print("p:pause n:next frame r:reset Tab:debug [/]:memory q:quit")
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gordonklaus/portaudio"
+	"github.com/thara/gorones/apu"
+	"github.com/thara/gorones/audio"
+	"github.com/thara/gorones/mapper"
+	"github.com/thara/gorones/nsf"
+	"github.com/thara/gorones/romfile"
+)
+
+var (
+	track  int
+	length time.Duration
+	fade   time.Duration
+	output string
+	filter string
+)
+
+const (
+	defaultLength = 3 * time.Minute
+	defaultFade   = 5 * time.Second
+)
+
+func init() {
+	flag.IntVar(&track, "track", 0, "track number to play, 1-based (default: the start song in header)")
+	flag.DurationVar(&length, "length", 0, "play length of the track before fade out (default: from NSFe metadata, or 3m)")
+	flag.DurationVar(&fade, "fade", -1, "fade out length (default: from NSFe metadata, or 5s)")
+	flag.StringVar(&filter, "audio-filter", "nes", "audio output filters: nes, famicom or none")
+	flag.StringVar(&output, "o", "", "path to WAV file to render the track into instead of playing")
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Printf("Usage: %s [OPTIONS] NSF\n", os.Args[0])
+		fmt.Println("Play a track in NSF/NSFe")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	file, err := romfile.Load(flag.Arg(0))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	f, err := nsf.Parse(bytes.NewReader(file.Data))
+	if err != nil {
+		log.Fatalf("fail to load %s: %v", file.Name(), err)
+	}
+	if c := mapper.UnsupportedNSFChips(f.Chips); c != 0 {
+		log.Fatalf("unsupported expansion sound chips: %s", strings.Join(mapper.NSFChipNames(c), ", "))
+	}
+
+	fmt.Printf("%s - %s (%s)\n", f.Title, f.Artist, f.Copyright)
+	for i := 0; i < f.Songs; i++ {
+		t := f.Track(i)
+		fmt.Printf("  %2d: %s %s\n", i+1, t.Label, formatLength(t.Length))
+	}
+
+	song := f.StartSong
+	if 0 < track {
+		song = track - 1
+	}
+	if song < 0 || f.Songs <= song {
+		log.Fatalf("invalid track: %d", song+1)
+	}
+	t := f.Track(song)
+	if length <= 0 {
+		length = t.Length
+	}
+	if length <= 0 {
+		length = defaultLength
+	}
+	if fade < 0 {
+		fade = t.Fade
+		if t.Length <= 0 && fade <= 0 {
+			fade = defaultFade
+		}
+	}
+	fmt.Printf("track %d: %s\n", song+1, formatLength(length+fade))
+
+	filters, ok := apu.FilterPresets[filter]
+	if !ok {
+		log.Fatalf("unknown audio filter: %s", filter)
+	}
+
+	if output != "" {
+		if err := render(f, song, filters, output); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+	if err := play(f, song, filters); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func formatLength(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return d.Round(time.Second).String()
+}
+
+// fader applies fade out to samples after length.
+type fader struct {
+	n            int
+	length, fade float64 // in samples
+	write        func(float32)
+}
+
+// start resets the position at the beginning of the track.
+func (f *fader) start(sampleRate float64) {
+	f.n = 0
+	f.length = length.Seconds() * sampleRate
+	f.fade = fade.Seconds() * sampleRate
+}
+
+func (f *fader) Write(v float32) {
+	if f.done() {
+		return
+	}
+	if over := float64(f.n) - f.length; 0 < over {
+		v *= float32(1 - over/f.fade)
+	}
+	f.n++
+	f.write(v)
+}
+
+func (f *fader) done() bool {
+	return f.length+f.fade <= float64(f.n)
+}
+
+func render(f *nsf.File, song int, filters []apu.Filter, path string) (err error) {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	var (
+		w    *audio.WAVWriter
+		werr error
+	)
+	fd := &fader{write: func(v float32) {
+		if werr == nil {
+			werr = w.WriteFrame(v)
+		}
+	}}
+	p := nsf.NewPlayer(f, fd)
+	w, err = audio.NewWAVWriter(out, int(math.Round(p.SampleRate())), 1, audio.WAVPCM16)
+	if err != nil {
+		return err
+	}
+	p.SetFilters(filters...)
+	p.Start(song)
+	fd.start(p.SampleRate())
+	p.Run(length + fade)
+	if werr != nil {
+		return werr
+	}
+	return w.Close()
+}
+
+func play(f *nsf.File, song int, filters []apu.Filter) error {
+	if err := portaudio.Initialize(); err != nil {
+		return err
+	}
+	defer portaudio.Terminate()
+	host, err := portaudio.DefaultHostApi()
+	if err != nil {
+		return err
+	}
+
+	param := portaudio.HighLatencyParameters(nil, host.DefaultOutputDevice)
+	outChan := param.Output.Channels
+
+	const latency = 100 * time.Millisecond
+	var pipeline *audio.Pipeline
+	fd := &fader{write: func(v float32) { pipeline.Write(v) }}
+	p := nsf.NewPlayer(f, fd)
+	pipeline = audio.NewPipeline(p.SampleRate(), param.SampleRate, latency)
+	p.SetFilters(filters...)
+	p.Start(song)
+	fd.start(p.SampleRate())
+
+	stream, err := portaudio.OpenStream(
+		param,
+		func(out []float32) {
+			pipeline.Read(out, outChan)
+		},
+	)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	if err := stream.Start(); err != nil {
+		return err
+	}
+
+	for !fd.done() {
+		p.Run(10 * time.Millisecond)
+		// paced by the device consuming samples
+		for latency < pipeline.Stats().Latency {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	// drain samples left in the pipeline
+	for 0 < pipeline.Stats().Latency {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return stream.Stop()
+}
@@ -0,0 +1,67 @@
+package cpu
+
+import "fmt"
+
+var mnemonicNames = [...]string{
+	"???",
+	"LDA", "LDX", "LDY", "STA", "STX", "STY",
+	"TAX", "TSX", "TAY", "TXA", "TXS", "TYA",
+	"PHA", "PHP", "PLA", "PLP",
+	"AND", "EOR", "ORA", "BIT",
+	"ADC", "SBC", "CMP", "CPX", "CPY",
+	"INC", "INX", "INY", "DEC", "DEX", "DEY",
+	"ASL", "LSR", "ROL", "ROR",
+	"JMP", "JSR", "RTS", "RTI",
+	"BCC", "BCS", "BEQ", "BMI", "BNE", "BPL", "BVC", "BVS",
+	"CLC", "CLD", "CLI", "CLV", "SEC", "SED", "SEI",
+	"BRK", "NOP",
+	"LAX", "SAX", "DCP", "ISB", "SLO", "RLA", "SRE", "RRA",
+}
+
+func (m mnemonic) String() string {
+	if int(m) < len(mnemonicNames) {
+		return mnemonicNames[m]
+	}
+	return fmt.Sprintf("mnemonic(%d)", m)
+}
+
+// Disassemble returns the instruction at pc in assembly, and the address of the next instruction.
+//
+// read should have no side effects, so that it doesn't change the state of the emulator.
+func Disassemble(read func(addr uint16) uint8, pc uint16) (string, uint16) {
+	inst := Decode(read(pc))
+	next := pc + uint16(inst.AddressingMode.instructionLength())
+	op1 := read(pc + 1)
+	word := uint16(op1) | uint16(read(pc+2))<<8
+
+	var operand string
+	switch inst.AddressingMode {
+	case implicit:
+		return inst.Mnemonic.String(), next
+	case accumulator:
+		operand = "A"
+	case immediate:
+		operand = fmt.Sprintf("#$%02X", op1)
+	case zeroPage:
+		operand = fmt.Sprintf("$%02X", op1)
+	case zeroPageX:
+		operand = fmt.Sprintf("$%02X,X", op1)
+	case zeroPageY:
+		operand = fmt.Sprintf("$%02X,Y", op1)
+	case absolute:
+		operand = fmt.Sprintf("$%04X", word)
+	case absoluteX, absoluteXWithPenalty:
+		operand = fmt.Sprintf("$%04X,X", word)
+	case absoluteY, absoluteYWithPenalty:
+		operand = fmt.Sprintf("$%04X,Y", word)
+	case relative:
+		operand = fmt.Sprintf("$%04X", next+uint16(int8(op1)))
+	case indirect:
+		operand = fmt.Sprintf("($%04X)", word)
+	case indexedIndirect:
+		operand = fmt.Sprintf("($%02X,X)", op1)
+	case indirectIndexed, indirectIndexedWithPenalty:
+		operand = fmt.Sprintf("($%02X),Y", op1)
+	}
+	return inst.Mnemonic.String() + " " + operand, next
+}
@@ -32,7 +32,7 @@ func (c *CPU) handleInterrupt(intr *Interrupt) {
 		return
 	case NMI: // interrupted
 	case IRQ:
-		if !c.P[status_I] {
+		if c.P[status_I] {
 			return
 		}
 		// interrupted
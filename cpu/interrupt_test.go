@@ -0,0 +1,40 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCPU_handleInterrupt(t *testing.T) {
+	bus := newBusMock()
+	// NOP at the reset point and each handler
+	bus[0x8000], bus[0x9000], bus[0xA000] = 0xEA, 0xEA, 0xEA
+	bus[0xFFFA], bus[0xFFFB] = 0x00, 0xA0
+	bus[0xFFFE], bus[0xFFFF] = 0x00, 0x90
+
+	c := New(tickMock, bus)
+	c.PC, c.S = 0x8000, 0xFD
+
+	// IRQ is ignored while masked, and kept pending
+	c.P[status_I] = true
+	intr := IRQ
+	c.Step(&intr)
+	assert.Equal(t, IRQ, intr)
+	assert.EqualValues(t, 0x8001, c.PC)
+
+	c.P[status_I] = false
+	c.Step(&intr)
+	assert.Equal(t, NoInterrupt, intr)
+	assert.EqualValues(t, 0x9001, c.PC)
+	assert.True(t, c.P[status_I], "masked in the handler")
+	assert.EqualValues(t, 0x80, bus[0x01FD], "return address")
+	assert.EqualValues(t, 0x01, bus[0x01FC], "return address")
+	assert.Zero(t, bus[0x01FB]&0x10, "B flag is clear")
+
+	// NMI is taken even while masked
+	intr = NMI
+	c.Step(&intr)
+	assert.Equal(t, NoInterrupt, intr)
+	assert.EqualValues(t, 0xA001, c.PC)
+}
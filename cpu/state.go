@@ -0,0 +1,14 @@
+package cpu
+
+import "github.com/thara/gorones/state"
+
+func (c *CPU) Serialize(s *state.Serializer) {
+	s.Section("CPU")
+	s.Uint8(&c.A)
+	s.Uint8(&c.X)
+	s.Uint8(&c.Y)
+	s.Uint8(&c.S)
+	s.Bools(c.P[:])
+	s.Uint16(&c.PC)
+	s.Uint64(&c.Cycles)
+}
@@ -0,0 +1,164 @@
+// Package headless runs ROMs without video and audio output until conditions are met, for automated testing.
+package headless
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/thara/gorones"
+	"github.com/thara/gorones/input"
+	"github.com/thara/gorones/mapper"
+	"github.com/thara/gorones/ppu"
+	"github.com/thara/gorones/romfile"
+)
+
+type nopFrameRenderer struct{}
+
+func (nopFrameRenderer) UpdateFrame(*[ppu.WIDTH * ppu.HEIGHT]uint8) {}
+
+type nopAudioRenderer struct{}
+
+func (nopAudioRenderer) Write(float32) {}
+
+// Load loads a ROM and returns NES after power on and reset.
+func Load(path string) (*gorones.NES, error) {
+	f, err := romfile.Load(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s", path)
+	}
+	rom, _, err := mapper.LoadROM(bytes.NewReader(f.Data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", path)
+	}
+	m, err := rom.Mapper()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get mapper of %s", path)
+	}
+
+	var ctrl1, ctrl2 input.StandardController
+	nes := gorones.NewNES(m, &ctrl1, &ctrl2, nopFrameRenderer{}, nopAudioRenderer{})
+	nes.PowerOn()
+	nes.Reset()
+	return nes, nil
+}
+
+// Memory is a condition met when the byte at the address equals the value.
+type Memory struct {
+	Addr  uint16
+	Value uint8
+}
+
+// Config are conditions to stop running. It passes when any condition is met, or fails by timeout.
+// Without conditions, it runs the max frames and passes.
+type Config struct {
+	// max frames to run
+	Frames int
+
+	// address of PC to stop, checked for each instruction
+	PC *uint16
+	// memory to stop, checked for each frame
+	Memory *Memory
+	// blargg's test ROM protocol, where the status at $6000 is the result and $6004 is the text
+	Blargg bool
+}
+
+func (c Config) hasCondition() bool {
+	return c.PC != nil || c.Memory != nil || c.Blargg
+}
+
+// Result is the result of Run.
+type Result struct {
+	Passed bool
+	// frames run
+	Frames int
+	// description of the result, like the text of blargg's test ROMs
+	Message string
+	// result code of blargg's test ROMs
+	Code uint8
+}
+
+func (r Result) String() string {
+	status := "FAILED"
+	if r.Passed {
+		status = "PASSED"
+	}
+	return fmt.Sprintf("%s at frame %d: %s", status, r.Frames, r.Message)
+}
+
+// https://github.com/christopherpow/nes-test-roms/blob/master/blargg_ppu_tests_2005.09.15b/readme.txt
+
+const (
+	blarggRunning    = 0x80
+	blarggNeedsReset = 0x81
+	// frames to wait before reset, which must be at least 100 ms
+	blarggResetDelay = 6
+)
+
+var blarggMagic = []uint8{0xDE, 0xB0, 0x61}
+
+// Run runs NES until the conditions are met.
+func Run(nes *gorones.NES, c Config) Result {
+	resetAt := -1
+	for frame := 0; frame < c.Frames; frame++ {
+		before := nes.Frames()
+		for before == nes.Frames() {
+			nes.Step()
+			if c.PC != nil && nes.PC() == *c.PC {
+				return Result{Passed: true, Frames: frame, Message: fmt.Sprintf("PC reached $%04X", *c.PC)}
+			}
+		}
+
+		if m := c.Memory; m != nil {
+			if v := nes.ReadCPU(m.Addr); v == m.Value {
+				return Result{Passed: true, Frames: frame + 1, Message: fmt.Sprintf("$%04X = $%02X", m.Addr, v)}
+			}
+		}
+
+		if c.Blargg && blarggReady(nes) {
+			switch status := nes.ReadCPU(0x6000); {
+			case status == blarggRunning:
+			case status == blarggNeedsReset:
+				if resetAt < 0 {
+					resetAt = frame + blarggResetDelay
+				} else if frame == resetAt {
+					nes.Reset()
+					resetAt = -1
+				}
+			default:
+				return Result{Passed: status == 0, Frames: frame + 1, Message: blarggText(nes), Code: status}
+			}
+		}
+	}
+
+	if c.hasCondition() {
+		msg := "timeout"
+		if c.Blargg && blarggReady(nes) {
+			msg += ": " + blarggText(nes)
+		}
+		return Result{Frames: c.Frames, Message: msg}
+	}
+	return Result{Passed: true, Frames: c.Frames, Message: "frames completed"}
+}
+
+func blarggReady(nes *gorones.NES) bool {
+	for i, v := range blarggMagic {
+		if nes.ReadCPU(0x6001+uint16(i)) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// blarggText returns the text at $6004 terminated by zero.
+func blarggText(nes *gorones.NES) string {
+	var b []byte
+	for addr := uint16(0x6004); addr < 0x8000; addr++ {
+		v := nes.ReadCPU(addr)
+		if v == 0 {
+			break
+		}
+		b = append(b, v)
+	}
+	return string(bytes.TrimSpace(b))
+}
@@ -0,0 +1,95 @@
+package headless
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testROM writes NROM whose program at $C000 reports the status by blargg's protocol, and loops at $C0xx.
+func testROM(t *testing.T, status uint8) (string, uint16) {
+	var code []byte
+	sta := func(v uint8, addr uint16) {
+		code = append(code, 0xA9, v, 0x8D, byte(addr), byte(addr>>8))
+	}
+	sta(0x80, 0x6000)
+	sta(0xDE, 0x6001)
+	sta(0xB0, 0x6002)
+	sta(0x61, 0x6003)
+	for i, c := range []byte("\nOK \n\x00") {
+		sta(c, 0x6004+uint16(i))
+	}
+	sta(status, 0x6000)
+	loop := 0xC000 + uint16(len(code))
+	code = append(code, 0x4C, byte(loop), byte(loop>>8))
+
+	prg := make([]byte, 0x4000)
+	copy(prg, code)
+	// NMI, RESET and IRQ vectors
+	copy(prg[0x3FFA:], []byte{0x00, 0xC0, 0x00, 0xC0, 0x00, 0xC0})
+
+	b := append([]byte{'N', 'E', 'S', 0x1A, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, prg...)
+	b = append(b, make([]byte, 0x2000)...)
+	path := filepath.Join(t.TempDir(), "test.nes")
+	require.NoError(t, os.WriteFile(path, b, 0644))
+	return path, loop
+}
+
+func TestRun(t *testing.T) {
+	t.Run("blargg passed", func(t *testing.T) {
+		path, _ := testROM(t, 0)
+		nes, err := Load(path)
+		require.NoError(t, err)
+		r := Run(nes, Config{Frames: 10, Blargg: true})
+		assert.True(t, r.Passed)
+		assert.Equal(t, "OK", r.Message)
+		assert.Equal(t, 1, r.Frames)
+	})
+	t.Run("blargg failed", func(t *testing.T) {
+		path, _ := testROM(t, 3)
+		nes, err := Load(path)
+		require.NoError(t, err)
+		r := Run(nes, Config{Frames: 10, Blargg: true})
+		assert.False(t, r.Passed)
+		assert.EqualValues(t, 3, r.Code)
+	})
+	t.Run("blargg running", func(t *testing.T) {
+		path, _ := testROM(t, 0x80)
+		nes, err := Load(path)
+		require.NoError(t, err)
+		r := Run(nes, Config{Frames: 10, Blargg: true})
+		assert.False(t, r.Passed)
+		assert.Equal(t, 10, r.Frames)
+		assert.Equal(t, "timeout: OK", r.Message)
+	})
+	t.Run("PC", func(t *testing.T) {
+		path, loop := testROM(t, 0x80)
+		nes, err := Load(path)
+		require.NoError(t, err)
+		r := Run(nes, Config{Frames: 10, PC: &loop})
+		assert.True(t, r.Passed)
+		assert.Equal(t, 0, r.Frames)
+		assert.Equal(t, loop, nes.PC())
+	})
+	t.Run("memory", func(t *testing.T) {
+		path, _ := testROM(t, 0x80)
+		nes, err := Load(path)
+		require.NoError(t, err)
+		r := Run(nes, Config{Frames: 10, Memory: &Memory{Addr: 0x6003, Value: 0x61}})
+		assert.True(t, r.Passed)
+
+		r = Run(nes, Config{Frames: 10, Memory: &Memory{Addr: 0x6003, Value: 0x00}})
+		assert.False(t, r.Passed)
+	})
+	t.Run("frames", func(t *testing.T) {
+		path, _ := testROM(t, 0x80)
+		nes, err := Load(path)
+		require.NoError(t, err)
+		r := Run(nes, Config{Frames: 5})
+		assert.True(t, r.Passed)
+		assert.Equal(t, 5, r.Frames)
+	})
+}
@@ -0,0 +1,294 @@
+package headless
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thara/gorones"
+	"github.com/thara/gorones/movie"
+)
+
+// Test ROMs other than nestest are not in this repository.
+// Clone https://github.com/christopherpow/nes-test-roms into testdata/nes-test-roms to run them.
+
+var (
+	reportPath  = flag.String("report", "", "path to write the accuracy report of TestROMSuite in Markdown")
+	requireROMs = flag.Bool("require-roms", false, "fail TestROMSuite on missing ROMs instead of skipping them")
+)
+
+// directory of nes-test-roms under testdata
+const testROMsPath = "nes-test-roms/"
+
+type accuracyROM struct {
+	// path under testdata
+	path string
+	// max frames to run
+	frames int
+
+	// pass criteria, all of which given are checked
+	blargg    bool
+	frameHash string
+	ram       map[uint16]uint8
+
+	// movie under testdata played as input
+	movie string
+
+	// reason why it fails now, which must be removed once it passes
+	knownFailure string
+}
+
+func (r accuracyROM) name() string {
+	if r.movie != "" {
+		return r.path + " (" + r.movie + ")"
+	}
+	return r.path
+}
+
+func (r accuracyROM) criteria() string {
+	var c []string
+	if r.blargg {
+		c = append(c, "$6000")
+	}
+	if r.frameHash != "" {
+		c = append(c, "screen")
+	}
+	if r.ram != nil {
+		c = append(c, "RAM")
+	}
+	return strings.Join(c, ", ")
+}
+
+const blarggFrames = 1200
+
+func blarggROMs(dir string, knownFailure string, names ...string) []accuracyROM {
+	roms := make([]accuracyROM, len(names))
+	for i, n := range names {
+		roms[i] = accuracyROM{path: testROMsPath + dir + "/" + n + ".nes", frames: blarggFrames, blargg: true, knownFailure: knownFailure}
+	}
+	return roms
+}
+
+// blargg's tests before the $6000 protocol write the result code into $F8, where 1 is passed
+const legacyBlarggFrames = 600
+
+func legacyBlarggROMs(dir string, knownFailure string, names ...string) []accuracyROM {
+	roms := make([]accuracyROM, len(names))
+	for i, n := range names {
+		roms[i] = accuracyROM{path: testROMsPath + dir + "/" + n + ".nes", frames: legacyBlarggFrames, ram: map[uint16]uint8{0x00F8: 1}, knownFailure: knownFailure}
+	}
+	return roms
+}
+
+var accuracyROMs = concat(
+	[]accuracyROM{
+		// kevtris
+		{
+			path: "nestest.nes", movie: "nestest.fm2", frames: 182,
+			frameHash: "2bfe5ffe2fae65fa730c04735a3b25115c5fb65e",
+			// error codes of official and unofficial opcodes
+			ram: map[uint16]uint8{0x0002: 0, 0x0003: 0},
+		},
+		{
+			path: "nestest.nes", movie: "nestest-invalid.fm2", frames: 214,
+			frameHash: "0b6895e6ff0e8be76e805a067be6ebec89e7d6ad",
+			ram:       map[uint16]uint8{0x0002: 0, 0x0003: 0},
+		},
+	},
+	// Known failures of nes-test-roms are not recorded yet, since testdata/accuracy.md has not been generated with them.
+	// Empty ones below are unverified until then.
+	blarggROMs("instr_test-v5/rom_singles", "",
+		"01-basics", "02-implied", "03-immediate", "04-zero_page", "05-zp_xy", "06-absolute", "07-abs_xy", "08-ind_x",
+		"09-ind_y", "10-branches", "11-stack", "12-jmp_jsr", "13-rts", "14-rti", "15-brk", "16-special"),
+	blarggROMs("instr_timing/rom_singles", "", "1-instr_timing", "2-branch_timing"),
+	blarggROMs("cpu_interrupts_v2/rom_singles", "",
+		"1-cli_latency", "2-nmi_and_brk", "3-nmi_and_irq", "4-irq_and_dma", "5-branch_delays_irq"),
+	blarggROMs("ppu_vbl_nmi/rom_singles", "",
+		"01-vbl_basics", "02-vbl_set_time", "03-vbl_clear_time", "04-nmi_control", "05-nmi_timing",
+		"06-suppression", "07-nmi_on_timing", "08-nmi_off_timing", "09-even_odd_frames", "10-even_odd_timing"),
+	blarggROMs("apu_test/rom_singles", "",
+		"1-len_ctr", "2-len_table", "3-irq_flag", "4-jitter", "5-len_timing", "6-irq_flag_timing", "7-dmc_basics", "8-dmc_rates"),
+	legacyBlarggROMs("sprite_hit_tests_2005.10.05", "",
+		"01.basics", "02.alignment", "03.corners", "04.flip", "05.left_clip", "06.right_edge",
+		"07.screen_bottom", "08.double_height", "09.timing_basics", "10.timing_order", "11.edge_timing"),
+	blarggROMs("mmc3_test_2/rom_singles", "mapper 4 (MMC3) is not implemented",
+		"1-clocking", "2-details", "3-A12_clocking", "4-scanline_timing", "5-MMC3", "6-MMC3_alt"),
+)
+
+func concat(lists ...[]accuracyROM) (all []accuracyROM) {
+	for _, l := range lists {
+		all = append(all, l...)
+	}
+	return
+}
+
+type accuracyResult struct {
+	rom     accuracyROM
+	status  string
+	frames  int
+	message string
+}
+
+// TestROMSuite runs test ROMs, where missing ROMs are skipped.
+func TestROMSuite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip test ROMs in short mode")
+	}
+
+	var results []accuracyResult
+	for _, rom := range accuracyROMs {
+		rom := rom
+		t.Run(rom.name(), func(t *testing.T) {
+			r := runAccuracyROM(t, rom)
+			results = append(results, r)
+			switch r.status {
+			case "missing":
+				if *requireROMs {
+					t.Error("missing ROM")
+					return
+				}
+				t.Skip("missing ROM")
+			case "unexpected pass":
+				t.Errorf("passed, so remove the known failure: %s", rom.knownFailure)
+			case "fail":
+				t.Errorf("failed at frame %d: %s", r.frames, r.message)
+			}
+		})
+	}
+
+	if onlyNestest(results) {
+		t.Log("testdata/" + testROMsPath + " is not found, so only nestest was tested; -require-roms fails on it")
+	}
+	if *reportPath != "" {
+		require.NoError(t, os.WriteFile(*reportPath, []byte(accuracyReport(results)), 0644))
+	}
+}
+
+// onlyNestest reports whether all ROMs of nes-test-roms are missing.
+func onlyNestest(results []accuracyResult) bool {
+	for _, r := range results {
+		if strings.HasPrefix(r.rom.path, testROMsPath) && r.status != "missing" {
+			return false
+		}
+	}
+	return true
+}
+
+func runAccuracyROM(t *testing.T, rom accuracyROM) accuracyResult {
+	result := accuracyResult{rom: rom}
+	path := filepath.Join("..", "testdata", rom.path)
+	if _, err := os.Stat(path); err != nil {
+		result.status = "missing"
+		return result
+	}
+
+	passed, frames, msg := checkAccuracyROM(t, path, rom)
+	result.frames, result.message = frames, msg
+	switch {
+	case passed && rom.knownFailure != "":
+		result.status = "unexpected pass"
+	case passed:
+		result.status = "pass"
+	case rom.knownFailure != "":
+		result.status = "known failure"
+		result.message = rom.knownFailure
+	default:
+		result.status = "fail"
+	}
+	return result
+}
+
+// checkAccuracyROM runs the ROM and checks all criteria.
+func checkAccuracyROM(t *testing.T, path string, rom accuracyROM) (bool, int, string) {
+	nes, err := Load(path)
+	if err != nil {
+		return false, 0, err.Error()
+	}
+
+	var frames int
+	if rom.movie != "" {
+		mv, err := movie.Load(filepath.Join("..", "testdata", rom.movie))
+		require.NoError(t, err)
+		p := movie.NewPlayer(nes, mv)
+		for frames < rom.frames && p.RunFrame() {
+			frames++
+		}
+	}
+
+	var msgs []string
+	if rom.blargg {
+		r := Run(nes, Config{Frames: rom.frames - frames, Blargg: true})
+		frames += r.Frames
+		if !r.Passed {
+			return false, frames, r.Message
+		}
+		msgs = append(msgs, r.Message)
+	} else if frames < rom.frames {
+		frames += Run(nes, Config{Frames: rom.frames - frames}).Frames
+	}
+
+	if rom.frameHash != "" {
+		if h := nes.FrameHash(); h != rom.frameHash {
+			return false, frames, "frame hash " + h
+		}
+	}
+	if msg, ok := checkRAM(nes, rom.ram); !ok {
+		return false, frames, msg
+	}
+	return true, frames, strings.Join(msgs, " ")
+}
+
+func checkRAM(nes *gorones.NES, ram map[uint16]uint8) (string, bool) {
+	for addr, want := range ram {
+		if v := nes.ReadCPU(addr); v != want {
+			return fmt.Sprintf("$%04X = $%02X, expected $%02X", addr, v, want), false
+		}
+	}
+	return "", true
+}
+
+// accuracyReport returns a Markdown table of the results.
+func accuracyReport(results []accuracyResult) string {
+	count := map[string]int{}
+	var b strings.Builder
+	b.WriteString("| ROM | Criteria | Result | Frames | Message |\n")
+	b.WriteString("|---|---|---|---|---|\n")
+	for _, r := range results {
+		count[r.status]++
+		msg := strings.ReplaceAll(strings.ReplaceAll(r.message, "\n", " "), "|", "\\|")
+		fmt.Fprintf(&b, "| %s | %s | %s | %d | %s |\n", r.rom.name(), r.rom.criteria(), r.status, r.frames, msg)
+	}
+	tested := len(results) - count["missing"]
+	fmt.Fprintf(&b, "\n%d/%d passed, %d known failures, %d missing\n",
+		count["pass"]+count["unexpected pass"], tested, count["known failure"], count["missing"])
+	return b.String()
+}
+
+func TestAccuracyReport(t *testing.T) {
+	rom := accuracyROM{path: "a.nes", blargg: true, frameHash: "x"}
+	report := accuracyReport([]accuracyResult{
+		{rom: rom, status: "pass", frames: 10, message: "a|b\nc"},
+		{rom: accuracyROM{path: "b.nes", movie: "b.fm2", ram: map[uint16]uint8{}}, status: "known failure", message: "reason"},
+		{rom: rom, status: "missing"},
+	})
+	assert.Equal(t, `| ROM | Criteria | Result | Frames | Message |
+|---|---|---|---|---|
+| a.nes | $6000, screen | pass | 10 | a\|b c |
+| b.nes (b.fm2) | RAM | known failure | 0 | reason |
+| a.nes | $6000, screen | missing | 0 |  |
+
+1/2 passed, 1 known failures, 1 missing
+`, report)
+}
+
+func Test_onlyNestest(t *testing.T) {
+	nestest := accuracyResult{rom: accuracyROM{path: "nestest.nes"}, status: "pass"}
+	missing := accuracyResult{rom: accuracyROM{path: testROMsPath + "a.nes"}, status: "missing"}
+	assert.True(t, onlyNestest([]accuracyResult{nestest, missing}))
+	assert.False(t, onlyNestest([]accuracyResult{nestest, missing, {rom: accuracyROM{path: testROMsPath + "b.nes"}, status: "fail"}}))
+}
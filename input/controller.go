@@ -7,3 +7,10 @@ type Controller interface {
 	Write(value uint8)
 	Read() uint8
 }
+
+// ButtonController is a controller whose input is the buttons pressed, which can be recorded and replayed.
+type ButtonController interface {
+	Controller
+	Buttons() uint8
+	Update(buttons uint8)
+}
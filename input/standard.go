@@ -14,6 +14,11 @@ func (c *StandardController) Update(state uint8) {
 	c.state = state
 }
 
+// Buttons returns the buttons pressed, updated by Update.
+func (c *StandardController) Buttons() uint8 {
+	return c.state
+}
+
 func (c *StandardController) Write(value uint8) {
 	c.strobe = (value & 1) == 1
 	c.cur = 1
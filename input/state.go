@@ -0,0 +1,9 @@
+package input
+
+import "github.com/thara/gorones/state"
+
+func (c *StandardController) Serialize(s *state.Serializer) {
+	s.Uint8(&c.state)
+	s.Uint8(&c.cur)
+	s.Bool(&c.strobe)
+}
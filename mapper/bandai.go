@@ -0,0 +1,204 @@
+package mapper
+
+import "fmt"
+
+// https://www.nesdev.org/wiki/Bandai_FCG_board
+// https://www.nesdev.org/wiki/INES_Mapper_016
+// https://www.nesdev.org/wiki/INES_Mapper_159
+
+func init() {
+	Register(16, AnySubmapper, "Bandai FCG/LZ93D50", infallible(newBandaiFCG))
+	Register(16, 4, "Bandai FCG-1/2", infallible(newBandaiFCG))
+	Register(16, 5, "Bandai LZ93D50 with 24C02", infallible(newBandaiFCG))
+	Register(159, AnySubmapper, "Bandai LZ93D50 with 24C01", infallible(newBandaiFCG))
+
+	RegisterUNIFBoard("BANDAI-FCG-1", 16, 4)
+	RegisterUNIFBoard("BANDAI-FCG-2", 16, 4)
+	RegisterUNIFBoard("BANDAI-LZ93D50", 16, 5)
+	RegisterUNIFBoard("BANDAI-LZ93D50+24C02", 16, 5)
+	RegisterUNIFBoard("BANDAI-LZ93D50+24C01", 159, AnySubmapper)
+}
+
+type bandaiFCG struct {
+	name string
+
+	prg []byte
+	chr []byte
+
+	chrRAM bool
+
+	// address ranges of registers
+	regsAt6000, regsAt8000 bool
+	// LZ93D50 reloads the counter from the latch, while FCG-1/2 writes the counter directly
+	irqLatched bool
+
+	prgBank   uint8
+	chrBanks  [8]uint8
+	mirroring Mirroring
+
+	irqEnabled bool
+	irqCounter uint16
+	irqLatch   uint16
+	irq        bool
+
+	eeprom     *eeprom
+	eepromRead bool
+}
+
+func newBandaiFCG(rom *ROM) Mapper {
+	prg, chr := rom.Banks()
+	m := &bandaiFCG{
+		prg:       prg,
+		chr:       chr,
+		chrRAM:    rom.header.CHRROMSize == 0,
+		mirroring: rom.header.Mirroring,
+	}
+
+	// https://www.nesdev.org/wiki/NES_2.0_submappers#016:_Bandai_FCG
+	switch {
+	case rom.header.MapperNo == 159:
+		m.name = "LZ93D50 with 24C01"
+		m.regsAt8000, m.irqLatched = true, true
+		m.eeprom = newEEPROM24C01()
+	case rom.header.NES2 && rom.header.Submapper == 4:
+		m.name = "FCG-1/2"
+		m.regsAt6000 = true
+	case rom.header.NES2 && rom.header.Submapper == 5:
+		m.name = "LZ93D50 with 24C02"
+		m.regsAt8000, m.irqLatched = true, true
+		m.eeprom = newEEPROM24C02()
+	default:
+		// games write registers at only one of the ranges
+		m.name = "FCG/LZ93D50"
+		m.regsAt6000, m.regsAt8000, m.irqLatched = true, true, true
+		m.eeprom = newEEPROM24C02()
+	}
+	return m
+}
+
+func (m *bandaiFCG) Read(addr uint16) uint8 {
+	switch {
+	case 0x0000 <= addr && addr <= 0x1FFF:
+		return m.chr[m.chrAddr(addr)]
+	case 0x6000 <= addr && addr <= 0x7FFF:
+		if m.eeprom != nil && m.eepromRead && m.eeprom.read() {
+			return 0x10
+		}
+	case 0x8000 <= addr && addr <= 0xFFFF:
+		return m.prg[m.prgAddr(addr)]
+	}
+	return 0
+}
+
+func (m *bandaiFCG) Write(addr uint16, value uint8) {
+	switch {
+	case 0x0000 <= addr && addr <= 0x1FFF:
+		if m.chrRAM {
+			m.chr[m.chrAddr(addr)] = value
+		}
+	case 0x6000 <= addr && addr <= 0x7FFF:
+		if m.regsAt6000 {
+			m.writeRegister(addr&0x0F, value)
+		}
+	case 0x8000 <= addr && addr <= 0xFFFF:
+		if m.regsAt8000 {
+			m.writeRegister(addr&0x0F, value)
+		}
+	}
+}
+
+func (m *bandaiFCG) writeRegister(reg uint16, value uint8) {
+	switch reg {
+	case 0x0, 0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7:
+		m.chrBanks[reg] = value
+	case 0x8:
+		m.prgBank = value & 0x0F
+	case 0x9:
+		m.mirroring = [4]Mirroring{
+			Mirroring_Vertical, Mirroring_Horizontal, Mirroring_SingleScreenA, Mirroring_SingleScreenB,
+		}[value&0b11]
+	case 0xA:
+		m.irqEnabled = value&1 != 0
+		if m.irqLatched {
+			m.irqCounter = m.irqLatch
+		}
+		m.irq = false
+	case 0xB:
+		if m.irqLatched {
+			m.irqLatch = m.irqLatch&0xFF00 | uint16(value)
+		} else {
+			m.irqCounter = m.irqCounter&0xFF00 | uint16(value)
+		}
+	case 0xC:
+		if m.irqLatched {
+			m.irqLatch = m.irqLatch&0x00FF | uint16(value)<<8
+		} else {
+			m.irqCounter = m.irqCounter&0x00FF | uint16(value)<<8
+		}
+	case 0xD:
+		if m.eeprom != nil {
+			m.eepromRead = value&0x80 != 0
+			m.eeprom.write(value&0x20 != 0, value&0x40 != 0)
+		}
+	}
+}
+
+func (m *bandaiFCG) prgAddr(addr uint16) int {
+	var bank int
+	if addr < 0xC000 {
+		bank = int(m.prgBank)
+	} else {
+		bank = len(m.prg)/0x4000 - 1
+	}
+	return (bank*0x4000 + int(addr%0x4000)) % len(m.prg)
+}
+
+func (m *bandaiFCG) chrAddr(addr uint16) int {
+	bank := int(m.chrBanks[addr/0x0400])
+	return (bank*0x0400 + int(addr%0x0400)) % len(m.chr)
+}
+
+// Tick clocks the IRQ counter on each CPU cycle.
+func (m *bandaiFCG) Tick() {
+	if !m.irqEnabled {
+		return
+	}
+	// checks before decrementing
+	if m.irqCounter == 0 {
+		m.irq = true
+	}
+	m.irqCounter--
+}
+
+func (m *bandaiFCG) IRQ() bool { return m.irq }
+
+// SaveData returns the contents of EEPROM, or nil if the board has no EEPROM.
+func (m *bandaiFCG) SaveData() []byte {
+	if m.eeprom == nil {
+		return nil
+	}
+	return append([]byte(nil), m.eeprom.data...)
+}
+
+// LoadSaveData restores EEPROM, where data is ignored if the board has no EEPROM.
+func (m *bandaiFCG) LoadSaveData(b []byte) error {
+	if m.eeprom == nil {
+		return nil
+	}
+	return m.eeprom.load(b)
+}
+
+func (m *bandaiFCG) Mirroring() Mirroring {
+	return m.mirroring
+}
+
+func (m *bandaiFCG) PRG() []byte { return append([]byte(nil), m.prg...) }
+func (m *bandaiFCG) CHR() []byte { return append([]byte(nil), m.chr...) }
+
+func (m bandaiFCG) String() string {
+	return fmt.Sprintf(`Bandai %s:
+	PRG: 0x%x byte
+	CHR: 0x%x byte
+	mirroring: %s
+`, m.name, len(m.prg), len(m.chr), m.mirroring)
+}
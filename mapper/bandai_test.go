@@ -0,0 +1,121 @@
+package mapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBandaiROM(mapperNo uint16, submapper uint8) *ROM {
+	// 256KB PRG, 256KB CHR; each byte holds its bank number
+	prg := make([]byte, 0x40000)
+	for i := range prg {
+		prg[i] = uint8(i / 0x4000)
+	}
+	chr := make([]byte, 0x40000)
+	for i := range chr {
+		chr[i] = uint8(i / 0x0400)
+	}
+	return &ROM{
+		header: Header{MapperNo: mapperNo, Submapper: submapper, NES2: submapper != 0, PRGROMSize: 16, CHRROMSize: 32},
+		raw:    append(prg, chr...),
+	}
+}
+
+func Test_bandaiFCG_banks(t *testing.T) {
+	m := newBandaiFCG(newBandaiROM(16, 5)).(*bandaiFCG)
+
+	m.Write(0x8008, 3)
+	assert.EqualValues(t, 3, m.Read(0x8000))
+	assert.EqualValues(t, 15, m.Read(0xC000))
+
+	m.Write(0x8000, 0x10)
+	m.Write(0xFFF7, 0xFF)
+	assert.EqualValues(t, 0x10, m.Read(0x0000))
+	assert.EqualValues(t, 0xFF, m.Read(0x1C00))
+
+	m.Write(0x8009, 3)
+	assert.Equal(t, Mirroring_SingleScreenB, m.Mirroring())
+
+	// LZ93D50 ignores $6000-$7FFF
+	m.Write(0x6008, 1)
+	assert.EqualValues(t, 3, m.Read(0x8000))
+
+	// FCG-1/2 uses only $6000-$7FFF
+	f := newBandaiFCG(newBandaiROM(16, 4)).(*bandaiFCG)
+	f.Write(0x6008, 1)
+	f.Write(0x8008, 2)
+	assert.EqualValues(t, 1, f.Read(0x8000))
+}
+
+func Test_bandaiFCG_irq(t *testing.T) {
+	m := newBandaiFCG(newBandaiROM(16, 5)).(*bandaiFCG)
+
+	// latch is reloaded on enabling
+	m.Write(0x800B, 2)
+	m.Write(0x800C, 0)
+	m.Write(0x800A, 1)
+	for i := 0; i < 2; i++ {
+		m.Tick()
+		assert.False(t, m.IRQ())
+	}
+	m.Tick()
+	assert.True(t, m.IRQ())
+
+	m.Write(0x800A, 0)
+	assert.False(t, m.IRQ())
+
+	// FCG-1/2 writes the counter directly
+	f := newBandaiFCG(newBandaiROM(16, 4)).(*bandaiFCG)
+	f.Write(0x600A, 1)
+	f.Write(0x600B, 1)
+	f.Write(0x600C, 0)
+	f.Tick()
+	assert.False(t, f.IRQ())
+	f.Tick()
+	assert.True(t, f.IRQ())
+}
+
+func Test_bandaiFCG_eeprom(t *testing.T) {
+	m := newBandaiFCG(newBandaiROM(159, 0)).(*bandaiFCG)
+	m.eeprom.data[0x01] = 0x01
+
+	// bit-bang via $800D: SCL=bit 5, SDA=bit 6, read enable=bit 7
+	write := func(scl, sda bool) {
+		v := uint8(0x80)
+		if scl {
+			v |= 0x20
+		}
+		if sda {
+			v |= 0x40
+		}
+		m.Write(0x800D, v)
+	}
+	write(true, true)
+	write(true, false) // start
+	write(false, false)
+	for i := 0; i < 8; i++ {
+		// address 0x01 and read
+		b := i == 0 || i == 7
+		write(false, b)
+		write(true, b)
+		write(false, b)
+	}
+	// ack
+	write(false, true)
+	write(true, true)
+	assert.EqualValues(t, 0x00, m.Read(0x6000)&0x10)
+	write(false, true)
+
+	write(true, true)
+	assert.EqualValues(t, 0x10, m.Read(0x6000)&0x10)
+
+	require.NoError(t, m.LoadSaveData(make([]byte, 128)))
+	assert.Len(t, m.SaveData(), 128)
+
+	f := newBandaiFCG(newBandaiROM(16, 4)).(*bandaiFCG)
+	assert.Nil(t, f.SaveData())
+	assert.NoError(t, f.LoadSaveData(make([]byte, 128)), "a leftover save file is ignored")
+	assert.Nil(t, f.SaveData())
+}
@@ -0,0 +1,193 @@
+package mapper
+
+import "github.com/pkg/errors"
+
+// I2C serial EEPROM in Bandai cartridges
+// https://www.nesdev.org/wiki/Bandai_FCG_board#Serial_EEPROM
+
+type eepromMode int
+
+const (
+	eepromIdle    eepromMode = iota
+	eepromDevice             // receiving a device address
+	eepromAddress            // receiving a word address
+	eepromWrite              // receiving data
+	eepromRead               // sending data
+)
+
+type eeprom struct {
+	data []byte
+
+	// X24C01 has no device address and sends/receives LSB first with 4 bytes page,
+	// while 24C02 is a standard I2C device with 8 bytes page.
+	x24c01 bool
+
+	// lines driven by the host
+	scl, sda bool
+	// SDA driven by the EEPROM, which is high while released
+	out bool
+
+	mode, next eepromMode
+	bit        uint8 // the number of bits transferred in the current byte, or 9 while acknowledging
+	shift      uint8
+	addr       uint8
+	ack        bool // the host acknowledged the sent byte
+}
+
+func newEEPROM24C01() *eeprom {
+	return &eeprom{data: make([]byte, 128), x24c01: true, out: true}
+}
+
+func newEEPROM24C02() *eeprom {
+	return &eeprom{data: make([]byte, 256), out: true}
+}
+
+// write updates the lines driven by the host.
+func (e *eeprom) write(scl, sda bool) {
+	switch {
+	case e.scl && scl && e.sda && !sda:
+		e.start()
+	case e.scl && scl && !e.sda && sda:
+		e.stop()
+	case !e.scl && scl:
+		e.rise(sda)
+	case e.scl && !scl:
+		e.fall()
+	}
+	e.scl, e.sda = scl, sda
+}
+
+// read returns the state of SDA, which is wired-AND of the host and the EEPROM.
+func (e *eeprom) read() bool {
+	return e.out && e.sda
+}
+
+func (e *eeprom) start() {
+	if e.x24c01 {
+		e.mode = eepromAddress
+	} else {
+		e.mode = eepromDevice
+	}
+	e.bit = 0
+	e.shift = 0
+	e.out = true
+}
+
+func (e *eeprom) stop() {
+	e.mode = eepromIdle
+	e.out = true
+}
+
+// rise handles the rising edge of SCL, where the receiver samples SDA.
+func (e *eeprom) rise(sda bool) {
+	if e.mode == eepromIdle {
+		return
+	}
+	if e.bit < 8 {
+		if e.mode != eepromRead {
+			var b uint8
+			if sda {
+				b = 1
+			}
+			if e.x24c01 {
+				e.shift |= b << e.bit
+			} else {
+				e.shift = e.shift<<1 | b
+			}
+		}
+		e.bit++
+	} else {
+		e.ack = !sda
+		e.bit = 9
+	}
+}
+
+// fall handles the falling edge of SCL, where the transmitter changes SDA.
+func (e *eeprom) fall() {
+	switch {
+	case e.mode == eepromIdle:
+		return
+	case e.bit == 8:
+		if e.mode == eepromRead {
+			// release SDA for the host to acknowledge
+			e.out = true
+			return
+		}
+		e.out = !e.receive()
+	case e.bit == 9:
+		e.out = true
+		e.bit = 0
+		e.shift = 0
+		if e.mode == eepromRead {
+			if !e.ack {
+				e.mode = eepromIdle
+				return
+			}
+			e.addr = uint8((int(e.addr) + 1) % len(e.data))
+		} else {
+			e.mode = e.next
+		}
+		e.output()
+	default:
+		e.output()
+	}
+}
+
+// receive handles a received byte and reports whether the EEPROM acknowledges it.
+func (e *eeprom) receive() bool {
+	switch e.mode {
+	case eepromDevice:
+		if e.shift&0xF0 != 0xA0 {
+			e.mode = eepromIdle
+			return false
+		}
+		if e.shift&1 != 0 {
+			e.next = eepromRead
+		} else {
+			e.next = eepromAddress
+		}
+	case eepromAddress:
+		if e.x24c01 {
+			e.addr = e.shift & 0x7F
+			if e.shift&0x80 != 0 {
+				e.next = eepromRead
+			} else {
+				e.next = eepromWrite
+			}
+		} else {
+			e.addr = e.shift
+			e.next = eepromWrite
+		}
+	case eepromWrite:
+		e.data[int(e.addr)%len(e.data)] = e.shift
+		// the address wraps within a page
+		page := uint8(8)
+		if e.x24c01 {
+			page = 4
+		}
+		e.addr = e.addr&^(page-1) | (e.addr+1)&(page-1)
+		e.next = eepromWrite
+	}
+	return true
+}
+
+// output drives SDA by the current bit of data in read mode.
+func (e *eeprom) output() {
+	if e.mode != eepromRead {
+		return
+	}
+	d := e.data[int(e.addr)%len(e.data)]
+	if e.x24c01 {
+		e.out = d>>e.bit&1 != 0
+	} else {
+		e.out = d>>(7-e.bit)&1 != 0
+	}
+}
+
+func (e *eeprom) load(b []byte) error {
+	if len(b) != len(e.data) {
+		return errors.Errorf("invalid EEPROM data size: %d bytes", len(b))
+	}
+	copy(e.data, b)
+	return nil
+}
@@ -0,0 +1,130 @@
+package mapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// i2cHost bit-bangs I2C bus of the EEPROM
+type i2cHost struct {
+	e *eeprom
+}
+
+func (h i2cHost) start() {
+	h.e.write(true, true)
+	h.e.write(true, false)
+	h.e.write(false, false)
+}
+
+func (h i2cHost) stop() {
+	h.e.write(false, false)
+	h.e.write(true, false)
+	h.e.write(true, true)
+}
+
+func (h i2cHost) writeBit(b bool) {
+	h.e.write(false, b)
+	h.e.write(true, b)
+	h.e.write(false, b)
+}
+
+func (h i2cHost) readBit() bool {
+	h.e.write(false, true)
+	h.e.write(true, true)
+	b := h.e.read()
+	h.e.write(false, true)
+	return b
+}
+
+// writeByte sends a byte and returns whether the EEPROM acknowledged it
+func (h i2cHost) writeByte(v uint8) bool {
+	for i := 0; i < 8; i++ {
+		if h.e.x24c01 {
+			h.writeBit(v>>i&1 != 0)
+		} else {
+			h.writeBit(v>>(7-i)&1 != 0)
+		}
+	}
+	return !h.readBit()
+}
+
+func (h i2cHost) readByte(ack bool) uint8 {
+	var v uint8
+	for i := 0; i < 8; i++ {
+		var b uint8
+		if h.readBit() {
+			b = 1
+		}
+		if h.e.x24c01 {
+			v |= b << i
+		} else {
+			v = v<<1 | b
+		}
+	}
+	h.writeBit(!ack)
+	return v
+}
+
+func Test_eeprom24C02(t *testing.T) {
+	e := newEEPROM24C02()
+	h := i2cHost{e}
+
+	// page write
+	h.start()
+	assert.True(t, h.writeByte(0xA0))
+	assert.True(t, h.writeByte(0x06))
+	assert.True(t, h.writeByte(0x11))
+	assert.True(t, h.writeByte(0x22))
+	assert.True(t, h.writeByte(0x33))
+	h.stop()
+	assert.EqualValues(t, 0x11, e.data[0x06])
+	assert.EqualValues(t, 0x22, e.data[0x07])
+	assert.EqualValues(t, 0x33, e.data[0x00], "wraps within a page")
+
+	// random read
+	h.start()
+	assert.True(t, h.writeByte(0xA0))
+	assert.True(t, h.writeByte(0x06))
+	h.start()
+	assert.True(t, h.writeByte(0xA1))
+	assert.EqualValues(t, 0x11, h.readByte(true))
+	assert.EqualValues(t, 0x22, h.readByte(false))
+	h.stop()
+	assert.Equal(t, eepromIdle, e.mode)
+
+	// other devices
+	h.start()
+	assert.False(t, h.writeByte(0xB0))
+	h.stop()
+}
+
+func Test_eeprom24C01(t *testing.T) {
+	e := newEEPROM24C01()
+	h := i2cHost{e}
+
+	// 7 bits address with R/W bit
+	h.start()
+	assert.True(t, h.writeByte(0x7E))
+	assert.True(t, h.writeByte(0xAB))
+	assert.True(t, h.writeByte(0xCD))
+	assert.True(t, h.writeByte(0xEF))
+	h.stop()
+	assert.EqualValues(t, 0xAB, e.data[0x7E])
+	assert.EqualValues(t, 0xCD, e.data[0x7F])
+	assert.EqualValues(t, 0xEF, e.data[0x7C], "wraps within a page")
+
+	h.start()
+	assert.True(t, h.writeByte(0x80|0x7E))
+	assert.EqualValues(t, 0xAB, h.readByte(true))
+	assert.EqualValues(t, 0xCD, h.readByte(true))
+	assert.EqualValues(t, 0x00, h.readByte(false), "sequential read wraps around the memory")
+	h.stop()
+}
+
+func Test_eeprom_load(t *testing.T) {
+	e := newEEPROM24C01()
+	require.NoError(t, e.load(make([]byte, 128)))
+	assert.Error(t, e.load(make([]byte, 256)))
+}
@@ -0,0 +1,376 @@
+package mapper
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/thara/gorones/apu"
+	"github.com/thara/gorones/patch"
+)
+
+// https://www.nesdev.org/wiki/Family_Computer_Disk_System
+
+// FDS is the interface of Famicom Disk System specific features.
+type FDS interface {
+	Mapper
+
+	// Sides returns the number of disk sides in the image.
+	Sides() int
+
+	// InsertedSide returns the index of the inserted side, or -1 if no disk is inserted.
+	InsertedSide() int
+
+	// FlipSide ejects the disk and inserts the next side after a while.
+	FlipSide()
+}
+
+func init() {
+	Register(20, AnySubmapper, "Famicom Disk System", newFDS)
+}
+
+const (
+	fdsBIOSSize = 0x2000
+
+	// CPU cycles to keep a disk ejected while flipping sides, which lets BIOS notice the change
+	fdsInsertDelay = apu.CPUClockNum / apu.CPUClockDen
+
+	// CPU cycles to transfer a byte (96.4kbit/s)
+	fdsByteCycles = 150
+	// CPU cycles for the head to return to the start of a disk
+	fdsHeadReturnCycles = 50000
+)
+
+type fds struct {
+	bios []byte
+	ram  [0x8000]uint8
+	chr  [0x2000]uint8
+
+	image *fdsImage
+	disks [][]byte // raw data of each side, including gaps
+
+	side        int // -1 if ejected
+	nextSide    int
+	insertDelay int
+
+	mirroring Mirroring
+
+	// $4020-$4022 timer IRQ
+	timerReload   uint16
+	timerCounter  uint16
+	timerRepeat   bool
+	timerEnabled  bool
+	timerIRQ      bool
+	diskIRQ       bool
+	diskIRQEnable bool
+
+	// $4023
+	diskRegEnabled  bool
+	soundRegEnabled bool
+
+	// $4025 control
+	motorOn       bool
+	resetTransfer bool
+	readMode      bool
+	crcControl    bool
+	diskReady     bool
+
+	writeData uint8
+	readData  uint8
+
+	transferComplete bool
+	endOfHead        bool
+	scanning         bool
+	gapEnded         bool
+	position         int
+	delay            int
+
+	audio fdsAudio
+}
+
+func newFDS(rom *ROM) (Mapper, error) {
+	if rom.disk == nil {
+		return nil, errors.New("mapper 20 is only for FDS disk images")
+	}
+	if rom.bios == nil {
+		return nil, errors.New("FDS BIOS is not loaded")
+	}
+	m := &fds{
+		bios:      rom.bios,
+		image:     rom.disk,
+		mirroring: Mirroring_Horizontal,
+		endOfHead: true,
+		audio:     newFDSAudio(),
+	}
+	m.loadDisks()
+	return m, nil
+}
+
+func (m *fds) loadDisks() {
+	m.disks = make([][]byte, len(m.image.sides))
+	for i, s := range m.image.sides {
+		m.disks[i] = addGaps(s)
+	}
+	m.side = 0
+}
+
+func (m *fds) Read(addr uint16) uint8 {
+	switch {
+	case 0x0000 <= addr && addr <= 0x1FFF:
+		return m.chr[addr]
+	case addr == 0x4030:
+		var v uint8
+		if m.timerIRQ {
+			v |= 0x01
+		}
+		if m.transferComplete {
+			v |= 0x02
+		}
+		if m.endOfHead {
+			v |= 0x40
+		}
+		m.timerIRQ = false
+		m.diskIRQ = false
+		m.transferComplete = false
+		return v
+	case addr == 0x4031:
+		m.transferComplete = false
+		m.diskIRQ = false
+		return m.readData
+	case addr == 0x4032:
+		v := uint8(0x40)
+		if !m.inserted() {
+			v |= 0b111 // not inserted, not ready, write protected
+		} else if !m.scanning {
+			v |= 0b010
+		}
+		return v
+	case addr == 0x4033:
+		return 0x80 // battery good
+	case 0x4040 <= addr && addr <= 0x4092:
+		return m.audio.read(addr)
+	case 0x6000 <= addr && addr <= 0xDFFF:
+		return m.ram[addr-0x6000]
+	case 0xE000 <= addr && addr <= 0xFFFF:
+		return m.bios[addr-0xE000]
+	}
+	return 0
+}
+
+func (m *fds) Write(addr uint16, value uint8) {
+	switch {
+	case 0x0000 <= addr && addr <= 0x1FFF:
+		m.chr[addr] = value
+	case addr == 0x4020:
+		m.timerReload = m.timerReload&0xFF00 | uint16(value)
+	case addr == 0x4021:
+		m.timerReload = m.timerReload&0x00FF | uint16(value)<<8
+	case addr == 0x4022:
+		m.timerRepeat = value&0x01 != 0
+		m.timerEnabled = value&0x02 != 0 && m.diskRegEnabled
+		if m.timerEnabled {
+			m.timerCounter = m.timerReload
+		} else {
+			m.timerIRQ = false
+		}
+	case addr == 0x4023:
+		m.diskRegEnabled = value&0x01 != 0
+		m.soundRegEnabled = value&0x02 != 0
+		if !m.diskRegEnabled {
+			m.timerEnabled = false
+			m.timerIRQ = false
+			m.diskIRQ = false
+		}
+	case addr == 0x4024:
+		m.writeData = value
+		m.transferComplete = false
+		m.diskIRQ = false
+	case addr == 0x4025:
+		m.motorOn = value&0x01 != 0
+		m.resetTransfer = value&0x02 != 0
+		m.readMode = value&0x04 != 0
+		if value&0x08 == 0 {
+			m.mirroring = Mirroring_Vertical
+		} else {
+			m.mirroring = Mirroring_Horizontal
+		}
+		m.crcControl = value&0x10 != 0
+		m.diskReady = value&0x40 != 0
+		m.diskIRQEnable = value&0x80 != 0
+		m.diskIRQ = false
+	case 0x4040 <= addr && addr <= 0x408A:
+		if m.soundRegEnabled {
+			m.audio.write(addr, value)
+		}
+	case 0x6000 <= addr && addr <= 0xDFFF:
+		m.ram[addr-0x6000] = value
+	}
+}
+
+func (m *fds) inserted() bool {
+	return 0 <= m.side && m.insertDelay == 0
+}
+
+// Tick clocks the timer, the disk drive and audio on each CPU cycle.
+func (m *fds) Tick() {
+	if m.timerEnabled {
+		if m.timerCounter == 0 {
+			m.timerIRQ = true
+			m.timerCounter = m.timerReload
+			if !m.timerRepeat {
+				m.timerEnabled = false
+			}
+		} else {
+			m.timerCounter--
+		}
+	}
+
+	if 0 < m.insertDelay {
+		m.insertDelay--
+		if m.insertDelay == 0 {
+			m.side = m.nextSide
+		}
+	}
+	m.clockDrive()
+
+	m.audio.clock()
+}
+
+// clockDrive emulates the disk drive which transfers a byte every fdsByteCycles.
+func (m *fds) clockDrive() {
+	if !m.inserted() || !m.motorOn {
+		m.endOfHead = true
+		m.scanning = false
+		return
+	}
+	if m.resetTransfer && !m.scanning {
+		return
+	}
+	if m.endOfHead {
+		// the head moves back to the start
+		m.delay = fdsHeadReturnCycles
+		m.endOfHead = false
+		m.position = 0
+		m.gapEnded = false
+		return
+	}
+	if 0 < m.delay {
+		m.delay--
+		return
+	}
+
+	m.scanning = true
+	disk := m.disks[m.side]
+
+	if m.readMode {
+		data := disk[m.position]
+		irq := m.diskIRQEnable
+		if !m.diskReady {
+			m.gapEnded = false
+		} else if data != 0 && !m.gapEnded {
+			// the gap end mark is not transferred
+			m.gapEnded = true
+			irq = false
+		}
+		if m.gapEnded {
+			m.transferComplete = true
+			m.readData = data
+			if irq {
+				m.diskIRQ = true
+			}
+		}
+	} else {
+		var data uint8
+		if !m.crcControl {
+			m.transferComplete = true
+			data = m.writeData
+			if m.diskIRQEnable {
+				m.diskIRQ = true
+			}
+		}
+		if !m.diskReady {
+			data = 0
+		}
+		disk[m.position] = data
+		m.gapEnded = false
+	}
+
+	m.position++
+	if len(disk) <= m.position {
+		m.motorOn = false
+		m.endOfHead = true
+	} else {
+		m.delay = fdsByteCycles
+	}
+}
+
+func (m *fds) IRQ() bool { return m.timerIRQ || m.diskIRQ }
+
+func (m *fds) Sample() float32 { return m.audio.sample() }
+
+func (m *fds) Sides() int { return len(m.disks) }
+
+func (m *fds) InsertedSide() int {
+	if !m.inserted() {
+		return -1
+	}
+	return m.side
+}
+
+func (m *fds) FlipSide() {
+	if m.side < 0 {
+		m.nextSide = 0
+	} else {
+		m.nextSide = (m.side + 1) % len(m.disks)
+	}
+	m.side = -1
+	m.insertDelay = fdsInsertDelay
+}
+
+// SaveData returns an IPS patch of the disk image modified by writes.
+func (m *fds) SaveData() []byte {
+	modified := fdsImage{header: m.image.header, sides: make([][]byte, len(m.disks))}
+	for i, d := range m.disks {
+		modified.sides[i] = removeGaps(d)
+	}
+	p, err := patch.CreateIPS(m.image.bytes(), modified.bytes())
+	if err != nil {
+		// never happens since the image is smaller than the limit of IPS
+		panic(err)
+	}
+	return p
+}
+
+// LoadSaveData applies an IPS patch created by SaveData to the disk image.
+func (m *fds) LoadSaveData(b []byte) error {
+	img, err := patch.ApplyIPS(m.image.bytes(), b)
+	if err != nil {
+		return errors.Wrap(err, "failed to apply saved disk")
+	}
+	if len(img) != len(m.image.bytes()) {
+		return errors.New("saved disk has different size")
+	}
+
+	modified := fdsImage{header: m.image.header}
+	sides := img[len(m.image.header):]
+	for i := range m.image.sides {
+		modified.sides = append(modified.sides, sides[i*fdsSideSize:(i+1)*fdsSideSize])
+	}
+	m.disks = make([][]byte, len(modified.sides))
+	for i, s := range modified.sides {
+		m.disks[i] = addGaps(s)
+	}
+	return nil
+}
+
+func (m *fds) Mirroring() Mirroring {
+	return m.mirroring
+}
+
+func (m *fds) PRG() []byte { return append([]byte(nil), m.bios...) }
+func (m *fds) CHR() []byte { return append([]byte(nil), m.chr[:]...) }
+
+func (m fds) String() string {
+	return fmt.Sprintf(`Famicom Disk System:
+	sides: %d
+`, len(m.disks))
+}
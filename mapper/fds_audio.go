@@ -0,0 +1,217 @@
+package mapper
+
+// https://www.nesdev.org/wiki/FDS_audio
+
+// scales the output (0 ..= 63*32), where full volume is roughly 2.4 times as loud as a 2A03 pulse at full volume.
+const fdsOutputScale = 0.36 / (63 * 32)
+
+type fdsEnvelope struct {
+	disabled bool // gain is set directly
+	increase bool
+	speed    uint8
+	gain     uint8
+
+	counter uint32
+}
+
+func (e *fdsEnvelope) write(value uint8) {
+	e.disabled = value&0x80 != 0
+	e.increase = value&0x40 != 0
+	e.speed = value & 0x3F
+	if e.disabled {
+		e.gain = value & 0x3F
+	}
+	e.counter = 0
+}
+
+func (e *fdsEnvelope) clock(masterSpeed uint8) {
+	if e.disabled || masterSpeed == 0 {
+		return
+	}
+	e.counter++
+	if e.counter < 8*(uint32(e.speed)+1)*uint32(masterSpeed) {
+		return
+	}
+	e.counter = 0
+	if e.increase && e.gain < 32 {
+		e.gain++
+	} else if !e.increase && 0 < e.gain {
+		e.gain--
+	}
+}
+
+type fdsAudio struct {
+	wave            [64]uint8 // 6-bit samples
+	waveWriteEnable bool
+	waveHalt        bool
+	waveFreq        uint16
+	waveAcc         uint32 // the upper 6 bits of 22 bits are the wave position
+
+	envelopesHalt bool
+	masterSpeed   uint8
+	masterVolume  uint8
+
+	vol, mod fdsEnvelope
+
+	modTable   [64]uint8 // 3-bit entries
+	modPos     uint8
+	modHalt    bool
+	modFreq    uint16
+	modAcc     uint16
+	modCounter int8 // 7-bit signed
+
+	output float32
+}
+
+func newFDSAudio() fdsAudio {
+	// initialized by BIOS
+	return fdsAudio{masterSpeed: 0xE8}
+}
+
+func (a *fdsAudio) read(addr uint16) uint8 {
+	switch {
+	case 0x4040 <= addr && addr <= 0x407F:
+		return a.wave[addr-0x4040] | 0x40
+	case addr == 0x4090:
+		return a.vol.gain | 0x40
+	case addr == 0x4092:
+		return a.mod.gain | 0x40
+	}
+	return 0x40
+}
+
+func (a *fdsAudio) write(addr uint16, value uint8) {
+	switch {
+	case 0x4040 <= addr && addr <= 0x407F:
+		if a.waveWriteEnable {
+			a.wave[addr-0x4040] = value & 0x3F
+		}
+	case addr == 0x4080:
+		a.vol.write(value)
+	case addr == 0x4082:
+		a.waveFreq = a.waveFreq&0x0F00 | uint16(value)
+	case addr == 0x4083:
+		a.waveFreq = a.waveFreq&0x00FF | uint16(value&0x0F)<<8
+		a.waveHalt = value&0x80 != 0
+		a.envelopesHalt = value&0x40 != 0
+		if a.waveHalt {
+			a.waveAcc = 0
+		}
+		if a.envelopesHalt {
+			a.vol.counter = 0
+			a.mod.counter = 0
+		}
+	case addr == 0x4084:
+		a.mod.write(value)
+	case addr == 0x4085:
+		a.modCounter = int8(value<<1) >> 1
+	case addr == 0x4086:
+		a.modFreq = a.modFreq&0x0F00 | uint16(value)
+	case addr == 0x4087:
+		a.modFreq = a.modFreq&0x00FF | uint16(value&0x0F)<<8
+		a.modHalt = value&0x80 != 0
+		if a.modHalt {
+			a.modAcc = 0
+		}
+	case addr == 0x4088:
+		// each write fills 2 entries
+		if a.modHalt {
+			a.modTable[a.modPos] = value & 0b111
+			a.modTable[(a.modPos+1)&0x3F] = value & 0b111
+			a.modPos = (a.modPos + 2) & 0x3F
+		}
+	case addr == 0x4089:
+		a.waveWriteEnable = value&0x80 != 0
+		a.masterVolume = value & 0b11
+	case addr == 0x408A:
+		a.masterSpeed = value
+	}
+}
+
+var fdsModAdjustments = [8]int8{0, 1, 2, 4, 0, -4, -2, -1}
+
+// clock is called every CPU cycle.
+func (a *fdsAudio) clock() {
+	if !a.envelopesHalt && !a.waveHalt {
+		a.vol.clock(a.masterSpeed)
+		a.mod.clock(a.masterSpeed)
+	}
+
+	if !a.modHalt && 0 < a.modFreq {
+		acc := uint32(a.modAcc) + uint32(a.modFreq)
+		a.modAcc = uint16(acc)
+		if 0xFFFF < acc {
+			entry := a.modTable[a.modPos]
+			if entry == 4 {
+				a.modCounter = 0
+			} else {
+				// wraps in 7 bits
+				a.modCounter = int8(uint8(a.modCounter+fdsModAdjustments[entry])<<1) >> 1
+			}
+			a.modPos = (a.modPos + 1) & 0x3F
+		}
+	}
+
+	if !a.waveHalt && !a.waveWriteEnable {
+		a.waveAcc = (a.waveAcc + a.pitch()) & 0x3FFFFF
+	}
+
+	a.updateOutput()
+}
+
+// pitch returns the wave frequency modulated by the mod unit
+func (a *fdsAudio) pitch() uint32 {
+	pitch := int32(a.waveFreq)
+	if a.modHalt {
+		return uint32(pitch)
+	}
+
+	temp := int32(a.modCounter) * int32(a.mod.gain)
+	remainder := temp & 0x0F
+	temp >>= 4
+	if 0 < remainder && temp&0x80 == 0 {
+		if a.modCounter < 0 {
+			temp -= 1
+		} else {
+			temp += 2
+		}
+	}
+	if 192 <= temp {
+		temp -= 256
+	} else if temp < -64 {
+		temp += 256
+	}
+
+	temp = pitch * temp
+	remainder = temp & 0x3F
+	temp >>= 6
+	if 32 <= remainder {
+		temp += 1
+	}
+
+	pitch += temp
+	if pitch < 0 {
+		return 0
+	}
+	return uint32(pitch)
+}
+
+// master volume: 2/2, 2/3, 2/4, 2/5
+var fdsMasterVolumes = [4]float32{1, 2.0 / 3, 2.0 / 4, 2.0 / 5}
+
+func (a *fdsAudio) updateOutput() {
+	// the output is held while the wave RAM is writable
+	if a.waveWriteEnable {
+		return
+	}
+	gain := a.vol.gain
+	if 32 < gain {
+		gain = 32
+	}
+	sample := a.wave[a.waveAcc>>16]
+	a.output = float32(sample) * float32(gain) * fdsMasterVolumes[a.masterVolume]
+}
+
+func (a *fdsAudio) sample() float32 {
+	return a.output * fdsOutputScale
+}
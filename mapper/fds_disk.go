@@ -0,0 +1,133 @@
+package mapper
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// https://www.nesdev.org/wiki/FDS_disk_format
+// https://www.nesdev.org/wiki/FDS_file_format
+
+const fdsSideSize = 65500
+
+var (
+	fdsMagicNumber = []byte{0x46, 0x44, 0x53, 0x1A} // "FDS\x1A"
+	// headerless images start with the disk info block
+	fdsDiskInfoMagic = []byte{0x01, 0x2A, 0x4E, 0x49} // "\x01*NI(NTENDO-HVC*)"
+)
+
+// fdsImage is a disk image in .fds format.
+type fdsImage struct {
+	header []byte // 16 bytes fwNES header if exists
+	sides  [][]byte
+}
+
+func isFDSMagic(magic []byte) bool {
+	return bytes.Equal(magic, fdsMagicNumber) || bytes.Equal(magic, fdsDiskInfoMagic)
+}
+
+// parseFDS parses a disk image after magic number was read
+func parseFDS(magic []byte, r io.Reader) (*fdsImage, error) {
+	rest, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read disk image")
+	}
+	b := append(append([]byte(nil), magic...), rest...)
+
+	var img fdsImage
+	if bytes.Equal(magic, fdsMagicNumber) {
+		if len(b) < 16 {
+			return nil, errors.New("too short FDS header")
+		}
+		img.header, b = b[:16], b[16:]
+	}
+	if len(b) < fdsSideSize {
+		return nil, errors.Errorf("too short disk image: %d bytes", len(b))
+	}
+	for ; fdsSideSize <= len(b); b = b[fdsSideSize:] {
+		img.sides = append(img.sides, b[:fdsSideSize:fdsSideSize])
+	}
+	return &img, nil
+}
+
+func (i *fdsImage) bytes() []byte {
+	b := append([]byte(nil), i.header...)
+	for _, s := range i.sides {
+		b = append(b, s...)
+	}
+	return b
+}
+
+// fdsBlockLength returns the length of a block started at b[pos], or 0 if it is not a valid block.
+func fdsBlockLength(b []byte, pos int) int {
+	switch b[pos] {
+	case 1: // disk info
+		return 56
+	case 2: // file amount
+		return 2
+	case 3: // file header
+		return 16
+	case 4: // file data, whose size is in the preceding file header
+		if pos < 3 {
+			return 0
+		}
+		return 1 + (int(b[pos-3]) | int(b[pos-2])<<8)
+	}
+	return 0
+}
+
+const (
+	fdsLeadingGap = 28300 / 8 // bytes of gap before the first block
+	fdsBlockGap   = 976 / 8   // bytes of gap after each block
+	fdsBlockStart = 0x80      // gap end mark before each block
+
+	// the length of raw data on a side, which has room for gaps and CRCs
+	fdsRawSideSize = fdsLeadingGap + fdsSideSize + 0x1000
+)
+
+// addGaps converts a side in .fds format to the raw data on a disk, which includes gaps and CRCs.
+func addGaps(side []byte) []byte {
+	raw := make([]byte, fdsLeadingGap, fdsRawSideSize)
+	for pos := 0; pos < len(side); {
+		n := fdsBlockLength(side, pos)
+		if n == 0 || len(side) < pos+n {
+			break
+		}
+		raw = append(raw, fdsBlockStart)
+		raw = append(raw, side[pos:pos+n]...)
+		// CRC is not checked by emulated drive
+		raw = append(raw, 0x4D, 0x62)
+		raw = append(raw, make([]byte, fdsBlockGap)...)
+		pos += n
+	}
+	if len(raw) < fdsRawSideSize {
+		raw = append(raw, make([]byte, fdsRawSideSize-len(raw))...)
+	}
+	return raw
+}
+
+// removeGaps converts the raw data on a disk to a side in .fds format.
+func removeGaps(raw []byte) []byte {
+	side := make([]byte, 0, fdsSideSize)
+	for pos := 0; pos < len(raw); {
+		// skip gap
+		for pos < len(raw) && raw[pos] != fdsBlockStart {
+			pos++
+		}
+		pos++
+		if len(raw) <= pos {
+			break
+		}
+
+		// the size of file data is in the preceding file header
+		n := fdsBlockLength(append(side, raw[pos]), len(side))
+		if n == 0 || len(raw) < pos+n || fdsSideSize < len(side)+n {
+			break
+		}
+		side = append(side, raw[pos:pos+n]...)
+		pos += n + 2 // CRC
+	}
+	return append(side, make([]byte, fdsSideSize-len(side))...)
+}
@@ -0,0 +1,232 @@
+package mapper
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFDSSide creates a side which has one file of the data
+func newFDSSide(data []byte) []byte {
+	side := make([]byte, 0, fdsSideSize)
+
+	info := make([]byte, 56)
+	copy(info, "\x01*NINTENDO-HVC*")
+	side = append(side, info...)
+	side = append(side, 2, 1)
+
+	header := make([]byte, 16)
+	header[0] = 3
+	header[13], header[14] = uint8(len(data)), uint8(len(data)>>8)
+	side = append(side, header...)
+	side = append(side, 4)
+	side = append(side, data...)
+
+	return append(side, make([]byte, fdsSideSize-len(side))...)
+}
+
+func newFDSROM(t *testing.T, sides ...[]byte) *ROM {
+	image := append([]byte(nil), fdsMagicNumber...)
+	image = append(image, uint8(len(sides)))
+	image = append(image, make([]byte, 11)...)
+	for _, s := range sides {
+		image = append(image, s...)
+	}
+	rom, err := ParseROM(bytes.NewReader(image))
+	require.NoError(t, err)
+	require.NoError(t, rom.SetFDSBIOS(make([]byte, fdsBIOSSize)))
+	return rom
+}
+
+func TestParseROM_fds(t *testing.T) {
+	side := newFDSSide([]byte{1, 2, 3})
+
+	rom := newFDSROM(t, side, side)
+	assert.True(t, rom.IsFDS())
+	assert.EqualValues(t, 20, rom.header.MapperNo)
+	assert.Len(t, rom.disk.header, 16)
+	assert.Len(t, rom.disk.sides, 2)
+
+	// headerless
+	rom, err := ParseROM(bytes.NewReader(side))
+	require.NoError(t, err)
+	assert.Empty(t, rom.disk.header)
+	assert.Len(t, rom.disk.sides, 1)
+
+	_, err = ParseROM(bytes.NewReader(side[:fdsSideSize-1]))
+	assert.Error(t, err)
+
+	_, err = rom.Mapper()
+	assert.Error(t, err, "BIOS is required")
+	assert.Error(t, rom.SetFDSBIOS(make([]byte, 0x1000)))
+}
+
+func Test_addGaps(t *testing.T) {
+	side := newFDSSide([]byte{0xAA, 0xBB, 0xCC})
+
+	raw := addGaps(side)
+	assert.Len(t, raw, fdsRawSideSize)
+	assert.EqualValues(t, fdsBlockStart, raw[fdsLeadingGap])
+	assert.Equal(t, side[:56], raw[fdsLeadingGap+1:fdsLeadingGap+57])
+
+	assert.Equal(t, side, removeGaps(raw))
+}
+
+func Test_fds_memory(t *testing.T) {
+	m, err := newFDSROM(t, newFDSSide(nil)).Mapper()
+	require.NoError(t, err)
+
+	m.Write(0x6000, 1)
+	m.Write(0xDFFF, 2)
+	m.Write(0x1FFF, 3)
+	assert.EqualValues(t, 1, m.Read(0x6000))
+	assert.EqualValues(t, 2, m.Read(0xDFFF))
+	assert.EqualValues(t, 3, m.Read(0x1FFF))
+
+	m.Write(0x4025, 0x08)
+	assert.Equal(t, Mirroring_Horizontal, m.Mirroring())
+	m.Write(0x4025, 0x00)
+	assert.Equal(t, Mirroring_Vertical, m.Mirroring())
+}
+
+func Test_fds_timerIRQ(t *testing.T) {
+	m, err := newFDS(newFDSROM(t, newFDSSide(nil)))
+	require.NoError(t, err)
+	f := m.(*fds)
+
+	f.Write(0x4020, 2)
+	f.Write(0x4021, 0)
+	f.Write(0x4022, 0x03)
+	assert.False(t, f.timerEnabled, "disk registers are disabled")
+
+	f.Write(0x4023, 0x01)
+	f.Write(0x4022, 0x03)
+	for i := 0; i < 2; i++ {
+		f.Tick()
+		assert.False(t, f.IRQ())
+	}
+	f.Tick()
+	assert.True(t, f.IRQ())
+
+	assert.EqualValues(t, 0x01, f.Read(0x4030)&0x01)
+	assert.False(t, f.IRQ())
+
+	// repeat
+	for i := 0; i < 3; i++ {
+		f.Tick()
+	}
+	assert.True(t, f.IRQ())
+}
+
+func Test_fds_read(t *testing.T) {
+	m, err := newFDS(newFDSROM(t, newFDSSide([]byte{0xAA})))
+	require.NoError(t, err)
+	f := m.(*fds)
+
+	// motor on, read mode, disk ready, IRQ enabled
+	f.Write(0x4025, 0b1100_0101)
+
+	var got []byte
+	for i := 0; i < 1_000_000 && len(got) < 4; i++ {
+		f.Tick()
+		if f.IRQ() {
+			got = append(got, f.Read(0x4031))
+		}
+	}
+	assert.Equal(t, []byte("\x01*NI"), got)
+	assert.EqualValues(t, 0, f.Read(0x4032)&0x01, "inserted")
+}
+
+func Test_fds_flipSide(t *testing.T) {
+	m, err := newFDS(newFDSROM(t, newFDSSide(nil), newFDSSide(nil)))
+	require.NoError(t, err)
+	f := m.(*fds)
+
+	assert.Equal(t, 2, f.Sides())
+	assert.Equal(t, 0, f.InsertedSide())
+
+	f.FlipSide()
+	assert.Equal(t, -1, f.InsertedSide())
+	assert.EqualValues(t, 0x01, f.Read(0x4032)&0x01, "not inserted")
+
+	for i := 0; i < fdsInsertDelay; i++ {
+		f.Tick()
+	}
+	assert.Equal(t, 1, f.InsertedSide())
+}
+
+func Test_fds_saveData(t *testing.T) {
+	rom := newFDSROM(t, newFDSSide([]byte{0xAA}))
+	m, err := newFDS(rom)
+	require.NoError(t, err)
+	f := m.(*fds)
+
+	// empty patch
+	assert.Equal(t, []byte("PATCHEOF"), f.SaveData())
+
+	// modify the file data after disk info, file amount and file header blocks
+	pos := fdsLeadingGap + (1 + 56 + 2 + fdsBlockGap) + (1 + 2 + 2 + fdsBlockGap) + (1 + 16 + 2 + fdsBlockGap) + 1 + 1
+	require.EqualValues(t, 0xAA, f.disks[0][pos])
+	f.disks[0][pos] = 0xBB
+	save := f.SaveData()
+
+	m, err = newFDS(rom)
+	require.NoError(t, err)
+	g := m.(*fds)
+	require.NoError(t, g.LoadSaveData(save))
+	assert.Equal(t, f.disks, g.disks)
+
+	assert.Error(t, g.LoadSaveData([]byte("invalid")))
+}
+
+func Test_fdsAudio(t *testing.T) {
+	a := newFDSAudio()
+
+	// wave RAM is writable only while enabled
+	a.write(0x4040, 0x3F)
+	assert.EqualValues(t, 0x40, a.read(0x4040))
+	a.write(0x4089, 0x80)
+	for i := uint16(0); i < 64; i++ {
+		a.write(0x4040+i, 0x3F)
+	}
+	assert.EqualValues(t, 0x7F, a.read(0x4040))
+	a.write(0x4089, 0x00)
+
+	// volume 32 without envelope
+	a.write(0x4080, 0x80|0x20)
+	a.write(0x4082, 0xFF)
+	a.write(0x4083, 0x0F)
+	a.write(0x4087, 0x80) // mod disabled
+	a.clock()
+	assert.InDelta(t, 63*32*fdsOutputScale, a.sample(), 1e-6)
+
+	// master volume 2/5
+	a.write(0x4089, 0x03)
+	a.clock()
+	assert.InDelta(t, 63*32*fdsOutputScale*2/5, a.sample(), 1e-6)
+
+	// volume envelope decreases the gain
+	a.write(0x4080, 0x00)
+	a.write(0x408A, 0x01)
+	for i := 0; i < 8; i++ {
+		a.clock()
+	}
+	assert.EqualValues(t, 31, a.vol.gain)
+}
+
+func Test_fdsAudio_pitch(t *testing.T) {
+	a := newFDSAudio()
+	a.write(0x4082, 0x00)
+	a.write(0x4083, 0x01)
+	assert.EqualValues(t, 0x100, a.pitch())
+
+	a.write(0x4087, 0x00)
+	a.write(0x4084, 0x80|0x20) // mod gain 32
+	a.write(0x4085, 0x10)
+	assert.Greater(t, a.pitch(), uint32(0x100))
+
+	a.write(0x4085, 0x70) // -16
+	assert.Less(t, a.pitch(), uint32(0x100))
+}
@@ -0,0 +1,203 @@
+package mapper
+
+import (
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"encoding/xml"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// GameDB is a game database keyed by hashes of PRG+CHR, in the format of NES 2.0 XML database.
+// https://forums.nesdev.org/viewtopic.php?t=19940
+type GameDB struct {
+	byCRC32 map[uint32]*Game
+	bySHA1  map[[sha1.Size]byte]*Game
+}
+
+// Game is an entry of GameDB.
+type Game struct {
+	Title  string
+	CRC32  uint32
+	SHA1   [sha1.Size]byte
+	Header Header
+}
+
+type xmlGameDB struct {
+	Games []xmlGame `xml:"game"`
+}
+
+type xmlGame struct {
+	Comment string `xml:",comment"`
+	Name    string `xml:"name,attr"`
+	PRGROM  xmlROM `xml:"prgrom"`
+	CHRROM  xmlROM `xml:"chrrom"`
+	ROM     xmlROM `xml:"rom"`
+	PCB     struct {
+		Mapper    uint16 `xml:"mapper,attr"`
+		Submapper uint8  `xml:"submapper,attr"`
+		Mirroring string `xml:"mirroring,attr"`
+		Battery   uint8  `xml:"battery,attr"`
+	} `xml:"pcb"`
+}
+
+type xmlROM struct {
+	Size  uint   `xml:"size,attr"`
+	CRC32 string `xml:"crc32,attr"`
+	SHA1  string `xml:"sha1,attr"`
+}
+
+// LoadGameDB reads a game database in the format of NES 2.0 XML database.
+func LoadGameDB(r io.Reader) (*GameDB, error) {
+	var x xmlGameDB
+	if err := xml.NewDecoder(r).Decode(&x); err != nil {
+		return nil, errors.Wrap(err, "failed to parse game database")
+	}
+
+	db := &GameDB{byCRC32: map[uint32]*Game{}, bySHA1: map[[sha1.Size]byte]*Game{}}
+	for _, xg := range x.Games {
+		g, err := xg.game()
+		if err != nil {
+			return nil, err
+		}
+		if xg.ROM.CRC32 != "" {
+			db.byCRC32[g.CRC32] = g
+		}
+		if xg.ROM.SHA1 != "" {
+			db.bySHA1[g.SHA1] = g
+		}
+	}
+	return db, nil
+}
+
+func (x *xmlGame) game() (*Game, error) {
+	g := Game{Title: x.Name}
+	if g.Title == "" {
+		g.Title = strings.TrimSpace(x.Comment)
+	}
+
+	if x.ROM.CRC32 != "" {
+		crc, err := strconv.ParseUint(x.ROM.CRC32, 16, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid crc32 of %s", g.Title)
+		}
+		g.CRC32 = uint32(crc)
+	}
+	if x.ROM.SHA1 != "" {
+		b, err := hex.DecodeString(x.ROM.SHA1)
+		if err != nil || len(b) != sha1.Size {
+			return nil, errors.Errorf("invalid sha1 of %s", g.Title)
+		}
+		copy(g.SHA1[:], b)
+	}
+
+	g.Header = Header{
+		MapperNo:   x.PCB.Mapper,
+		Submapper:  x.PCB.Submapper,
+		PRGROMSize: x.PRGROM.Size / 0x4000,
+		CHRROMSize: x.CHRROM.Size / 0x2000,
+		Battery:    x.PCB.Battery != 0,
+		NES2:       true,
+	}
+	switch x.PCB.Mirroring {
+	case "":
+	case "H", "0":
+		g.Header.Mirroring = Mirroring_Horizontal
+	case "V", "1":
+		g.Header.Mirroring = Mirroring_Vertical
+	case "4":
+		g.Header.Mirroring = Mirroring_FourScreen
+	default:
+		return nil, errors.Errorf("unknown mirroring %q of %s", x.PCB.Mirroring, g.Title)
+	}
+	return &g, nil
+}
+
+// Lookup finds the game of the ROM by SHA-1, or CRC32 of PRG+CHR.
+func (db *GameDB) Lookup(rom *ROM) (*Game, bool) {
+	data := rom.raw
+	if size := rom.header.PRGROMSize*0x4000 + rom.header.CHRROMSize*0x2000; size <= uint(len(data)) {
+		data = data[:size]
+	}
+	if g, ok := db.bySHA1[sha1.Sum(data)]; ok {
+		return g, true
+	}
+	g, ok := db.byCRC32[crc32.ChecksumIEEE(data)]
+	return g, ok
+}
+
+// gamedb.xml is extracted from NES 2.0 XML database for the registered mappers, which has only nestest until extracted.
+// Put nes20db.xml from https://forums.nesdev.org/viewtopic.php?t=19940 in this directory to extract it.
+//
+//go:generate go run gamedb_gen.go -src nes20db.xml
+//go:embed gamedb.xml
+var embeddedGameDB []byte
+
+var (
+	defaultGameDB     *GameDB
+	defaultGameDBOnce sync.Once
+)
+
+// DefaultGameDB returns the game database embedded in this package.
+func DefaultGameDB() *GameDB {
+	defaultGameDBOnce.Do(func() {
+		db, err := LoadGameDB(bytes.NewReader(embeddedGameDB))
+		if err != nil {
+			panic(err)
+		}
+		defaultGameDB = db
+	})
+	return defaultGameDB
+}
+
+// LoadROM parses a ROM, and fixes its header by the game found first in dbs, or DefaultGameDB if no dbs are given.
+//
+// The game is nil if not found. Frontends should load ROMs by this rather than ParseROM to fix bad headers.
+func LoadROM(r io.Reader, dbs ...*GameDB) (*ROM, *Game, error) {
+	rom, err := ParseROM(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(dbs) == 0 {
+		dbs = []*GameDB{DefaultGameDB()}
+	}
+	g, _ := rom.ApplyGameDB(dbs...)
+	return rom, g, nil
+}
+
+// ApplyGameDB overrides the header of this rom by the entry found first in dbs, and returns the game.
+//
+// Header fields which are unknown to the database are kept.
+func (r *ROM) ApplyGameDB(dbs ...*GameDB) (*Game, bool) {
+	if r.disk != nil {
+		return nil, false
+	}
+	var g *Game
+	for _, db := range dbs {
+		if found, ok := db.Lookup(r); ok {
+			g = found
+			break
+		}
+	}
+	if g == nil {
+		return nil, false
+	}
+	h := g.Header
+	if h.Mirroring == 0 {
+		h.Mirroring = r.header.Mirroring
+	}
+	// keep the sizes of the file if not given, or larger than the file
+	if h.PRGROMSize == 0 || uint(len(r.raw)) < h.PRGROMSize*0x4000+h.CHRROMSize*0x2000 {
+		h.PRGROMSize = r.header.PRGROMSize
+		h.CHRROMSize = r.header.CHRROMSize
+	}
+	r.header = h
+	return g, true
+}
@@ -0,0 +1,98 @@
+//go:build ignore
+
+// This program extracts games of the registered mappers from NES 2.0 XML database into gamedb.xml,
+// keeping entries of gamedb.xml which are not in the database like test ROMs.
+//
+//	go run gamedb_gen.go -src nes20db.xml
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/thara/gorones/mapper"
+)
+
+var (
+	gameRe    = regexp.MustCompile(`(?s)<game>.*?</game>`)
+	mapperRe  = regexp.MustCompile(`<pcb[^>]*\smapper="(\d+)"`)
+	romSHA1Re = regexp.MustCompile(`<rom[^>]*\ssha1="([0-9A-Fa-f]{40})"`)
+)
+
+const header = `<?xml version="1.0" encoding="UTF-8"?>
+<!-- Game database in the format of NES 2.0 XML database, extracted for the supported mappers by gamedb_gen.go -->
+<!-- https://forums.nesdev.org/viewtopic.php?t=19940 -->
+<nes20db>
+`
+
+func main() {
+	src := flag.String("src", "", "path to NES 2.0 XML database")
+	out := flag.String("o", "gamedb.xml", "path to write the extracted database")
+	flag.Parse()
+	if *src == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := os.ReadFile(*src)
+	if err != nil {
+		log.Fatal(err)
+	}
+	supported := map[uint64]bool{}
+	for _, r := range mapper.Registered() {
+		supported[uint64(r.MapperNo)] = true
+	}
+
+	var games []string
+	seen := map[string]bool{}
+	for _, g := range gameRe.FindAllString(string(db), -1) {
+		m := mapperRe.FindStringSubmatch(g)
+		if m == nil {
+			continue
+		}
+		if n, err := strconv.ParseUint(m[1], 10, 16); err != nil || !supported[n] {
+			continue
+		}
+		games = append(games, g)
+		seen[romSHA1(g)] = true
+	}
+	n := len(games)
+
+	current, err := os.ReadFile(*out)
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatal(err)
+	}
+	for _, g := range gameRe.FindAllString(string(current), -1) {
+		if !seen[romSHA1(g)] {
+			games = append(games, g)
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString(header)
+	for _, g := range games {
+		b.WriteString("\t" + g + "\n")
+	}
+	b.WriteString("</nes20db>\n")
+
+	if _, err := mapper.LoadGameDB(bytes.NewReader(b.Bytes())); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, b.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s: %d games from %s, %d kept\n", *out, n, *src, len(games)-n)
+}
+
+func romSHA1(game string) string {
+	if m := romSHA1Re.FindStringSubmatch(game); m != nil {
+		return strings.ToUpper(m[1])
+	}
+	return game
+}
@@ -0,0 +1,147 @@
+package mapper
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DefaultGameDB(t *testing.T) {
+	f, err := os.Open("../testdata/nestest.nes")
+	require.NoError(t, err)
+	defer f.Close()
+
+	rom, err := ParseROM(f)
+	require.NoError(t, err)
+
+	g, ok := rom.ApplyGameDB(DefaultGameDB())
+	require.True(t, ok)
+	assert.Equal(t, "nestest", g.Title)
+	assert.True(t, rom.header.NES2)
+	assert.EqualValues(t, 0, rom.header.MapperNo)
+	assert.EqualValues(t, 1, rom.header.PRGROMSize)
+}
+
+const testGameDB = `<nes20db>
+	<game>
+		<!-- Bad Header Game -->
+		<rom size="24576" crc32="158B0388"/>
+		<pcb mapper="16" submapper="5" mirroring="V" battery="1"/>
+	</game>
+	<game name="by SHA-1">
+		<rom sha1="0000000000000000000000000000000000000000"/>
+		<pcb mapper="1"/>
+	</game>
+</nes20db>`
+
+func TestLoadGameDB(t *testing.T) {
+	db, err := LoadGameDB(strings.NewReader(testGameDB))
+	require.NoError(t, err)
+	assert.Len(t, db.byCRC32, 1)
+	assert.Len(t, db.bySHA1, 1)
+
+	f, err := os.Open("../testdata/nestest.nes")
+	require.NoError(t, err)
+	defer f.Close()
+	rom, err := ParseROM(f)
+	require.NoError(t, err)
+
+	// override file takes precedence
+	g, ok := rom.ApplyGameDB(db, DefaultGameDB())
+	require.True(t, ok)
+	assert.Equal(t, "Bad Header Game", g.Title)
+	assert.Equal(t, Header{
+		MapperNo:   16,
+		Submapper:  5,
+		PRGROMSize: 1,
+		CHRROMSize: 1,
+		Mirroring:  Mirroring_Vertical,
+		Battery:    true,
+		NES2:       true,
+	}, rom.Header())
+
+	_, ok = (&ROM{raw: []byte{1, 2, 3}}).ApplyGameDB(db)
+	assert.False(t, ok)
+
+	_, err = LoadGameDB(strings.NewReader(`<nes20db><game><rom crc32="XYZ"/></game></nes20db>`))
+	assert.Error(t, err)
+	_, err = LoadGameDB(strings.NewReader(`<nes20db><game><rom sha1="00"/></game></nes20db>`))
+	assert.Error(t, err)
+	_, err = LoadGameDB(strings.NewReader(`<nes20db>`))
+	assert.Error(t, err)
+}
+
+func TestLoadGameDB_mirroring(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Mirroring
+	}{
+		{"", 0},
+		{"H", Mirroring_Horizontal},
+		{"0", Mirroring_Horizontal},
+		{"V", Mirroring_Vertical},
+		{"1", Mirroring_Vertical},
+		{"4", Mirroring_FourScreen},
+	}
+	for _, tt := range tests {
+		db, err := LoadGameDB(strings.NewReader(`<nes20db><game><rom crc32="158B0388"/><pcb mirroring="` + tt.value + `"/></game></nes20db>`))
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.want, db.byCRC32[0x158B0388].Header.Mirroring, tt.value)
+	}
+
+	_, err := LoadGameDB(strings.NewReader(`<nes20db><game><rom crc32="158B0388"/><pcb mirroring="X"/></game></nes20db>`))
+	assert.Error(t, err)
+}
+
+func TestLoadROM(t *testing.T) {
+	b, err := os.ReadFile("../testdata/nestest.nes")
+	require.NoError(t, err)
+
+	rom, g, err := LoadROM(bytes.NewReader(b))
+	require.NoError(t, err)
+	require.NotNil(t, g)
+	assert.Equal(t, "nestest", g.Title)
+	assert.True(t, rom.Header().NES2, "fixed by DefaultGameDB")
+
+	db, err := LoadGameDB(strings.NewReader(testGameDB))
+	require.NoError(t, err)
+	rom, g, err = LoadROM(bytes.NewReader(b), db)
+	require.NoError(t, err)
+	assert.Equal(t, "Bad Header Game", g.Title)
+	assert.EqualValues(t, 16, rom.Header().MapperNo)
+
+	rom, g, err = LoadROM(bytes.NewReader(b), &GameDB{})
+	require.NoError(t, err)
+	assert.Nil(t, g)
+	assert.False(t, rom.Header().NES2)
+
+	_, _, err = LoadROM(bytes.NewReader(nil))
+	assert.Error(t, err)
+}
+
+func TestLoadROM_badHeader(t *testing.T) {
+	b, err := os.ReadFile("../testdata/nestest.nes")
+	require.NoError(t, err)
+	// a header broken by "DiskDude!" and vertical mirroring
+	b[6] |= 1
+	copy(b[7:16], "DiskDude!")
+
+	rom, err := ParseROM(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, rom.Header().MapperNo, "upper bits of mapper number are ignored")
+	assert.Equal(t, Mirroring_Vertical, rom.Header().Mirroring)
+
+	rom, g, err := LoadROM(bytes.NewReader(b))
+	require.NoError(t, err)
+	require.NotNil(t, g)
+	assert.Equal(t, Header{
+		PRGROMSize: 1,
+		CHRROMSize: 1,
+		Mirroring:  Mirroring_Horizontal,
+		NES2:       true,
+	}, rom.Header(), "fixed by DefaultGameDB")
+}
@@ -2,8 +2,6 @@ package mapper
 
 import (
 	"fmt"
-
-	"github.com/pkg/errors"
 )
 
 // https://www.nesdev.org/wiki/Mapper
@@ -22,38 +20,50 @@ type Mapper interface {
 	CHR() []byte
 }
 
-// Mapper creates a mapper object from this rom's data
-func (r *ROM) Mapper() (Mapper, error) {
-	switch r.header.mapperNO {
-	case 0:
-		return newMapper0(r), nil
-	}
-	return nil, errors.Errorf("unsupported mapper no: %d", r.header.mapperNO)
+// IRQSource is implemented by mappers which can assert the CPU /IRQ line.
+type IRQSource interface {
+	// IRQ reports whether the mapper is asserting IRQ now.
+	IRQ() bool
+}
+
+// NametableMapper is implemented by mappers which control the nametable space ($2000-$2FFF) by themselves
+// instead of hard-wired mirroring.
+type NametableMapper interface {
+	ReadNametable(addr uint16) uint8
+	WriteNametable(addr uint16, value uint8)
+}
+
+// Persistent is implemented by mappers which have data to be kept across power cycles, like battery-backed RAM.
+type Persistent interface {
+	// SaveData returns the data to be saved, or nil if nothing to be saved.
+	SaveData() []byte
+
+	// LoadSaveData restores the data returned by SaveData.
+	LoadSaveData([]byte) error
+}
+
+func init() {
+	Register(0, AnySubmapper, "NROM", infallible(newMapper0))
 }
 
 type mapper0 struct {
 	prg []byte
 	chr []byte
 
+	// Family BASIC has PRG RAM, which test ROMs also use to report results
+	prgRAM [0x2000]uint8
+
 	mirroring Mirroring
 	mirrored  bool
 }
 
 func newMapper0(rom *ROM) Mapper {
-	prgSize := rom.header.prgROMSize * 0x4000
-	prg := rom.raw[:prgSize]
-
-	var chr []byte
-	if rom.header.chrROMSize == 0 {
-		chr = make([]byte, 0x2000)
-	} else {
-		chrSize := rom.header.chrROMSize * 0x2000
-		chr = rom.raw[prgSize : prgSize+chrSize]
-	}
+	prg, chr := rom.Banks()
+	prgSize := rom.header.PRGROMSize * 0x4000
 	return &mapper0{
 		prg:       prg,
 		chr:       chr,
-		mirroring: rom.header.mirroring,
+		mirroring: rom.header.Mirroring,
 		mirrored:  prgSize == 0x4000,
 	}
 }
@@ -62,6 +72,8 @@ func (m *mapper0) Read(addr uint16) uint8 {
 	switch {
 	case 0x0000 <= addr && addr <= 0x1FFF:
 		return m.chr[addr]
+	case 0x6000 <= addr && addr <= 0x7FFF:
+		return m.prgRAM[addr-0x6000]
 	case 0x8000 <= addr && addr <= 0xFFFF:
 		if m.mirrored {
 			addr %= 0x4000
@@ -77,6 +89,8 @@ func (m *mapper0) Write(addr uint16, value uint8) {
 	switch {
 	case 0x0000 <= addr && addr <= 0x1FFF:
 		m.chr[addr] = value
+	case 0x6000 <= addr && addr <= 0x7FFF:
+		m.prgRAM[addr-0x6000] = value
 	}
 }
 
@@ -0,0 +1,177 @@
+package mapper
+
+import (
+	"fmt"
+)
+
+// https://www.nesdev.org/wiki/INES_Mapper_019
+
+// Namco163 is the interface of Namco 163 specific features.
+type Namco163 interface {
+	Mapper
+
+	// SetCleanMixing switches how the wavetable channels are mixed.
+	//
+	// By default, the output is time-multiplexed like the real chip, so the switching noise is audible when many channels are enabled.
+	// If clean is true, all enabled channels are mixed at once instead.
+	SetCleanMixing(clean bool)
+}
+
+func init() {
+	Register(19, AnySubmapper, "Namco 163", infallible(newNamco163))
+	RegisterUNIFBoard("NAMCOT-163", 19, AnySubmapper)
+}
+
+type namco163 struct {
+	prg []byte
+	chr []byte
+
+	prgRAM [0x2000]uint8
+	ciram  [0x0800]uint8
+
+	prgBanks [3]uint8 // $8000, $A000, $C000 ($E000 is fixed to the last bank)
+	chrBanks [8]uint8 // 1KB banks of $0000-$1FFF
+	ntBanks  [4]uint8 // 1KB banks of $2000-$2FFF
+
+	chrRAMDisabledLow  bool // CIRAM can not be selected as $0000-$0FFF
+	chrRAMDisabledHigh bool // CIRAM can not be selected as $1000-$1FFF
+
+	irqCounter uint16 // 15 bits
+	irqEnabled bool
+	irq        bool
+
+	audio namco163Audio
+
+	mirroring Mirroring
+}
+
+func newNamco163(rom *ROM) Mapper {
+	prg, chr := rom.Banks()
+	return &namco163{
+		prg:       prg,
+		chr:       chr,
+		mirroring: rom.header.Mirroring,
+	}
+}
+
+func (m *namco163) Read(addr uint16) uint8 {
+	switch {
+	case 0x0000 <= addr && addr <= 0x1FFF:
+		return *m.chrAt(addr)
+	case 0x4800 <= addr && addr <= 0x4FFF:
+		return m.audio.readData()
+	case 0x5000 <= addr && addr <= 0x57FF:
+		return uint8(m.irqCounter)
+	case 0x5800 <= addr && addr <= 0x5FFF:
+		v := uint8(m.irqCounter >> 8)
+		if m.irqEnabled {
+			v |= 0x80
+		}
+		return v
+	case 0x6000 <= addr && addr <= 0x7FFF:
+		return m.prgRAM[addr-0x6000]
+	case 0x8000 <= addr && addr <= 0xFFFF:
+		var bank int
+		if addr < 0xE000 {
+			bank = int(m.prgBanks[(addr-0x8000)/0x2000])
+		} else {
+			bank = len(m.prg)/0x2000 - 1
+		}
+		return m.prg[(bank*0x2000+int(addr%0x2000))%len(m.prg)]
+	}
+	return 0
+}
+
+func (m *namco163) Write(addr uint16, value uint8) {
+	switch {
+	case 0x0000 <= addr && addr <= 0x1FFF:
+		*m.chrAt(addr) = value
+	case 0x4800 <= addr && addr <= 0x4FFF:
+		m.audio.writeData(value)
+	case 0x5000 <= addr && addr <= 0x57FF:
+		m.irqCounter = m.irqCounter&0x7F00 | uint16(value)
+		m.irq = false
+	case 0x5800 <= addr && addr <= 0x5FFF:
+		m.irqCounter = m.irqCounter&0x00FF | uint16(value&0x7F)<<8
+		m.irqEnabled = value&0x80 != 0
+		m.irq = false
+	case 0x6000 <= addr && addr <= 0x7FFF:
+		m.prgRAM[addr-0x6000] = value
+	case 0x8000 <= addr && addr <= 0xBFFF:
+		m.chrBanks[(addr-0x8000)/0x0800] = value
+	case 0xC000 <= addr && addr <= 0xDFFF:
+		m.ntBanks[(addr-0xC000)/0x0800] = value
+	case 0xE000 <= addr && addr <= 0xE7FF:
+		m.prgBanks[0] = value & 0x3F
+		m.audio.disabled = value&0x40 != 0
+	case 0xE800 <= addr && addr <= 0xEFFF:
+		m.prgBanks[1] = value & 0x3F
+		m.chrRAMDisabledLow = value&0x40 != 0
+		m.chrRAMDisabledHigh = value&0x80 != 0
+	case 0xF000 <= addr && addr <= 0xF7FF:
+		m.prgBanks[2] = value & 0x3F
+	case 0xF800 <= addr && addr <= 0xFFFF:
+		m.audio.writeAddr(value)
+	}
+}
+
+// chrAt returns the location of a pattern table byte, which may be either CHR or CIRAM.
+func (m *namco163) chrAt(addr uint16) *uint8 {
+	bank := m.chrBanks[addr/0x0400]
+
+	ciramDisabled := m.chrRAMDisabledLow
+	if 0x1000 <= addr {
+		ciramDisabled = m.chrRAMDisabledHigh
+	}
+	if 0xE0 <= bank && !ciramDisabled {
+		return &m.ciram[uint16(bank&1)*0x0400+addr%0x0400]
+	}
+	return &m.chr[(int(bank)*0x0400+int(addr%0x0400))%len(m.chr)]
+}
+
+func (m *namco163) ReadNametable(addr uint16) uint8 {
+	bank := m.ntBanks[(addr&0x0FFF)/0x0400]
+	if 0xE0 <= bank {
+		return m.ciram[uint16(bank&1)*0x0400+addr%0x0400]
+	}
+	return m.chr[(int(bank)*0x0400+int(addr%0x0400))%len(m.chr)]
+}
+
+func (m *namco163) WriteNametable(addr uint16, value uint8) {
+	bank := m.ntBanks[(addr&0x0FFF)/0x0400]
+	if 0xE0 <= bank {
+		m.ciram[uint16(bank&1)*0x0400+addr%0x0400] = value
+	}
+	// CHR ROM is not writable
+}
+
+// Tick clocks IRQ counter and wavetable audio on each CPU cycle.
+func (m *namco163) Tick() {
+	if m.irqEnabled && m.irqCounter < 0x7FFF {
+		m.irqCounter++
+		if m.irqCounter == 0x7FFF {
+			m.irq = true
+		}
+	}
+	m.audio.clock()
+}
+
+func (m *namco163) IRQ() bool { return m.irq }
+
+func (m *namco163) Sample() float32 { return m.audio.output() }
+
+func (m *namco163) SetCleanMixing(clean bool) { m.audio.clean = clean }
+
+func (m *namco163) Mirroring() Mirroring {
+	return m.mirroring
+}
+
+func (m *namco163) PRG() []byte { return append([]byte(nil), m.prg...) }
+func (m *namco163) CHR() []byte { return append([]byte(nil), m.chr...) }
+
+func (m namco163) String() string {
+	return fmt.Sprintf(`mapper 19 (Namco 163):
+	PRG: 0x%x byte
+	CHR: 0x%x byte
+`, len(m.prg), len(m.chr))
+}
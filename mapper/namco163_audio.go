@@ -0,0 +1,134 @@
+package mapper
+
+// https://www.nesdev.org/wiki/Namco_163_audio
+
+const (
+	// each enabled channel is updated in turn every 15 CPU cycles
+	namco163ChannelCycles = 15
+
+	// scales a channel output (-120 ..= 105) to the APU output level.
+	// A channel at full volume is roughly twice as loud as a 2A03 pulse at full volume.
+	namco163OutputScale = 0.3 / 120
+)
+
+type namco163Audio struct {
+	// internal RAM shared by waveform samples and channel registers ($40-$7F)
+	ram [0x80]uint8
+
+	addr          uint8
+	autoIncrement bool
+
+	disabled bool
+	clean    bool
+
+	cycles  uint8
+	channel uint8 // channel currently being updated: 0 ..= 7
+
+	outputs [8]int
+}
+
+// channel registers
+//
+// $x0 frequency low
+// $x1 phase low
+// $x2 frequency middle
+// $x3 phase middle
+// $x4 frequency high (bits 0-1), wave length (bits 2-7)
+// $x5 phase high
+// $x6 wave address
+// $x7 volume (bits 0-3), number of enabled channels (bits 4-6, only at $7F)
+func namco163ChannelBase(ch uint8) uint8 { return 0x40 + ch*8 }
+
+func (a *namco163Audio) writeAddr(value uint8) {
+	a.addr = value & 0x7F
+	a.autoIncrement = value&0x80 != 0
+}
+
+func (a *namco163Audio) readData() uint8 {
+	v := a.ram[a.addr]
+	a.incrAddr()
+	return v
+}
+
+func (a *namco163Audio) writeData(value uint8) {
+	a.ram[a.addr] = value
+	a.incrAddr()
+}
+
+func (a *namco163Audio) incrAddr() {
+	if a.autoIncrement {
+		a.addr = (a.addr + 1) & 0x7F
+	}
+}
+
+// enabledChannels returns the number of enabled channels: 1 ..= 8
+//
+// The enabled channels are always the last ones: channel 7 only, channel 6 and 7, ...
+func (a *namco163Audio) enabledChannels() uint8 {
+	return (a.ram[0x7F]>>4)&0b111 + 1
+}
+
+func (a *namco163Audio) clock() {
+	a.cycles++
+	if a.cycles < namco163ChannelCycles {
+		return
+	}
+	a.cycles = 0
+
+	first := 8 - a.enabledChannels()
+	if a.channel < first {
+		a.channel = first
+	}
+	a.updateChannel(a.channel)
+
+	a.channel++
+	if 7 < a.channel {
+		a.channel = 8 - a.enabledChannels()
+	}
+}
+
+func (a *namco163Audio) updateChannel(ch uint8) {
+	base := namco163ChannelBase(ch)
+
+	freq := uint32(a.ram[base]) | uint32(a.ram[base+2])<<8 | uint32(a.ram[base+4]&0b11)<<16
+	phase := uint32(a.ram[base+1]) | uint32(a.ram[base+3])<<8 | uint32(a.ram[base+5])<<16
+	length := 256 - uint32(a.ram[base+4]&0b11111100)
+
+	phase = (phase + freq) % (length << 16)
+
+	a.ram[base+1] = uint8(phase)
+	a.ram[base+3] = uint8(phase >> 8)
+	a.ram[base+5] = uint8(phase >> 16)
+
+	// samples are 4-bit, packed in little-endian order
+	addr := uint8(phase>>16) + a.ram[base+6]
+	sample := a.ram[addr>>1] >> ((addr & 1) * 4) & 0x0F
+
+	volume := int(a.ram[base+7] & 0x0F)
+	a.outputs[ch] = (int(sample) - 8) * volume
+}
+
+func (a *namco163Audio) output() float32 {
+	if a.disabled {
+		return 0
+	}
+
+	if !a.clean {
+		// the chip outputs only the channel updated last
+		var ch uint8
+		if a.channel == 8-a.enabledChannels() {
+			ch = 7
+		} else {
+			ch = a.channel - 1
+		}
+		return float32(a.outputs[ch]) * namco163OutputScale
+	}
+
+	// time-multiplexing averages enabled channels
+	n := a.enabledChannels()
+	var sum int
+	for ch := 8 - n; ch < 8; ch++ {
+		sum += a.outputs[ch]
+	}
+	return float32(sum) / float32(n) * namco163OutputScale
+}
@@ -0,0 +1,141 @@
+package mapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newNamco163ROM() *ROM {
+	// 128KB PRG, 128KB CHR; each byte holds its bank number
+	prg := make([]byte, 0x20000)
+	for i := range prg {
+		prg[i] = uint8(i / 0x2000)
+	}
+	chr := make([]byte, 0x20000)
+	for i := range chr {
+		chr[i] = uint8(i / 0x0400)
+	}
+	return &ROM{
+		header: Header{MapperNo: 19, PRGROMSize: 8, CHRROMSize: 16, Mirroring: Mirroring_Vertical},
+		raw:    append(prg, chr...),
+	}
+}
+
+func Test_namco163_prg(t *testing.T) {
+	m := newNamco163(newNamco163ROM()).(*namco163)
+
+	m.Write(0xE000, 3)
+	m.Write(0xE800, 5)
+	m.Write(0xF000, 7)
+
+	assert.EqualValues(t, 3, m.Read(0x8000))
+	assert.EqualValues(t, 5, m.Read(0xA000))
+	assert.EqualValues(t, 7, m.Read(0xC000))
+	assert.EqualValues(t, 15, m.Read(0xE000), "fixed to the last bank")
+}
+
+func Test_namco163_chr(t *testing.T) {
+	m := newNamco163(newNamco163ROM()).(*namco163)
+
+	m.Write(0x8000, 0x12)
+	m.Write(0x8800, 0xE1)
+	assert.EqualValues(t, 0x12, m.Read(0x0000))
+
+	// $E0-$FF selects CIRAM
+	m.Write(0x0400, 0xAB)
+	assert.EqualValues(t, 0xAB, m.Read(0x0400))
+	assert.EqualValues(t, 0xAB, m.ciram[0x0400])
+
+	// CIRAM disabled for $0000-$0FFF, so bank $E1 selects CHR ROM (mirrored by its size)
+	m.Write(0xE800, 0x40)
+	assert.EqualValues(t, 0xE1%0x80, m.Read(0x0400))
+}
+
+func Test_namco163_nametable(t *testing.T) {
+	m := newNamco163(newNamco163ROM()).(*namco163)
+
+	m.Write(0xC000, 0xE0)
+	m.Write(0xC800, 0xE1)
+	m.Write(0xD000, 0x20)
+
+	m.WriteNametable(0x2010, 0x55)
+	m.WriteNametable(0x2410, 0x66)
+	assert.EqualValues(t, 0x55, m.ReadNametable(0x2010))
+	assert.EqualValues(t, 0x66, m.ReadNametable(0x2410))
+	assert.EqualValues(t, 0x66, m.ciram[0x0410])
+
+	// CHR ROM as nametable
+	assert.EqualValues(t, 0x20, m.ReadNametable(0x2810))
+	m.WriteNametable(0x2810, 0x77)
+	assert.EqualValues(t, 0x20, m.ReadNametable(0x2810))
+}
+
+func Test_namco163_irq(t *testing.T) {
+	m := newNamco163(newNamco163ROM()).(*namco163)
+
+	m.Write(0x5000, 0xFD)
+	m.Write(0x5800, 0xFF)
+	assert.EqualValues(t, 0xFD, m.Read(0x5000))
+	assert.EqualValues(t, 0xFF, m.Read(0x5800))
+
+	m.Tick()
+	assert.False(t, m.IRQ())
+	m.Tick()
+	assert.True(t, m.IRQ())
+
+	// stops counting at $7FFF
+	m.Tick()
+	assert.EqualValues(t, 0x7FFF, m.irqCounter)
+
+	// acknowledge
+	m.Write(0x5800, 0x00)
+	assert.False(t, m.IRQ())
+}
+
+func Test_namco163Audio(t *testing.T) {
+	var a namco163Audio
+
+	// auto-increment
+	a.writeAddr(0x80)
+	a.writeData(0xF0)
+	a.writeData(0x5A)
+	assert.EqualValues(t, 0xF0, a.ram[0])
+	assert.EqualValues(t, 0x5A, a.ram[1])
+
+	// channel 7: frequency $10000 (1 sample per update), length 4, volume 15, 1 channel
+	a.writeAddr(0x78 | 0x80)
+	for _, v := range []uint8{0x00, 0x00, 0x00, 0x00, 0xFC | 0x01, 0x00, 0x00, 0x0F} {
+		a.writeData(v)
+	}
+
+	var outputs []int
+	for i := 0; i < 4*namco163ChannelCycles; i++ {
+		a.clock()
+		if a.cycles == 0 {
+			outputs = append(outputs, a.outputs[7])
+		}
+	}
+	// samples: 0, F, A, 5
+	require.Len(t, outputs, 4)
+	assert.Equal(t, []int{(0xF - 8) * 15, (0xA - 8) * 15, (0x5 - 8) * 15, (0x0 - 8) * 15}, outputs)
+}
+
+func Test_namco163Audio_output(t *testing.T) {
+	var a namco163Audio
+	a.ram[0x7F] = 1 << 4 // 2 channels
+	a.outputs[6] = 10
+	a.outputs[7] = -30
+
+	a.channel = 7 // channel 6 was updated last
+	assert.InDelta(t, 10*namco163OutputScale, a.output(), 1e-6)
+	a.channel = 6 // channel 7 was updated last
+	assert.InDelta(t, -30*namco163OutputScale, a.output(), 1e-6)
+
+	a.clean = true
+	assert.InDelta(t, -10*namco163OutputScale, a.output(), 1e-6)
+
+	a.disabled = true
+	assert.Zero(t, a.output())
+}
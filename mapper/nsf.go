@@ -0,0 +1,171 @@
+package mapper
+
+import (
+	"fmt"
+
+	"github.com/thara/gorones/opll"
+)
+
+// https://www.nesdev.org/wiki/NSF#Bankswitching
+
+// Expansion sound chips of NSF
+const (
+	NSFChipVRC6 = 1 << iota
+	NSFChipVRC7
+	NSFChipFDS
+	NSFChipMMC5
+	NSFChipN163
+	NSFChipS5B
+)
+
+var nsfChipNames = []string{"VRC6", "VRC7", "FDS", "MMC5", "Namco 163", "Sunsoft 5B"}
+
+// supported expansion sound chips by NSF board
+const nsfSupportedChips = NSFChipVRC7 | NSFChipFDS | NSFChipN163
+
+// NSFChipNames returns names of expansion sound chips in chips.
+func NSFChipNames(chips uint8) []string {
+	var names []string
+	for i, n := range nsfChipNames {
+		if chips&(1<<i) != 0 {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+type nsfBoard struct {
+	prg   []byte
+	banks [10]uint8 // 4KB banks at $6000-$FFFF
+	chips uint8
+
+	// PRG RAM at $6000-$7FFF, or $6000-$DFFF for FDS
+	ram [0x8000]uint8
+
+	fm       *opll.OPLL
+	fmCycles uint8
+	n163     namco163Audio
+	fds      fdsAudio
+}
+
+// NewNSFBoard creates a board to play NSF, which has 4KB PRG banks switched via $5FF6-$5FFF.
+//
+// banks are the initial bank numbers at $6000-$FFFF, where those at $6000-$7FFF are used for FDS only.
+// Expansion sound chips other than VRC7, FDS and Namco 163 are ignored.
+func NewNSFBoard(prg []byte, banks [10]uint8, chips uint8) Mapper {
+	m := &nsfBoard{prg: prg, chips: chips & nsfSupportedChips}
+	if m.chips&NSFChipVRC7 != 0 {
+		m.fm = opll.New()
+	}
+	if m.chips&NSFChipFDS != 0 {
+		m.fds = newFDSAudio()
+	}
+	for i, b := range banks {
+		m.switchBank(i, b)
+	}
+	return m
+}
+
+// UnsupportedNSFChips returns expansion sound chips in chips which are not supported by NSF board.
+func UnsupportedNSFChips(chips uint8) uint8 {
+	return chips &^ nsfSupportedChips
+}
+
+func (m *nsfBoard) switchBank(i int, bank uint8) {
+	m.banks[i] = bank
+	if m.chips&NSFChipFDS != 0 && i < 8 {
+		// FDS has RAM which bank switching copies into
+		start := int(bank) * 0x1000
+		for j := 0; j < 0x1000; j++ {
+			m.ram[i*0x1000+j] = m.prgAt(start + j)
+		}
+	}
+}
+
+func (m *nsfBoard) prgAt(i int) uint8 {
+	if len(m.prg) <= i {
+		return 0
+	}
+	return m.prg[i]
+}
+
+func (m *nsfBoard) Read(addr uint16) uint8 {
+	switch {
+	case 0x4040 <= addr && addr <= 0x4092 && m.chips&NSFChipFDS != 0:
+		return m.fds.read(addr)
+	case 0x4800 <= addr && addr <= 0x4FFF && m.chips&NSFChipN163 != 0:
+		return m.n163.readData()
+	case 0x6000 <= addr && addr <= 0x7FFF:
+		return m.ram[addr-0x6000]
+	case 0x8000 <= addr && addr <= 0xDFFF && m.chips&NSFChipFDS != 0:
+		return m.ram[addr-0x6000]
+	case 0x8000 <= addr && addr <= 0xFFFF:
+		i := (addr - 0x6000) / 0x1000
+		return m.prgAt(int(m.banks[i])*0x1000 + int(addr%0x1000))
+	}
+	return 0
+}
+
+func (m *nsfBoard) Write(addr uint16, value uint8) {
+	switch {
+	case 0x4040 <= addr && addr <= 0x408A && m.chips&NSFChipFDS != 0:
+		m.fds.write(addr, value)
+	case 0x4800 <= addr && addr <= 0x4FFF && m.chips&NSFChipN163 != 0:
+		m.n163.writeData(value)
+	case 0xF800 <= addr && addr <= 0xFFFF && m.chips&NSFChipN163 != 0:
+		m.n163.writeAddr(value)
+	case addr == 0x9010 && m.chips&NSFChipVRC7 != 0:
+		m.fm.WriteAddress(value)
+	case addr == 0x9030 && m.chips&NSFChipVRC7 != 0:
+		m.fm.WriteData(value)
+	case 0x5FF6 <= addr && addr <= 0x5FFF:
+		m.switchBank(int(addr-0x5FF6), value)
+	case 0x6000 <= addr && addr <= 0x7FFF:
+		m.ram[addr-0x6000] = value
+	case 0x8000 <= addr && addr <= 0xDFFF && m.chips&NSFChipFDS != 0:
+		m.ram[addr-0x6000] = value
+	}
+}
+
+// Tick clocks expansion sound chips on each CPU cycle.
+func (m *nsfBoard) Tick() {
+	if m.fm != nil {
+		m.fmCycles++
+		if vrc7AudioCycles <= m.fmCycles {
+			m.fmCycles = 0
+			m.fm.Clock()
+		}
+	}
+	if m.chips&NSFChipN163 != 0 {
+		m.n163.clock()
+	}
+	if m.chips&NSFChipFDS != 0 {
+		m.fds.clock()
+	}
+}
+
+func (m *nsfBoard) Sample() float32 {
+	var out float32
+	if m.fm != nil {
+		out += m.fm.Output() * vrc7OutputScale
+	}
+	if m.chips&NSFChipN163 != 0 {
+		out += m.n163.output()
+	}
+	if m.chips&NSFChipFDS != 0 {
+		out += m.fds.sample()
+	}
+	return out
+}
+
+func (m *nsfBoard) Mirroring() Mirroring { return Mirroring_Vertical }
+
+func (m *nsfBoard) PRG() []byte { return append([]byte(nil), m.prg...) }
+func (m *nsfBoard) CHR() []byte { return nil }
+
+func (m nsfBoard) String() string {
+	return fmt.Sprintf(`NSF:
+	PRG: 0x%x byte
+	chips: %06b
+`, len(m.prg), m.chips)
+}
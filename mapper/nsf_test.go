@@ -0,0 +1,16 @@
+package mapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnsupportedNSFChips(t *testing.T) {
+	chips := uint8(NSFChipVRC6 | NSFChipFDS | NSFChipMMC5 | NSFChipS5B)
+	c := UnsupportedNSFChips(chips)
+	assert.EqualValues(t, NSFChipVRC6|NSFChipMMC5|NSFChipS5B, c)
+	assert.Equal(t, []string{"VRC6", "MMC5", "Sunsoft 5B"}, NSFChipNames(c))
+	assert.Equal(t, []string{"VRC7", "Namco 163"}, NSFChipNames(NSFChipVRC7|NSFChipN163))
+	assert.Nil(t, NSFChipNames(0))
+}
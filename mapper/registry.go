@@ -0,0 +1,106 @@
+package mapper
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Constructor creates a mapper from a ROM, whose parsed header information is available by ROM.Header.
+type Constructor func(rom *ROM) (Mapper, error)
+
+// AnySubmapper registers a constructor for all submappers which are not registered individually.
+const AnySubmapper = -1
+
+// Registration describes a registered mapper.
+type Registration struct {
+	MapperNo  uint16
+	Submapper int // AnySubmapper if not specific
+	Name      string
+
+	new Constructor
+}
+
+func (r Registration) String() string {
+	if r.Submapper == AnySubmapper {
+		return fmt.Sprintf("%d: %s", r.MapperNo, r.Name)
+	}
+	return fmt.Sprintf("%d.%d: %s", r.MapperNo, r.Submapper, r.Name)
+}
+
+type registryKey struct {
+	mapperNo  uint16
+	submapper int
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[registryKey]Registration{}
+)
+
+// Register makes a mapper available by the mapper number and submapper.
+//
+// The submapper is only used to look up NES 2.0 ROMs. If Register is called twice with the same mapper number and submapper,
+// it panics.
+func Register(mapperNo uint16, submapper int, name string, new Constructor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if new == nil {
+		panic("mapper: Register constructor is nil")
+	}
+	key := registryKey{mapperNo, submapper}
+	if _, dup := registry[key]; dup {
+		panic(fmt.Sprintf("mapper: Register called twice for mapper %d.%d", mapperNo, submapper))
+	}
+	registry[key] = Registration{MapperNo: mapperNo, Submapper: submapper, Name: name, new: new}
+}
+
+// Registered returns all registered mappers sorted by mapper number and submapper.
+func Registered() []Registration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	list := make([]Registration, 0, len(registry))
+	for _, r := range registry {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].MapperNo != list[j].MapperNo {
+			return list[i].MapperNo < list[j].MapperNo
+		}
+		return list[i].Submapper < list[j].Submapper
+	})
+	return list
+}
+
+func lookup(h Header) (Registration, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	if h.NES2 {
+		if r, ok := registry[registryKey{h.MapperNo, int(h.Submapper)}]; ok {
+			return r, true
+		}
+	}
+	r, ok := registry[registryKey{h.MapperNo, AnySubmapper}]
+	return r, ok
+}
+
+// Mapper creates a mapper object from this rom's data
+func (r *ROM) Mapper() (Mapper, error) {
+	reg, ok := lookup(r.header)
+	if !ok {
+		return nil, errors.Errorf("unsupported mapper no: %d", r.header.MapperNo)
+	}
+	return reg.new(r)
+}
+
+// infallible adapts constructors which never fail
+func infallible(new func(*ROM) Mapper) Constructor {
+	return func(rom *ROM) (Mapper, error) {
+		return new(rom), nil
+	}
+}
@@ -0,0 +1,83 @@
+package mapper
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testBoard struct {
+	mapper0
+	submapper uint8
+}
+
+// registerForTest registers a mapper, which is unregistered at the end of the test
+func registerForTest(t *testing.T, mapperNo uint16, submapper int, name string, new Constructor) {
+	t.Helper()
+	Register(mapperNo, submapper, name, new)
+	t.Cleanup(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		delete(registry, registryKey{mapperNo, submapper})
+	})
+}
+
+func TestRegister(t *testing.T) {
+	newBoard := func(rom *ROM) (Mapper, error) {
+		return &testBoard{submapper: rom.Header().Submapper}, nil
+	}
+	registerForTest(t, 4000, AnySubmapper, "test board", newBoard)
+	registerForTest(t, 4000, 3, "test board rev.3", func(rom *ROM) (Mapper, error) {
+		return &testBoard{submapper: 0xFF}, nil
+	})
+
+	m, err := (&ROM{header: Header{MapperNo: 4000, Submapper: 2, NES2: true}}).Mapper()
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, m.(*testBoard).submapper)
+
+	m, err = (&ROM{header: Header{MapperNo: 4000, Submapper: 3, NES2: true}}).Mapper()
+	require.NoError(t, err)
+	assert.EqualValues(t, 0xFF, m.(*testBoard).submapper)
+
+	// iNES has no submapper
+	m, err = (&ROM{header: Header{MapperNo: 4000, Submapper: 3}}).Mapper()
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, m.(*testBoard).submapper)
+
+	_, err = (&ROM{header: Header{MapperNo: 4001}}).Mapper()
+	assert.Error(t, err)
+
+	assert.Panics(t, func() { Register(4000, 3, "dup", newBoard) })
+	assert.Panics(t, func() { Register(4001, AnySubmapper, "nil", nil) })
+}
+
+func TestRegister_cleanup(t *testing.T) {
+	t.Run("register", func(t *testing.T) {
+		registerForTest(t, 4000, AnySubmapper, "test board", infallible(newMapper0))
+		_, ok := lookup(Header{MapperNo: 4000})
+		assert.True(t, ok)
+	})
+	_, ok := lookup(Header{MapperNo: 4000})
+	assert.False(t, ok, "unregistered after the test")
+}
+
+func TestRegistered(t *testing.T) {
+	list := Registered()
+	assert.True(t, sort.SliceIsSorted(list, func(i, j int) bool {
+		if list[i].MapperNo != list[j].MapperNo {
+			return list[i].MapperNo < list[j].MapperNo
+		}
+		return list[i].Submapper < list[j].Submapper
+	}))
+
+	names := map[string]bool{}
+	for _, r := range list {
+		names[r.String()] = true
+	}
+	assert.True(t, names["0: NROM"])
+	assert.True(t, names["16.4: Bandai FCG-1/2"])
+	assert.True(t, names["21.2: Konami VRC4c"])
+	assert.True(t, names["85: Konami VRC7"])
+}
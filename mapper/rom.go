@@ -9,8 +9,12 @@ import (
 
 // ROM wraps byte array of iNES format binary.
 type ROM struct {
-	header header
+	header Header
 	raw    []byte
+
+	// Famicom Disk System
+	disk *fdsImage
+	bios []byte
 }
 
 // Kind of Nametable Mirroring
@@ -21,6 +25,9 @@ const (
 	_ Mirroring = iota
 	Mirroring_Horizontal
 	Mirroring_Vertical
+	Mirroring_SingleScreenA // one-screen, lower bank
+	Mirroring_SingleScreenB // one-screen, upper bank
+	Mirroring_FourScreen    // four-screen with VRAM on the cartridge
 )
 
 func (m Mirroring) String() string {
@@ -29,15 +36,27 @@ func (m Mirroring) String() string {
 		return "H"
 	case Mirroring_Vertical:
 		return "V"
+	case Mirroring_SingleScreenA:
+		return "1A"
+	case Mirroring_SingleScreenB:
+		return "1B"
+	case Mirroring_FourScreen:
+		return "4"
 	}
 	return "Unknown"
 }
 
-type header struct {
-	mapperNO   uint8
-	prgROMSize uint
-	chrROMSize uint
-	mirroring  Mirroring
+// Header is parsed header information of a ROM.
+type Header struct {
+	MapperNo   uint16
+	Submapper  uint8
+	PRGROMSize uint // in 16KB units
+	CHRROMSize uint // in 8KB units
+	Mirroring  Mirroring
+	Battery    bool // battery-backed PRG RAM or other persistent memory
+
+	// NES 2.0 format https://www.nesdev.org/wiki/NES_2.0
+	NES2 bool
 }
 
 var (
@@ -45,12 +64,22 @@ var (
 	padding     = []byte{0, 0, 0, 0, 0}
 )
 
-// ParseROM load NES binary program in iNES file format
+// ParseROM load NES binary program in iNES or UNIF file format, or a disk image in .fds format
 func ParseROM(r io.Reader) (*ROM, error) {
 	buf := make([]byte, 4)
 	if _, err := io.ReadFull(r, buf); err != nil {
 		return nil, errors.Wrap(err, "failed to parse for magic number")
 	}
+	if isFDSMagic(buf) {
+		disk, err := parseFDS(buf, r)
+		if err != nil {
+			return nil, err
+		}
+		return &ROM{header: Header{MapperNo: 20, Mirroring: Mirroring_Horizontal}, disk: disk}, nil
+	}
+	if bytes.Equal(buf, unifMagicNumber) {
+		return parseUNIF(r)
+	}
 	if !bytes.Equal(buf, magicNumber) {
 		return nil, errors.New("invalid magic number")
 	}
@@ -67,43 +96,110 @@ func ParseROM(r io.Reader) (*ROM, error) {
 	flag7 := buf[3]
 
 	var mirroring Mirroring
-	if flag6&1 == 0 {
+	switch {
+	case flag6&0b1000 != 0:
+		mirroring = Mirroring_FourScreen
+	case flag6&1 == 0:
 		mirroring = Mirroring_Horizontal
-	} else {
+	default:
 		mirroring = Mirroring_Vertical
 	}
 
-	mapperNo := (flag6 & 0b1111000) | (flag7&0b11110000)<<4
+	mapperNo := uint16(flag6>>4 | flag7&0b11110000)
+
+	nes2 := flag7&0b1100 == 0b1000
 
-	// skip flag 8, 9, 10
+	// flag 8, 9, 10
 	buf = make([]byte, 3)
 	if n, err := io.ReadAtLeast(r, buf, 3); err != nil {
 		return nil, errors.Wrap(err, "failed to parse for flag 8..10")
 	} else if n != 3 {
-		return nil, errors.Errorf("invalid skip flags reading: n=%d", n)
+		return nil, errors.Errorf("invalid flag 8..10 reading: n=%d", n)
+	}
+
+	var submapper uint8
+	prgSize, chrSize := uint(prgROMSize), uint(chrROMSize)
+	if nes2 {
+		mapperNo |= uint16(buf[0]&0b1111) << 8
+		submapper = buf[0] >> 4
+		// https://www.nesdev.org/wiki/NES_2.0#PRG-ROM_Area
+		if buf[1]&0x0F == 0x0F || buf[1]&0xF0 == 0xF0 {
+			return nil, errors.New("exponent-multiplier notation of ROM size is not supported")
+		}
+		prgSize |= uint(buf[1]&0b1111) << 8
+		chrSize |= uint(buf[1]>>4) << 8
 	}
 
-	// validate unused padding
 	buf = make([]byte, 5)
 	if n, err := io.ReadAtLeast(r, buf, 5); err != nil {
 		return nil, errors.Wrap(err, "failed to parse for padding")
 	} else if n != 5 {
 		return nil, errors.Errorf("invalid padding reading: n=%d", n)
-	} else if !bytes.Equal(buf, padding) {
-		return nil, errors.New("invalid padding")
+	} else if !nes2 && !bytes.Equal(buf[1:], padding[1:]) {
+		// garbage like "DiskDude!" written by old tools from byte 7, where the upper bits of mapper number are broken.
+		// https://www.nesdev.org/wiki/INES#Flags_7
+		mapperNo &= 0x0F
+	}
+
+	// skip trainer
+	if flag6&0b100 != 0 {
+		if _, err := io.CopyN(io.Discard, r, 512); err != nil {
+			return nil, errors.Wrap(err, "failed to skip trainer")
+		}
 	}
 
 	raw, err := io.ReadAll(r)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to read raw data after header")
 	}
+	if size := prgSize*0x4000 + chrSize*0x2000; uint(len(raw)) < size {
+		return nil, errors.Errorf("too short PRG/CHR ROM: %d bytes, expected %d bytes", len(raw), size)
+	}
 	return &ROM{
-		header: header{
-			mapperNO:   mapperNo,
-			prgROMSize: uint(prgROMSize),
-			chrROMSize: uint(chrROMSize),
-			mirroring:  mirroring,
+		header: Header{
+			MapperNo:   mapperNo,
+			Submapper:  submapper,
+			PRGROMSize: prgSize,
+			CHRROMSize: chrSize,
+			Mirroring:  mirroring,
+			Battery:    flag6&0b10 != 0,
+			NES2:       nes2,
 		},
 		raw: raw,
 	}, nil
 }
+
+// Header returns the parsed header information.
+func (r *ROM) Header() Header {
+	return r.header
+}
+
+// IsFDS reports whether this is a disk image of Famicom Disk System.
+func (r *ROM) IsFDS() bool {
+	return r.disk != nil
+}
+
+// SetFDSBIOS sets the 8KB BIOS ROM of Famicom Disk System, which is required to run disk images.
+func (r *ROM) SetFDSBIOS(bios []byte) error {
+	if len(bios) != fdsBIOSSize {
+		return errors.Errorf("invalid FDS BIOS size: %d bytes", len(bios))
+	}
+	r.bios = bios
+	return nil
+}
+
+// Banks splits raw data into PRG and CHR.
+//
+// If the cartridge has no CHR ROM, it returns 8KB CHR RAM instead.
+func (r *ROM) Banks() (prg, chr []byte) {
+	prgSize := r.header.PRGROMSize * 0x4000
+	prg = r.raw[:prgSize]
+
+	if r.header.CHRROMSize == 0 {
+		chr = make([]byte, 0x2000)
+	} else {
+		chrSize := r.header.CHRROMSize * 0x2000
+		chr = r.raw[prgSize : prgSize+chrSize]
+	}
+	return
+}
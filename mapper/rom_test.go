@@ -1,6 +1,7 @@
 package mapper
 
 import (
+	"bytes"
 	"os"
 	"testing"
 
@@ -16,8 +17,64 @@ func TestParseROM(t *testing.T) {
 	rom, err := ParseROM(f)
 	require.NoError(t, err)
 
-	assert.EqualValues(t, 0, rom.header.mapperNO)
-	assert.EqualValues(t, 1, rom.header.prgROMSize)
-	assert.EqualValues(t, 1, rom.header.chrROMSize)
-	assert.EqualValues(t, Mirroring_Horizontal, rom.header.mirroring)
+	assert.EqualValues(t, 0, rom.header.MapperNo)
+	assert.EqualValues(t, 1, rom.header.PRGROMSize)
+	assert.EqualValues(t, 1, rom.header.CHRROMSize)
+	assert.EqualValues(t, Mirroring_Horizontal, rom.header.Mirroring)
+}
+
+func TestParseROM_mapperNo(t *testing.T) {
+	b := []byte{0x4E, 0x45, 0x53, 0x1A, 1, 1, 0x31, 0x10, 0, 0, 0, 0, 0, 0, 0, 0}
+	b = append(b, make([]byte, 0x4000+0x2000)...)
+	rom, err := ParseROM(bytes.NewReader(b))
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 0x13, rom.header.MapperNo)
+	assert.EqualValues(t, Mirroring_Vertical, rom.header.Mirroring)
+}
+
+func TestParseROM_nes2(t *testing.T) {
+	b := []byte{0x4E, 0x45, 0x53, 0x1A, 2, 1, 0x70, 0x18, 0x21, 0, 0, 0x07, 0, 0, 0, 0}
+	b = append(b, make([]byte, 2*0x4000+0x2000)...)
+	rom, err := ParseROM(bytes.NewReader(b))
+	require.NoError(t, err)
+
+	assert.True(t, rom.header.NES2)
+	assert.EqualValues(t, 0x117, rom.header.MapperNo)
+	assert.EqualValues(t, 2, rom.header.Submapper)
+	assert.EqualValues(t, 2, rom.header.PRGROMSize)
+}
+
+func TestParseROM_fourScreen(t *testing.T) {
+	b := []byte{0x4E, 0x45, 0x53, 0x1A, 1, 1, 0x09, 0x00, 0, 0, 0, 0, 0, 0, 0, 0}
+	b = append(b, make([]byte, 0x4000+0x2000)...)
+	rom, err := ParseROM(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.EqualValues(t, Mirroring_FourScreen, rom.header.Mirroring)
+}
+
+func TestParseROM_exponentSize(t *testing.T) {
+	// PRG size MSB nibble 0xF is exponent-multiplier notation
+	b := []byte{0x4E, 0x45, 0x53, 0x1A, 0x0E, 1, 0x00, 0x08, 0, 0x0F, 0, 0, 0, 0, 0, 0}
+	b = append(b, make([]byte, 0x10000)...)
+	_, err := ParseROM(bytes.NewReader(b))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "exponent")
+	}
+}
+
+func TestParseROM_truncated(t *testing.T) {
+	b := []byte{0x4E, 0x45, 0x53, 0x1A, 2, 1, 0x00, 0x00, 0, 0, 0, 0, 0, 0, 0, 0}
+	b = append(b, make([]byte, 100)...)
+	_, err := ParseROM(bytes.NewReader(b))
+	assert.Error(t, err)
+
+	// extra data after CHR ROM is allowed
+	b = []byte{0x4E, 0x45, 0x53, 0x1A, 1, 0, 0x00, 0x00, 0, 0, 0, 0, 0, 0, 0, 0}
+	b = append(b, make([]byte, 0x4000+100)...)
+	rom, err := ParseROM(bytes.NewReader(b))
+	require.NoError(t, err)
+	prg, chr := rom.Banks()
+	assert.Len(t, prg, 0x4000)
+	assert.Len(t, chr, 0x2000)
 }
@@ -0,0 +1,187 @@
+package mapper
+
+import "github.com/thara/gorones/state"
+
+// Each mapper saves registers and RAM by state.Serializable, but not ROM.
+
+func (m *mapper0) Serialize(s *state.Serializer) {
+	s.Section("NROM")
+	// CHR may be RAM
+	s.Data(m.chr)
+	s.Data(m.prgRAM[:])
+}
+
+func (m *bandaiFCG) Serialize(s *state.Serializer) {
+	s.Section("FCG")
+	if m.chrRAM {
+		s.Data(m.chr)
+	}
+	s.Uint8(&m.prgBank)
+	s.Data(m.chrBanks[:])
+	s.Int((*int)(&m.mirroring))
+	s.Bool(&m.irqEnabled)
+	s.Uint16(&m.irqCounter)
+	s.Uint16(&m.irqLatch)
+	s.Bool(&m.irq)
+	if m.eeprom != nil {
+		m.eeprom.serialize(s)
+	}
+	s.Bool(&m.eepromRead)
+}
+
+func (e *eeprom) serialize(s *state.Serializer) {
+	s.Data(e.data)
+	s.Bool(&e.scl)
+	s.Bool(&e.sda)
+	s.Bool(&e.out)
+	s.Int((*int)(&e.mode))
+	s.Int((*int)(&e.next))
+	s.Uint8(&e.bit)
+	s.Uint8(&e.shift)
+	s.Uint8(&e.addr)
+	s.Bool(&e.ack)
+}
+
+func (m *fds) Serialize(s *state.Serializer) {
+	s.Section("FDS")
+	s.Data(m.ram[:])
+	s.Data(m.chr[:])
+	for _, d := range m.disks {
+		s.Data(d)
+	}
+
+	s.Int(&m.side)
+	s.Int(&m.nextSide)
+	s.Int(&m.insertDelay)
+	s.Int((*int)(&m.mirroring))
+
+	s.Uint16(&m.timerReload)
+	s.Uint16(&m.timerCounter)
+	s.Bool(&m.timerRepeat)
+	s.Bool(&m.timerEnabled)
+	s.Bool(&m.timerIRQ)
+	s.Bool(&m.diskIRQ)
+	s.Bool(&m.diskIRQEnable)
+
+	s.Bool(&m.diskRegEnabled)
+	s.Bool(&m.soundRegEnabled)
+
+	s.Bool(&m.motorOn)
+	s.Bool(&m.resetTransfer)
+	s.Bool(&m.readMode)
+	s.Bool(&m.crcControl)
+	s.Bool(&m.diskReady)
+
+	s.Uint8(&m.writeData)
+	s.Uint8(&m.readData)
+
+	s.Bool(&m.transferComplete)
+	s.Bool(&m.endOfHead)
+	s.Bool(&m.scanning)
+	s.Bool(&m.gapEnded)
+	s.Int(&m.position)
+	s.Int(&m.delay)
+
+	m.audio.serialize(s)
+}
+
+func (a *fdsAudio) serialize(s *state.Serializer) {
+	s.Data(a.wave[:])
+	s.Bool(&a.waveWriteEnable)
+	s.Bool(&a.waveHalt)
+	s.Uint16(&a.waveFreq)
+	s.Uint32(&a.waveAcc)
+
+	s.Bool(&a.envelopesHalt)
+	s.Uint8(&a.masterSpeed)
+	s.Uint8(&a.masterVolume)
+	a.vol.serialize(s)
+	a.mod.serialize(s)
+
+	s.Data(a.modTable[:])
+	s.Uint8(&a.modPos)
+	s.Bool(&a.modHalt)
+	s.Uint16(&a.modFreq)
+	s.Uint16(&a.modAcc)
+	s.Int8(&a.modCounter)
+
+	s.Float32(&a.output)
+}
+
+func (e *fdsEnvelope) serialize(s *state.Serializer) {
+	s.Bool(&e.disabled)
+	s.Bool(&e.increase)
+	s.Uint8(&e.speed)
+	s.Uint8(&e.gain)
+	s.Uint32(&e.counter)
+}
+
+func (m *namco163) Serialize(s *state.Serializer) {
+	s.Section("N163")
+	s.Data(m.prgRAM[:])
+	s.Data(m.ciram[:])
+	s.Data(m.prgBanks[:])
+	s.Data(m.chrBanks[:])
+	s.Data(m.ntBanks[:])
+	s.Bool(&m.chrRAMDisabledLow)
+	s.Bool(&m.chrRAMDisabledHigh)
+	s.Uint16(&m.irqCounter)
+	s.Bool(&m.irqEnabled)
+	s.Bool(&m.irq)
+	m.audio.serialize(s)
+	s.Int((*int)(&m.mirroring))
+}
+
+func (a *namco163Audio) serialize(s *state.Serializer) {
+	s.Data(a.ram[:])
+	s.Uint8(&a.addr)
+	s.Bool(&a.autoIncrement)
+	s.Bool(&a.disabled)
+	s.Uint8(&a.cycles)
+	s.Uint8(&a.channel)
+	for i := range a.outputs {
+		s.Int(&a.outputs[i])
+	}
+}
+
+func (m *vrc24) Serialize(s *state.Serializer) {
+	s.Section("VRC2/4")
+	if m.chrRAM {
+		s.Data(m.chr)
+	}
+	s.Data(m.prgRAM[:])
+	s.Uint8(&m.microwire)
+	s.Data(m.prgBanks[:])
+	s.Bool(&m.prgSwap)
+	for i := range m.chrBanks {
+		s.Uint16(&m.chrBanks[i])
+	}
+	s.Int((*int)(&m.mirroring))
+	m.irq.serialize(s)
+}
+
+func (m *vrc7) Serialize(s *state.Serializer) {
+	s.Section("VRC7")
+	if m.chrRAM {
+		s.Data(m.chr)
+	}
+	s.Data(m.prgRAM[:])
+	s.Bool(&m.prgRAMEnabled)
+	s.Data(m.prgBanks[:])
+	s.Data(m.chrBanks[:])
+	s.Int((*int)(&m.mirroring))
+	m.irq.serialize(s)
+	m.fm.Serialize(s)
+	s.Uint8(&m.audioCycles)
+	s.Bool(&m.audioReset)
+}
+
+func (irq *vrcIRQ) serialize(s *state.Serializer) {
+	s.Uint8(&irq.latch)
+	s.Uint8(&irq.counter)
+	s.Int(&irq.prescaler)
+	s.Bool(&irq.enabled)
+	s.Bool(&irq.enabledAfterAck)
+	s.Bool(&irq.cycleMode)
+	s.Bool(&irq.irq)
+}
@@ -0,0 +1,174 @@
+package mapper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// https://www.nesdev.org/wiki/UNIF
+
+var unifMagicNumber = []byte("UNIF")
+
+const unifHeaderSize = 32
+
+type unifBoard struct {
+	mapperNo  uint16
+	submapper int // AnySubmapper if not specific
+}
+
+var (
+	unifBoardsMu sync.RWMutex
+	// board names without prefixes like "NES-"
+	unifBoards = map[string]unifBoard{
+		"NROM":     {0, AnySubmapper},
+		"NROM-128": {0, AnySubmapper},
+		"NROM-256": {0, AnySubmapper},
+		"RROM":     {0, AnySubmapper},
+		"RROM-128": {0, AnySubmapper},
+	}
+	unifBoardPrefixes = []string{"NES-", "UNL-", "HVC-", "BTL-", "BMC-"}
+)
+
+// RegisterUNIFBoard maps a UNIF board name to the mapper number and submapper for Register.
+func RegisterUNIFBoard(name string, mapperNo uint16, submapper int) {
+	unifBoardsMu.Lock()
+	defer unifBoardsMu.Unlock()
+	unifBoards[trimUNIFPrefix(name)] = unifBoard{mapperNo, submapper}
+}
+
+func trimUNIFPrefix(name string) string {
+	for _, p := range unifBoardPrefixes {
+		if strings.HasPrefix(name, p) {
+			return name[len(p):]
+		}
+	}
+	return name
+}
+
+func lookupUNIFBoard(name string) (unifBoard, bool) {
+	unifBoardsMu.RLock()
+	defer unifBoardsMu.RUnlock()
+	b, ok := unifBoards[trimUNIFPrefix(name)]
+	return b, ok
+}
+
+// parseUNIF parses a ROM in UNIF format after magic number was read
+func parseUNIF(r io.Reader) (*ROM, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read UNIF")
+	}
+	if len(b) < unifHeaderSize-len(unifMagicNumber) {
+		return nil, errors.New("too short UNIF header")
+	}
+	b = b[unifHeaderSize-len(unifMagicNumber):]
+
+	var (
+		board    string
+		prg, chr [16][]byte
+		prgCRC   [16]*uint32
+		chrCRC   [16]*uint32
+		h        = Header{Mirroring: Mirroring_Horizontal}
+	)
+	for len(b) > 0 {
+		if len(b) < 8 {
+			return nil, errors.New("too short UNIF chunk header")
+		}
+		id := string(b[:4])
+		size := binary.LittleEndian.Uint32(b[4:8])
+		if uint32(len(b)-8) < size {
+			return nil, errors.Errorf("too short UNIF chunk %s", id)
+		}
+		data := b[8 : 8+size]
+		b = b[8+size:]
+
+		// PRG0..PRGF, CHR0..CHRF, PCK0..PCKF, CCK0..CCKF
+		n := strings.IndexByte("0123456789ABCDEF", id[3])
+		switch {
+		case id == "MAPR":
+			board = string(bytes.TrimRight(data, "\x00"))
+		case id == "MIRR" && 0 < len(data):
+			switch data[0] {
+			case 0:
+				h.Mirroring = Mirroring_Horizontal
+			case 1:
+				h.Mirroring = Mirroring_Vertical
+			case 2:
+				h.Mirroring = Mirroring_SingleScreenA
+			case 3:
+				h.Mirroring = Mirroring_SingleScreenB
+			case 4:
+				h.Mirroring = Mirroring_FourScreen
+			}
+		case id == "BATR":
+			h.Battery = true
+		case id[:3] == "PRG" && 0 <= n:
+			prg[n] = data
+		case id[:3] == "CHR" && 0 <= n:
+			chr[n] = data
+		case id[:3] == "PCK" && 0 <= n && 4 <= len(data):
+			v := binary.LittleEndian.Uint32(data)
+			prgCRC[n] = &v
+		case id[:3] == "CCK" && 0 <= n && 4 <= len(data):
+			v := binary.LittleEndian.Uint32(data)
+			chrCRC[n] = &v
+		}
+	}
+
+	if board == "" {
+		return nil, errors.New("no MAPR chunk in UNIF")
+	}
+	bd, ok := lookupUNIFBoard(board)
+	if !ok {
+		return nil, errors.Errorf("unsupported UNIF board: %s", board)
+	}
+	h.MapperNo = bd.mapperNo
+	if bd.submapper != AnySubmapper {
+		h.Submapper = uint8(bd.submapper)
+		h.NES2 = true
+	}
+
+	var prgROM, chrROM []byte
+	for i := range prg {
+		if prgCRC[i] != nil && crc32.ChecksumIEEE(prg[i]) != *prgCRC[i] {
+			return nil, errors.Errorf("CRC mismatch of PRG%X", i)
+		}
+		if chrCRC[i] != nil && crc32.ChecksumIEEE(chr[i]) != *chrCRC[i] {
+			return nil, errors.Errorf("CRC mismatch of CHR%X", i)
+		}
+		prgROM = append(prgROM, prg[i]...)
+		chrROM = append(chrROM, chr[i]...)
+	}
+	if len(prgROM) == 0 {
+		return nil, errors.New("no PRG chunk in UNIF")
+	}
+
+	prgROM = fillBanks(prgROM, 0x4000)
+	chrROM = fillBanks(chrROM, 0x2000)
+	h.PRGROMSize = uint(len(prgROM) / 0x4000)
+	h.CHRROMSize = uint(len(chrROM) / 0x2000)
+	return &ROM{header: h, raw: append(prgROM, chrROM...)}, nil
+}
+
+// fillBanks mirrors b to fill up the last bank, since PRG/CHR in UNIF can be smaller than iNES units.
+func fillBanks(b []byte, bank int) []byte {
+	if len(b) == 0 || len(b)%bank == 0 {
+		return b
+	}
+	size := (len(b)/bank + 1) * bank
+	out := make([]byte, 0, size)
+	for len(out) < size {
+		n := len(b)
+		if size-len(out) < n {
+			n = size - len(out)
+		}
+		out = append(out, b[:n]...)
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package mapper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func unifChunk(id string, data []byte) []byte {
+	b := append([]byte(id), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(data)))
+	return append(b, data...)
+}
+
+func unifFile(chunks ...[]byte) []byte {
+	b := append([]byte("UNIF"), 7, 0, 0, 0)
+	b = append(b, make([]byte, 24)...)
+	for _, c := range chunks {
+		b = append(b, c...)
+	}
+	return b
+}
+
+func TestParseROM_unif(t *testing.T) {
+	prg := bytes.Repeat([]byte{0xEA}, 0x8000)
+	chr := bytes.Repeat([]byte{0x55}, 0x2000)
+	var crc [4]byte
+	binary.LittleEndian.PutUint32(crc[:], crc32.ChecksumIEEE(prg))
+
+	b := unifFile(
+		unifChunk("MAPR", []byte("NES-NROM-256\x00")),
+		unifChunk("NAME", []byte("test\x00")),
+		unifChunk("PRG0", prg),
+		unifChunk("PCK0", crc[:]),
+		unifChunk("CHR0", chr),
+		unifChunk("MIRR", []byte{1}),
+		unifChunk("BATR", []byte{1}),
+	)
+	rom, err := ParseROM(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, Header{
+		MapperNo:   0,
+		PRGROMSize: 2,
+		CHRROMSize: 1,
+		Mirroring:  Mirroring_Vertical,
+		Battery:    true,
+	}, rom.Header())
+
+	m, err := rom.Mapper()
+	require.NoError(t, err)
+	assert.EqualValues(t, 0xEA, m.Read(0xFFFC))
+	assert.EqualValues(t, 0x55, m.Read(0x0000))
+
+	// CRC mismatch
+	crc[0] ^= 0xFF
+	_, err = ParseROM(bytes.NewReader(unifFile(
+		unifChunk("MAPR", []byte("NROM\x00")),
+		unifChunk("PRG0", prg),
+		unifChunk("PCK0", crc[:]),
+	)))
+	assert.Error(t, err)
+
+	_, err = ParseROM(bytes.NewReader(unifFile(unifChunk("MAPR", []byte("UNL-UNKNOWN\x00")), unifChunk("PRG0", prg))))
+	assert.Error(t, err)
+	_, err = ParseROM(bytes.NewReader(unifFile(unifChunk("PRG0", prg))))
+	assert.Error(t, err)
+	_, err = ParseROM(bytes.NewReader(unifFile(unifChunk("MAPR", []byte("NROM")))[:40]))
+	assert.Error(t, err)
+}
+
+// registerUNIFBoardForTest registers a UNIF board name, which is unregistered at the end of the test
+func registerUNIFBoardForTest(t *testing.T, name string, mapperNo uint16, submapper int) {
+	t.Helper()
+	RegisterUNIFBoard(name, mapperNo, submapper)
+	t.Cleanup(func() {
+		unifBoardsMu.Lock()
+		defer unifBoardsMu.Unlock()
+		delete(unifBoards, trimUNIFPrefix(name))
+	})
+}
+
+func TestRegisterUNIFBoard(t *testing.T) {
+	registerUNIFBoardForTest(t, "UNL-TEST-BOARD", 16, 5)
+
+	// PRG0 and PRG1 are concatenated in order, and 4KB CHR is mirrored to fill an 8KB bank
+	b := unifFile(
+		unifChunk("MAPR", []byte("BMC-TEST-BOARD")),
+		unifChunk("PRG1", bytes.Repeat([]byte{1}, 0x4000)),
+		unifChunk("PRG0", bytes.Repeat([]byte{0}, 0x4000)),
+		unifChunk("CHR0", bytes.Repeat([]byte{2}, 0x1000)),
+	)
+	rom, err := ParseROM(bytes.NewReader(b))
+	require.NoError(t, err)
+	h := rom.Header()
+	assert.EqualValues(t, 16, h.MapperNo)
+	assert.EqualValues(t, 5, h.Submapper)
+	assert.True(t, h.NES2)
+	assert.EqualValues(t, 2, h.PRGROMSize)
+	assert.EqualValues(t, 1, h.CHRROMSize)
+
+	prg, chr := rom.Banks()
+	assert.EqualValues(t, 0, prg[0])
+	assert.EqualValues(t, 1, prg[0x4000])
+	assert.Len(t, chr, 0x2000)
+}
+
+func TestUNIFBoards(t *testing.T) {
+	tests := []struct {
+		name      string
+		mapperNo  uint16
+		submapper int
+	}{
+		{"BANDAI-FCG-1", 16, 4},
+		{"BANDAI-LZ93D50+24C02", 16, 5},
+		{"BANDAI-LZ93D50+24C01", 159, AnySubmapper},
+		{"NAMCOT-163", 19, AnySubmapper},
+		{"KONAMI-VRC-2A", 22, 0},
+		{"KONAMI-VRC-2B", 23, 3},
+		{"KONAMI-VRC-4C", 21, 2},
+		{"KONAMI-VRC-4F", 23, 1},
+		{"KONAMI-VRC-7", 85, AnySubmapper},
+	}
+	for _, tt := range tests {
+		b, ok := lookupUNIFBoard(tt.name)
+		if assert.True(t, ok, tt.name) {
+			assert.Equal(t, unifBoard{tt.mapperNo, tt.submapper}, b, tt.name)
+		}
+		_, ok = lookup(Header{MapperNo: b.mapperNo, Submapper: uint8(b.submapper), NES2: b.submapper != AnySubmapper})
+		assert.True(t, ok, tt.name)
+	}
+}
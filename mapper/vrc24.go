@@ -0,0 +1,262 @@
+package mapper
+
+import (
+	"fmt"
+	"strings"
+)
+
+// https://www.nesdev.org/wiki/VRC2_and_VRC4
+
+// vrcWiring is how a board connects CPU address lines to the register select pins (A0, A1) of VRC2/VRC4.
+//
+// Each pin may be wired to several address lines for heuristic wiring.
+type vrcWiring struct {
+	name   string
+	a0, a1 uint16 // masks of CPU address lines
+}
+
+func (w vrcWiring) register(addr uint16) uint16 {
+	r := addr & 0xF000
+	if addr&w.a0 != 0 {
+		r |= 0b01
+	}
+	if addr&w.a1 != 0 {
+		r |= 0b10
+	}
+	return r
+}
+
+type vrc24Variant struct {
+	wiring vrcWiring
+	vrc4   bool
+	// VRC2a ignores the lowest bit of CHR bank numbers
+	chrShift uint
+}
+
+// vrc24Variants are boards selected by NES 2.0 submapper
+// https://www.nesdev.org/wiki/NES_2.0_submappers#021.2C_023.2C_025:_Konami_VRC2.2FVRC4
+var vrc24Variants = map[uint16]map[uint8]vrc24Variant{
+	21: {
+		1: {wiring: vrcWiring{"VRC4a", 0x02, 0x04}, vrc4: true},
+		2: {wiring: vrcWiring{"VRC4c", 0x40, 0x80}, vrc4: true},
+	},
+	22: {
+		0: {wiring: vrcWiring{"VRC2a", 0x02, 0x01}, chrShift: 1},
+	},
+	23: {
+		1: {wiring: vrcWiring{"VRC4f", 0x01, 0x02}, vrc4: true},
+		2: {wiring: vrcWiring{"VRC4e", 0x04, 0x08}, vrc4: true},
+		3: {wiring: vrcWiring{"VRC2b", 0x01, 0x02}},
+	},
+	25: {
+		1: {wiring: vrcWiring{"VRC4b", 0x02, 0x01}, vrc4: true},
+		2: {wiring: vrcWiring{"VRC4d", 0x08, 0x04}, vrc4: true},
+		3: {wiring: vrcWiring{"VRC2c", 0x02, 0x01}},
+	},
+}
+
+// vrc24Fallbacks are used for iNES files which have no submapper.
+//
+// Both wirings of a mapper number are combined, because games access registers via only one of them.
+// VRC4 is chosen since it is a superset of VRC2, except for mapper 22 which is only VRC2a.
+var vrc24Fallbacks = map[uint16]vrc24Variant{
+	21: {wiring: vrcWiring{"VRC4a/c", 0x02 | 0x40, 0x04 | 0x80}, vrc4: true},
+	22: vrc24Variants[22][0],
+	23: {wiring: vrcWiring{"VRC4e/f", 0x01 | 0x04, 0x02 | 0x08}, vrc4: true},
+	25: {wiring: vrcWiring{"VRC4b/d", 0x02 | 0x08, 0x01 | 0x04}, vrc4: true},
+}
+
+func selectVRC24Variant(h Header) vrc24Variant {
+	if h.NES2 {
+		if v, ok := vrc24Variants[h.MapperNo][h.Submapper]; ok {
+			return v
+		}
+	}
+	return vrc24Fallbacks[h.MapperNo]
+}
+
+func init() {
+	for no, variants := range vrc24Variants {
+		for sub, v := range variants {
+			Register(no, int(sub), "Konami "+v.wiring.name, infallible(newVRC24))
+			// e.g. KONAMI-VRC-4A
+			RegisterUNIFBoard("KONAMI-VRC-"+strings.ToUpper(strings.TrimPrefix(v.wiring.name, "VRC")), no, int(sub))
+		}
+	}
+	for no, v := range vrc24Fallbacks {
+		Register(no, AnySubmapper, "Konami "+v.wiring.name, infallible(newVRC24))
+	}
+}
+
+type vrc24 struct {
+	vrc24Variant
+
+	prg    []byte
+	chr    []byte
+	chrRAM bool
+
+	prgRAM [0x2000]uint8
+	// VRC2 has a 1-bit latch at $6000-$6FFF instead of PRG RAM, which some games use for copy protection.
+	microwire uint8
+
+	prgBanks  [2]uint8
+	prgSwap   bool // VRC4 only: swap $8000 and $C000
+	chrBanks  [8]uint16
+	mirroring Mirroring
+
+	irq vrcIRQ
+}
+
+func newVRC24(rom *ROM) Mapper {
+	prg, chr := rom.Banks()
+	return &vrc24{
+		vrc24Variant: selectVRC24Variant(rom.header),
+		prg:          prg,
+		chr:          chr,
+		chrRAM:       rom.header.CHRROMSize == 0,
+		mirroring:    rom.header.Mirroring,
+	}
+}
+
+func (m *vrc24) Read(addr uint16) uint8 {
+	switch {
+	case 0x0000 <= addr && addr <= 0x1FFF:
+		return m.chr[m.chrAddr(addr)]
+	case 0x6000 <= addr && addr <= 0x7FFF:
+		if m.vrc4 {
+			return m.prgRAM[addr-0x6000]
+		}
+		if addr <= 0x6FFF {
+			// the other bits are open bus
+			return uint8(addr>>8)&0xFE | m.microwire
+		}
+	case 0x8000 <= addr && addr <= 0xFFFF:
+		return m.prg[m.prgAddr(addr)]
+	}
+	return 0
+}
+
+func (m *vrc24) Write(addr uint16, value uint8) {
+	switch {
+	case 0x0000 <= addr && addr <= 0x1FFF:
+		if m.chrRAM {
+			m.chr[m.chrAddr(addr)] = value
+		}
+	case 0x6000 <= addr && addr <= 0x7FFF:
+		if m.vrc4 {
+			m.prgRAM[addr-0x6000] = value
+		} else if addr <= 0x6FFF {
+			m.microwire = value & 1
+		}
+	case 0x8000 <= addr && addr <= 0xFFFF:
+		m.writeRegister(m.wiring.register(addr), value)
+	}
+}
+
+func (m *vrc24) writeRegister(reg uint16, value uint8) {
+	switch {
+	case 0x8000 <= reg && reg <= 0x8003:
+		m.prgBanks[0] = value & 0x1F
+	case 0x9000 <= reg && reg <= 0x9003:
+		if !m.vrc4 {
+			if value&1 == 0 {
+				m.mirroring = Mirroring_Vertical
+			} else {
+				m.mirroring = Mirroring_Horizontal
+			}
+			return
+		}
+		switch reg {
+		case 0x9000:
+			m.mirroring = [4]Mirroring{
+				Mirroring_Vertical, Mirroring_Horizontal, Mirroring_SingleScreenA, Mirroring_SingleScreenB,
+			}[value&0b11]
+		case 0x9002:
+			m.prgSwap = value&0b10 != 0
+		}
+	case 0xA000 <= reg && reg <= 0xA003:
+		m.prgBanks[1] = value & 0x1F
+	case 0xB000 <= reg && reg <= 0xE003:
+		// each 1KB bank has low 4 bits at even registers and high bits at odd registers
+		n := ((reg-0xB000)>>12)*2 + (reg>>1)&1
+		if reg&1 == 0 {
+			m.chrBanks[n] = m.chrBanks[n]&0x1F0 | uint16(value&0x0F)
+		} else {
+			high := uint16(value & 0x0F)
+			if m.vrc4 {
+				high = uint16(value & 0x1F)
+			}
+			m.chrBanks[n] = m.chrBanks[n]&0x0F | high<<4
+		}
+	case reg == 0xF000:
+		if m.vrc4 {
+			m.irq.latch = m.irq.latch&0xF0 | value&0x0F
+		}
+	case reg == 0xF001:
+		if m.vrc4 {
+			m.irq.latch = m.irq.latch&0x0F | value<<4
+		}
+	case reg == 0xF002:
+		if m.vrc4 {
+			m.irq.writeControl(value)
+		}
+	case reg == 0xF003:
+		if m.vrc4 {
+			m.irq.acknowledge()
+		}
+	}
+}
+
+func (m *vrc24) prgAddr(addr uint16) int {
+	last := len(m.prg)/0x2000 - 1
+
+	var bank int
+	switch addr & 0xE000 {
+	case 0x8000:
+		if m.prgSwap {
+			bank = last - 1
+		} else {
+			bank = int(m.prgBanks[0])
+		}
+	case 0xA000:
+		bank = int(m.prgBanks[1])
+	case 0xC000:
+		if m.prgSwap {
+			bank = int(m.prgBanks[0])
+		} else {
+			bank = last - 1
+		}
+	case 0xE000:
+		bank = last
+	}
+	return (bank*0x2000 + int(addr%0x2000)) % len(m.prg)
+}
+
+func (m *vrc24) chrAddr(addr uint16) int {
+	bank := int(m.chrBanks[addr/0x0400] >> m.chrShift)
+	return (bank*0x0400 + int(addr%0x0400)) % len(m.chr)
+}
+
+// Tick clocks the IRQ counter on each CPU cycle.
+func (m *vrc24) Tick() {
+	if m.vrc4 {
+		m.irq.clock()
+	}
+}
+
+func (m *vrc24) IRQ() bool { return m.irq.irq }
+
+func (m *vrc24) Mirroring() Mirroring {
+	return m.mirroring
+}
+
+func (m *vrc24) PRG() []byte { return append([]byte(nil), m.prg...) }
+func (m *vrc24) CHR() []byte { return append([]byte(nil), m.chr...) }
+
+func (m vrc24) String() string {
+	return fmt.Sprintf(`Konami %s:
+	PRG: 0x%x byte
+	CHR: 0x%x byte
+	mirroring: %s
+`, m.wiring.name, len(m.prg), len(m.chr), m.mirroring)
+}
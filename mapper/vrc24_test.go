@@ -0,0 +1,162 @@
+package mapper
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newVRC24ROM(mapperNo uint16, submapper uint8, nes2 bool) *ROM {
+	// 128KB PRG, 128KB CHR; each byte holds its bank number
+	prg := make([]byte, 0x20000)
+	for i := range prg {
+		prg[i] = uint8(i / 0x2000)
+	}
+	chr := make([]byte, 0x20000)
+	for i := range chr {
+		chr[i] = uint8(i / 0x0400)
+	}
+	return &ROM{
+		header: Header{MapperNo: mapperNo, Submapper: submapper, NES2: nes2, PRGROMSize: 8, CHRROMSize: 16},
+		raw:    append(prg, chr...),
+	}
+}
+
+func Test_selectVRC24Variant(t *testing.T) {
+	tests := []struct {
+		mapperNo  uint16
+		submapper uint8
+		nes2      bool
+		expected  string
+		vrc4      bool
+	}{
+		{21, 1, true, "VRC4a", true},
+		{21, 2, true, "VRC4c", true},
+		{21, 0, false, "VRC4a/c", true},
+		{22, 0, true, "VRC2a", false},
+		{22, 0, false, "VRC2a", false},
+		{23, 1, true, "VRC4f", true},
+		{23, 2, true, "VRC4e", true},
+		{23, 3, true, "VRC2b", false},
+		{23, 3, false, "VRC4e/f", true},
+		{25, 1, true, "VRC4b", true},
+		{25, 2, true, "VRC4d", true},
+		{25, 3, true, "VRC2c", false},
+		{25, 0, true, "VRC4b/d", true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d.%d", tt.mapperNo, tt.submapper), func(t *testing.T) {
+			v := selectVRC24Variant(Header{MapperNo: tt.mapperNo, Submapper: tt.submapper, NES2: tt.nes2})
+			assert.Equal(t, tt.expected, v.wiring.name)
+			assert.Equal(t, tt.vrc4, v.vrc4)
+		})
+	}
+}
+
+func Test_vrcWiring_register(t *testing.T) {
+	vrc4e := vrc24Variants[23][2].wiring
+	assert.EqualValues(t, 0xB000, vrc4e.register(0xB000))
+	assert.EqualValues(t, 0xB001, vrc4e.register(0xB004))
+	assert.EqualValues(t, 0xB002, vrc4e.register(0xB008))
+	assert.EqualValues(t, 0xB003, vrc4e.register(0xB00C))
+
+	fallback := vrc24Fallbacks[23].wiring
+	assert.EqualValues(t, 0xB003, fallback.register(0xB003))
+	assert.EqualValues(t, 0xB003, fallback.register(0xB00C))
+}
+
+func Test_vrc24_prg(t *testing.T) {
+	m := newVRC24(newVRC24ROM(21, 1, true)).(*vrc24)
+
+	m.Write(0x8000, 3)
+	m.Write(0xA000, 5)
+	assert.EqualValues(t, 3, m.Read(0x8000))
+	assert.EqualValues(t, 5, m.Read(0xA000))
+	assert.EqualValues(t, 14, m.Read(0xC000))
+	assert.EqualValues(t, 15, m.Read(0xE000))
+
+	// swap mode
+	m.Write(0x9004, 0b10)
+	assert.EqualValues(t, 14, m.Read(0x8000))
+	assert.EqualValues(t, 3, m.Read(0xC000))
+}
+
+func Test_vrc24_chr(t *testing.T) {
+	t.Run("VRC4", func(t *testing.T) {
+		m := newVRC24(newVRC24ROM(25, 1, true)).(*vrc24)
+
+		// VRC4b: A1 -> A0, A0 -> A1
+		m.Write(0xC001, 0x0A) // bank 3 low
+		m.Write(0xC003, 0x03) // bank 3 high
+		assert.EqualValues(t, 0x3A, m.chrBanks[3])
+		assert.EqualValues(t, 0x3A, m.Read(0x0C00))
+	})
+	t.Run("VRC2a", func(t *testing.T) {
+		m := newVRC24(newVRC24ROM(22, 0, false)).(*vrc24)
+
+		m.Write(0xB000, 0x05)
+		m.Write(0xB002, 0x01)
+		assert.EqualValues(t, 0x15>>1, m.Read(0x0000))
+	})
+}
+
+func Test_vrc24_mirroring(t *testing.T) {
+	m := newVRC24(newVRC24ROM(23, 2, true)).(*vrc24)
+	m.Write(0x9000, 2)
+	assert.Equal(t, Mirroring_SingleScreenA, m.Mirroring())
+	m.Write(0x9000, 1)
+	assert.Equal(t, Mirroring_Horizontal, m.Mirroring())
+
+	vrc2 := newVRC24(newVRC24ROM(23, 3, true)).(*vrc24)
+	vrc2.Write(0x9000, 3)
+	assert.Equal(t, Mirroring_Horizontal, vrc2.Mirroring())
+}
+
+func Test_vrc24_microwire(t *testing.T) {
+	m := newVRC24(newVRC24ROM(23, 3, true)).(*vrc24)
+	m.Write(0x6000, 0xFF)
+	assert.EqualValues(t, 1, m.Read(0x6000)&1)
+	m.Write(0x6123, 0xFE)
+	assert.EqualValues(t, 0, m.Read(0x6000)&1)
+
+	// VRC4 has PRG RAM instead
+	vrc4 := newVRC24(newVRC24ROM(23, 2, true)).(*vrc24)
+	vrc4.Write(0x6000, 0xAB)
+	assert.EqualValues(t, 0xAB, vrc4.Read(0x6000))
+}
+
+func Test_vrcIRQ(t *testing.T) {
+	t.Run("cycle mode", func(t *testing.T) {
+		var i vrcIRQ
+		i.latch = 0xFD
+		i.writeControl(0b111)
+
+		i.clock()
+		i.clock()
+		assert.False(t, i.irq)
+		i.clock()
+		assert.True(t, i.irq)
+		assert.EqualValues(t, 0xFD, i.counter, "reloaded")
+
+		i.acknowledge()
+		assert.False(t, i.irq)
+		assert.True(t, i.enabled)
+	})
+	t.Run("scanline mode", func(t *testing.T) {
+		var i vrcIRQ
+		i.latch = 0xFF
+		i.writeControl(0b010)
+
+		// 341 / 3 = 113.67 CPU cycles per scanline
+		for n := 0; n < 113; n++ {
+			i.clock()
+		}
+		assert.False(t, i.irq)
+		i.clock()
+		assert.True(t, i.irq)
+
+		i.acknowledge()
+		assert.False(t, i.enabled)
+	})
+}
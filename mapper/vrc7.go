@@ -0,0 +1,187 @@
+package mapper
+
+import (
+	"fmt"
+
+	"github.com/thara/gorones/opll"
+)
+
+// https://www.nesdev.org/wiki/VRC7
+
+const (
+	// OPLL generates a sample every 36 CPU cycles
+	vrc7AudioCycles = 36
+
+	// scales OPLL output, where a channel at full volume is roughly as loud as a 2A03 pulse at full volume.
+	vrc7OutputScale = 0.15
+)
+
+func init() {
+	Register(85, AnySubmapper, "Konami VRC7", infallible(newVRC7))
+	RegisterUNIFBoard("KONAMI-VRC-7", 85, AnySubmapper)
+}
+
+type vrc7 struct {
+	name string
+	// the CPU address line connected to the register select pin
+	a0 uint16
+
+	prg []byte
+	chr []byte
+
+	chrRAM bool
+
+	prgRAM        [0x2000]uint8
+	prgRAMEnabled bool
+
+	prgBanks  [3]uint8
+	chrBanks  [8]uint8
+	mirroring Mirroring
+
+	irq vrcIRQ
+
+	fm          *opll.OPLL
+	audioCycles uint8
+	audioReset  bool
+}
+
+func newVRC7(rom *ROM) Mapper {
+	prg, chr := rom.Banks()
+	m := &vrc7{
+		prg:       prg,
+		chr:       chr,
+		chrRAM:    rom.header.CHRROMSize == 0,
+		mirroring: rom.header.Mirroring,
+		fm:        opll.New(),
+	}
+
+	// https://www.nesdev.org/wiki/NES_2.0_submappers#085:_Konami_VRC7
+	switch {
+	case rom.header.NES2 && rom.header.Submapper == 1:
+		m.name, m.a0 = "VRC7b", 0x08
+	case rom.header.NES2 && rom.header.Submapper == 2:
+		m.name, m.a0 = "VRC7a", 0x10
+	default:
+		// games access registers via only one of them
+		m.name, m.a0 = "VRC7a/b", 0x08|0x10
+	}
+	return m
+}
+
+func (m *vrc7) Read(addr uint16) uint8 {
+	switch {
+	case 0x0000 <= addr && addr <= 0x1FFF:
+		return m.chr[m.chrAddr(addr)]
+	case 0x6000 <= addr && addr <= 0x7FFF:
+		if m.prgRAMEnabled {
+			return m.prgRAM[addr-0x6000]
+		}
+	case 0x8000 <= addr && addr <= 0xFFFF:
+		return m.prg[m.prgAddr(addr)]
+	}
+	return 0
+}
+
+func (m *vrc7) Write(addr uint16, value uint8) {
+	switch {
+	case 0x0000 <= addr && addr <= 0x1FFF:
+		if m.chrRAM {
+			m.chr[m.chrAddr(addr)] = value
+		}
+	case 0x6000 <= addr && addr <= 0x7FFF:
+		if m.prgRAMEnabled {
+			m.prgRAM[addr-0x6000] = value
+		}
+	case addr&0xF030 == 0x9010:
+		m.fm.WriteAddress(value)
+	case addr&0xF030 == 0x9030:
+		m.fm.WriteData(value)
+	case 0x8000 <= addr && addr <= 0xFFFF:
+		reg := addr & 0xF000
+		if addr&m.a0 != 0 {
+			reg |= 0x10
+		}
+		m.writeRegister(reg, value)
+	}
+}
+
+func (m *vrc7) writeRegister(reg uint16, value uint8) {
+	switch reg {
+	case 0x8000:
+		m.prgBanks[0] = value & 0x3F
+	case 0x8010:
+		m.prgBanks[1] = value & 0x3F
+	case 0x9000:
+		m.prgBanks[2] = value & 0x3F
+	case 0xA000, 0xA010, 0xB000, 0xB010, 0xC000, 0xC010, 0xD000, 0xD010:
+		n := ((reg-0xA000)>>12)*2 + (reg>>4)&1
+		m.chrBanks[n] = value
+	case 0xE000:
+		m.mirroring = [4]Mirroring{
+			Mirroring_Vertical, Mirroring_Horizontal, Mirroring_SingleScreenA, Mirroring_SingleScreenB,
+		}[value&0b11]
+		m.audioReset = value&0x40 != 0
+		if m.audioReset {
+			m.fm.Reset()
+		}
+		m.prgRAMEnabled = value&0x80 != 0
+	case 0xE010:
+		m.irq.latch = value
+	case 0xF000:
+		m.irq.writeControl(value)
+	case 0xF010:
+		m.irq.acknowledge()
+	}
+}
+
+func (m *vrc7) prgAddr(addr uint16) int {
+	var bank int
+	if addr < 0xE000 {
+		bank = int(m.prgBanks[(addr-0x8000)/0x2000])
+	} else {
+		bank = len(m.prg)/0x2000 - 1
+	}
+	return (bank*0x2000 + int(addr%0x2000)) % len(m.prg)
+}
+
+func (m *vrc7) chrAddr(addr uint16) int {
+	bank := int(m.chrBanks[addr/0x0400])
+	return (bank*0x0400 + int(addr%0x0400)) % len(m.chr)
+}
+
+// Tick clocks the IRQ counter and FM synthesis on each CPU cycle.
+func (m *vrc7) Tick() {
+	m.irq.clock()
+
+	m.audioCycles++
+	if vrc7AudioCycles <= m.audioCycles {
+		m.audioCycles = 0
+		if !m.audioReset {
+			m.fm.Clock()
+		}
+	}
+}
+
+func (m *vrc7) IRQ() bool { return m.irq.irq }
+
+func (m *vrc7) Sample() float32 {
+	if m.audioReset {
+		return 0
+	}
+	return m.fm.Output() * vrc7OutputScale
+}
+
+func (m *vrc7) Mirroring() Mirroring {
+	return m.mirroring
+}
+
+func (m *vrc7) PRG() []byte { return append([]byte(nil), m.prg...) }
+func (m *vrc7) CHR() []byte { return append([]byte(nil), m.chr...) }
+
+func (m vrc7) String() string {
+	return fmt.Sprintf(`Konami %s:
+	PRG: 0x%x byte
+	CHR: 0x%x byte
+	mirroring: %s
+`, m.name, len(m.prg), len(m.chr), m.mirroring)
+}
@@ -0,0 +1,83 @@
+package mapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newVRC7ROM(submapper uint8, nes2 bool) *ROM {
+	// 128KB PRG, 128KB CHR; each byte holds its bank number
+	prg := make([]byte, 0x20000)
+	for i := range prg {
+		prg[i] = uint8(i / 0x2000)
+	}
+	chr := make([]byte, 0x20000)
+	for i := range chr {
+		chr[i] = uint8(i / 0x0400)
+	}
+	return &ROM{
+		header: Header{MapperNo: 85, Submapper: submapper, NES2: nes2, PRGROMSize: 8, CHRROMSize: 16},
+		raw:    append(prg, chr...),
+	}
+}
+
+func Test_vrc7_banks(t *testing.T) {
+	m := newVRC7(newVRC7ROM(2, true)).(*vrc7)
+
+	m.Write(0x8000, 1)
+	m.Write(0x8010, 2)
+	m.Write(0x9000, 3)
+	assert.EqualValues(t, 1, m.Read(0x8000))
+	assert.EqualValues(t, 2, m.Read(0xA000))
+	assert.EqualValues(t, 3, m.Read(0xC000))
+	assert.EqualValues(t, 15, m.Read(0xE000))
+
+	m.Write(0xA010, 0x21)
+	m.Write(0xD010, 0x7F)
+	assert.EqualValues(t, 0x21, m.Read(0x0400))
+	assert.EqualValues(t, 0x7F, m.Read(0x1C00))
+
+	// VRC7b uses A3 instead
+	b := newVRC7(newVRC7ROM(1, true)).(*vrc7)
+	b.Write(0x8008, 4)
+	assert.EqualValues(t, 4, b.Read(0xA000))
+}
+
+func Test_vrc7_control(t *testing.T) {
+	m := newVRC7(newVRC7ROM(0, false)).(*vrc7)
+
+	m.Write(0x6000, 0xAB)
+	assert.EqualValues(t, 0, m.Read(0x6000), "PRG RAM disabled")
+
+	m.Write(0xE000, 0x83)
+	assert.Equal(t, Mirroring_SingleScreenB, m.Mirroring())
+	m.Write(0x6000, 0xAB)
+	assert.EqualValues(t, 0xAB, m.Read(0x6000))
+}
+
+func Test_vrc7_audio(t *testing.T) {
+	m := newVRC7(newVRC7ROM(0, false)).(*vrc7)
+
+	// channel 0: Flute, 440Hz, key on
+	for _, r := range [][2]uint8{{0x30, 0x40}, {0x10, 0x22}, {0x20, 0x19}} {
+		m.Write(0x9010, r[0])
+		m.Write(0x9030, r[1])
+	}
+
+	var peak float32
+	for i := 0; i < vrc7AudioCycles*1000; i++ {
+		m.Tick()
+		if s := m.Sample(); peak < s {
+			peak = s
+		}
+	}
+	assert.Less(t, float32(0), peak)
+
+	// sound reset silences
+	m.Write(0xE000, 0x40)
+	for i := 0; i < vrc7AudioCycles*10; i++ {
+		m.Tick()
+		assert.Zero(t, m.Sample())
+	}
+}
@@ -0,0 +1,61 @@
+package mapper
+
+// https://www.nesdev.org/wiki/VRC_IRQ
+
+// vrcIRQ is the IRQ counter shared by Konami VRC4, VRC6 and VRC7.
+type vrcIRQ struct {
+	latch   uint8
+	counter uint8
+
+	// the prescaler divides CPU clock by 113.667 to emulate scanlines
+	prescaler int
+
+	enabled         bool
+	enabledAfterAck bool
+	cycleMode       bool
+
+	irq bool
+}
+
+const vrcIRQPrescalerPeriod = 341
+
+func (i *vrcIRQ) writeControl(value uint8) {
+	i.enabledAfterAck = value&0b001 != 0
+	i.enabled = value&0b010 != 0
+	i.cycleMode = value&0b100 != 0
+	if i.enabled {
+		i.counter = i.latch
+		i.prescaler = vrcIRQPrescalerPeriod
+	}
+	i.irq = false
+}
+
+func (i *vrcIRQ) acknowledge() {
+	i.irq = false
+	i.enabled = i.enabledAfterAck
+}
+
+// clock is called every CPU cycle.
+func (i *vrcIRQ) clock() {
+	if !i.enabled {
+		return
+	}
+	if i.cycleMode {
+		i.clockCounter()
+		return
+	}
+	i.prescaler -= 3
+	if i.prescaler <= 0 {
+		i.prescaler += vrcIRQPrescalerPeriod
+		i.clockCounter()
+	}
+}
+
+func (i *vrcIRQ) clockCounter() {
+	if i.counter == 0xFF {
+		i.counter = i.latch
+		i.irq = true
+	} else {
+		i.counter++
+	}
+}
@@ -0,0 +1,149 @@
+package movie
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// https://fceux.com/web/help/fm2.html
+
+// buttons of a gamepad in FM2 from bit 7 to bit 0, the same order as input.StandardControllerButton
+const fm2Buttons = "RLDUTSBA"
+
+// ReadFM2 reads a movie in FCEUX's FM2 text format, which must start from power on with gamepads.
+func ReadFM2(r io.Reader) (*Movie, error) {
+	m := &Movie{}
+	ports := [2]int{1, 1}
+
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "|") {
+			f, err := parseFM2Frame(line, ports)
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d", n)
+			}
+			m.Frames = append(m.Frames, f)
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "romFilename":
+			m.ROMFilename = value
+		case "romChecksum":
+			b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, "base64:"))
+			if err != nil || len(b) != len(m.ROMChecksum) {
+				return nil, errors.Errorf("line %d: invalid romChecksum: %s", n, value)
+			}
+			copy(m.ROMChecksum[:], b)
+		case "rerecordCount":
+			m.RerecordCount, _ = strconv.Atoi(value)
+		case "comment":
+			m.Comments = append(m.Comments, value)
+		case "FDS":
+			m.FDS = value == "1"
+		case "port0", "port1":
+			p, err := strconv.Atoi(value)
+			if err != nil || 1 < p {
+				return nil, errors.Errorf("line %d: unsupported input device: %s", n, line)
+			}
+			ports[key[4]-'0'] = p
+		case "binary", "fourscore", "palFlag":
+			if value != "0" {
+				return nil, errors.Errorf("line %d: unsupported movie: %s", n, line)
+			}
+		case "savestate":
+			return nil, errors.Errorf("line %d: movies starting from a savestate are not supported", n)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read FM2")
+	}
+	return m, nil
+}
+
+// parseFM2Frame parses a line like "|0|R..U...A|........||".
+func parseFM2Frame(line string, ports [2]int) (Frame, error) {
+	fields := strings.Split(line, "|")
+	if len(fields) < 4 {
+		return Frame{}, errors.Errorf("invalid input: %s", line)
+	}
+	var f Frame
+	c, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return Frame{}, errors.Errorf("invalid command: %s", line)
+	}
+	f.Command = Command(c)
+	for i, p := range ports {
+		s := fields[2+i]
+		if p == 0 {
+			continue
+		}
+		if len(s) != len(fm2Buttons) {
+			return Frame{}, errors.Errorf("invalid gamepad input: %s", line)
+		}
+		for j := range s {
+			if s[j] != '.' && s[j] != ' ' {
+				f.Input[i] |= 0x80 >> j
+			}
+		}
+	}
+	return f, nil
+}
+
+// WriteFM2 writes the movie in FCEUX's FM2 text format with gamepads on port 0 and 1.
+func (m *Movie) WriteFM2(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "version 3")
+	fmt.Fprintln(bw, "emuVersion 22020")
+	fmt.Fprintf(bw, "rerecordCount %d\n", m.RerecordCount)
+	fmt.Fprintln(bw, "palFlag 0")
+	fmt.Fprintf(bw, "romFilename %s\n", m.ROMFilename)
+	fmt.Fprintf(bw, "romChecksum base64:%s\n", base64.StdEncoding.EncodeToString(m.ROMChecksum[:]))
+	fmt.Fprintf(bw, "guid %s\n", newGUID())
+	fmt.Fprintln(bw, "fourscore 0")
+	fmt.Fprintln(bw, "microphone 0")
+	fmt.Fprintln(bw, "port0 1")
+	fmt.Fprintln(bw, "port1 1")
+	fmt.Fprintln(bw, "port2 0")
+	if m.FDS {
+		fmt.Fprintln(bw, "FDS 1")
+	} else {
+		fmt.Fprintln(bw, "FDS 0")
+	}
+	fmt.Fprintln(bw, "NewPPU 0")
+	for _, c := range m.Comments {
+		fmt.Fprintf(bw, "comment %s\n", c)
+	}
+
+	var buttons [2][len(fm2Buttons)]byte
+	for _, f := range m.Frames {
+		for i, in := range f.Input {
+			for j := range fm2Buttons {
+				buttons[i][j] = '.'
+				if in&(0x80>>j) != 0 {
+					buttons[i][j] = fm2Buttons[j]
+				}
+			}
+		}
+		fmt.Fprintf(bw, "|%d|%s|%s||\n", f.Command, buttons[0][:], buttons[1][:])
+	}
+	return errors.Wrap(bw.Flush(), "failed to write FM2")
+}
+
+func newGUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,93 @@
+// Package movie records and plays back the input of controllers in each frame.
+package movie
+
+import (
+	"bytes"
+	"crypto/md5"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/thara/gorones"
+	"github.com/thara/gorones/mapper"
+)
+
+// Command is an event applied at the start of a frame, by the same bits as FM2.
+type Command uint8
+
+const (
+	CommandReset Command = 1 << 0
+	// power cycle
+	CommandPower Command = 1 << 1
+)
+
+// Frame is the input of a frame.
+type Frame struct {
+	Command Command
+	Input   gorones.Input
+}
+
+// Movie is the input log from power on.
+type Movie struct {
+	ROMFilename string
+	// MD5 of PRG and CHR ROM
+	ROMChecksum [16]byte
+	// the number of times rewound or loaded while recording
+	RerecordCount int
+	// whether the ROM is a disk image of Famicom Disk System
+	FDS      bool
+	Comments []string
+
+	Frames []Frame
+}
+
+// Checksum returns MD5 of PRG and CHR ROM of the mapper, which identifies the ROM of a movie.
+func Checksum(m mapper.Mapper) [16]byte {
+	return md5.Sum(append(m.PRG(), m.CHR()...))
+}
+
+// Load loads a movie in FM2 if the extension is ".fm2", or in the native format.
+func Load(path string) (*Movie, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+	if isFM2(path) {
+		return ReadFM2(bytes.NewReader(b))
+	}
+	return Read(bytes.NewReader(b))
+}
+
+// Save saves the movie in FM2 if the extension is ".fm2", or in the native format.
+func (m *Movie) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create %s", path)
+	}
+	var w io.Writer = f
+	if isFM2(path) {
+		err = m.WriteFM2(w)
+	} else {
+		err = m.Write(w)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func isFM2(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".fm2")
+}
+
+// apply applies the command to NES.
+func (c Command) apply(nes *gorones.NES) {
+	if c&CommandPower != 0 {
+		nes.PowerOn()
+		nes.Reset()
+	} else if c&CommandReset != 0 {
+		nes.Reset()
+	}
+}
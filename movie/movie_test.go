@@ -0,0 +1,221 @@
+package movie
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thara/gorones"
+	"github.com/thara/gorones/input"
+	"github.com/thara/gorones/mapper"
+	"github.com/thara/gorones/ppu"
+)
+
+type nopFrameRenderer struct{}
+
+func (nopFrameRenderer) UpdateFrame(*[ppu.WIDTH * ppu.HEIGHT]uint8) {}
+
+type nopAudioRenderer struct{}
+
+func (nopAudioRenderer) Write(float32) {}
+
+func newTestNES(t *testing.T) (*gorones.NES, mapper.Mapper) {
+	f, err := os.Open("../testdata/nestest.nes")
+	require.NoError(t, err)
+	defer f.Close()
+
+	rom, err := mapper.ParseROM(f)
+	require.NoError(t, err)
+	m, err := rom.Mapper()
+	require.NoError(t, err)
+
+	var ctrl1, ctrl2 input.StandardController
+	nes := gorones.NewNES(m, &ctrl1, &ctrl2, nopFrameRenderer{}, nopAudioRenderer{})
+	nes.PowerOn()
+	nes.Reset()
+	return nes, m
+}
+
+const testFM2 = `version 3
+emuVersion 22020
+rerecordCount 7
+palFlag 0
+romFilename nestest
+romChecksum base64:AAECAwQFBgcICQoLDA0ODw==
+guid 01234567-89AB-CDEF-0123-456789ABCDEF
+fourscore 0
+microphone 0
+port0 1
+port1 1
+port2 0
+FDS 0
+NewPPU 0
+comment author someone
+|2|........|........||
+|0|R......A|.L......||
+|0|...UT...|        ||
+|1|........|RLDUTSBA||
+`
+
+var testFrames = []Frame{
+	{Command: CommandPower},
+	{Input: gorones.Input{input.StandardRight | input.StandardA, input.StandardLeft}},
+	{Input: gorones.Input{input.StandardUp | input.StandardStart, 0}},
+	{Command: CommandReset, Input: gorones.Input{0, 0xFF}},
+}
+
+func TestReadFM2(t *testing.T) {
+	m, err := ReadFM2(strings.NewReader(testFM2))
+	require.NoError(t, err)
+	assert.Equal(t, "nestest", m.ROMFilename)
+	assert.Equal(t, [16]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}, m.ROMChecksum)
+	assert.Equal(t, 7, m.RerecordCount)
+	assert.Equal(t, []string{"author someone"}, m.Comments)
+	assert.Equal(t, testFrames, m.Frames)
+
+	var b bytes.Buffer
+	require.NoError(t, m.WriteFM2(&b))
+	assert.Contains(t, b.String(), "|0|R......A|.L......||\n")
+	got, err := ReadFM2(&b)
+	require.NoError(t, err)
+	assert.Equal(t, m, got)
+
+	m.FDS = true
+	b.Reset()
+	require.NoError(t, m.WriteFM2(&b))
+	assert.Contains(t, b.String(), "FDS 1\n")
+	got, err = ReadFM2(&b)
+	require.NoError(t, err)
+	assert.True(t, got.FDS)
+
+	for _, s := range []string{"binary 1\n", "fourscore 1\n", "port0 2\n", "savestate base64:AA==\n", "|x|........|........||\n", "|0|...|........||\n"} {
+		_, err := ReadFM2(strings.NewReader(s))
+		assert.Error(t, err, s)
+	}
+}
+
+func TestNative(t *testing.T) {
+	m := &Movie{ROMFilename: "nestest", RerecordCount: 300, FDS: true, Comments: []string{"a", "b"}, Frames: testFrames}
+	for i := 0; i < 1000; i++ {
+		m.Frames = append(m.Frames, Frame{Input: gorones.Input{input.StandardRight}})
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, m.Write(&b))
+	// run-length encoded
+	assert.Less(t, b.Len(), 64)
+	got, err := Read(&b)
+	require.NoError(t, err)
+	assert.Equal(t, m, got)
+
+	_, err = Read(strings.NewReader("GNMV\x03"))
+	assert.Error(t, err)
+
+	// version 1 has no flags
+	b.Reset()
+	require.NoError(t, m.Write(&b))
+	v1 := b.Bytes()
+	v1[4] = 1
+	v1 = append(v1[:5+16], v1[5+16+1:]...)
+	got, err = Read(bytes.NewReader(v1))
+	require.NoError(t, err)
+	assert.False(t, got.FDS)
+	assert.Equal(t, m.Frames, got.Frames)
+}
+
+func TestRecordAndPlay(t *testing.T) {
+	nes, m := newTestNES(t)
+	rec := NewRecorder(nes, &Movie{ROMChecksum: Checksum(m)})
+	for i := 0; i < 200; i++ {
+		var in gorones.Input
+		switch {
+		case i%20 == 5:
+			in[0] = input.StandardDown
+		case i == 90 || i == 150:
+			in[0] = input.StandardStart
+		}
+		nes.SetInput(in)
+		if i == 120 {
+			rec.Reset()
+		}
+		rec.RunFrame()
+	}
+	ram, frame := nes.RAMHash(), nes.FrameHash()
+	movie := rec.Movie()
+	require.Len(t, movie.Frames, 200)
+	assert.Equal(t, CommandReset, movie.Frames[120].Command)
+
+	for _, name := range []string{"movie.fm2", "movie.gnmv"} {
+		path := filepath.Join(t.TempDir(), name)
+		require.NoError(t, movie.Save(path))
+		loaded, err := Load(path)
+		require.NoError(t, err)
+
+		nes, m := newTestNES(t)
+		assert.Equal(t, Checksum(m), loaded.ROMChecksum)
+		p := NewPlayer(nes, loaded)
+		for p.RunFrame() {
+		}
+		assert.True(t, p.Done())
+		assert.Equal(t, 200, p.Frame())
+		assert.Equal(t, ram, nes.RAMHash(), name)
+		assert.Equal(t, frame, nes.FrameHash(), name)
+	}
+}
+
+func TestRecorder_LoadState(t *testing.T) {
+	nes, m := newTestNES(t)
+	rec := NewRecorder(nes, &Movie{ROMChecksum: Checksum(m)})
+	run := func(n int, in gorones.Input) {
+		for i := 0; i < n; i++ {
+			nes.SetInput(in)
+			rec.RunFrame()
+		}
+	}
+	run(60, gorones.Input{})
+	b, err := rec.SaveState()
+	require.NoError(t, err)
+	run(30, gorones.Input{input.StandardStart})
+
+	require.NoError(t, rec.LoadState(b))
+	assert.Len(t, rec.Movie().Frames, 60, "truncated to the frame of the state")
+	assert.Equal(t, 1, rec.Movie().RerecordCount)
+	run(30, gorones.Input{input.StandardDown})
+	ram, frame := nes.RAMHash(), nes.FrameHash()
+
+	// the rerecorded movie reproduces the last run
+	nes, _ = newTestNES(t)
+	p := NewPlayer(nes, rec.Movie())
+	for p.RunFrame() {
+	}
+	assert.Equal(t, 90, p.Frame())
+	assert.Equal(t, ram, nes.RAMHash())
+	assert.Equal(t, frame, nes.FrameHash())
+
+	// a state saved after the current frame is not of this movie
+	require.NoError(t, rec.LoadState(b))
+	later := appendUvarint(nil, 1000)
+	later = append(later, b[1:]...)
+	assert.Error(t, rec.LoadState(later))
+	assert.Error(t, rec.LoadState(nil))
+	assert.Equal(t, 2, rec.Movie().RerecordCount)
+}
+
+// Test_nestestMovie runs all tests of nestest.nes by a movie, and verifies the result as a regression test.
+func Test_nestestMovie(t *testing.T) {
+	mv, err := Load("../testdata/nestest.fm2")
+	require.NoError(t, err)
+
+	nes, m := newTestNES(t)
+	assert.Equal(t, Checksum(m), mv.ROMChecksum)
+	p := NewPlayer(nes, mv)
+	for p.RunFrame() {
+	}
+	assert.Equal(t, 182, p.Frame())
+	assert.Equal(t, "52c6e98794057f8bfdaa2cf66aed15bb1dab1e61", nes.RAMHash())
+	assert.Equal(t, "2bfe5ffe2fae65fa730c04735a3b25115c5fb65e", nes.FrameHash())
+}
@@ -0,0 +1,140 @@
+package movie
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// The native format is a compact binary of run-length encoded frames:
+//
+//	magic "GNMV", version (1 byte)
+//	ROM checksum (16 bytes), flags (1 byte, bit 0: FDS; since version 2), rerecord count (uvarint)
+//	ROM filename, comments count and each comment (uvarint length + bytes)
+//	runs until EOF: the number of frames (uvarint), command, input of port 0 and 1 (1 byte each)
+
+const (
+	nativeMagic   = "GNMV"
+	nativeVersion = 2
+)
+
+const nativeFlagFDS = 1 << 0
+
+// Read reads a movie in the native format.
+func Read(r io.Reader) (*Movie, error) {
+	br := bufio.NewReader(r)
+	var h [len(nativeMagic) + 1]byte
+	if _, err := io.ReadFull(br, h[:]); err != nil {
+		return nil, errors.Wrap(err, "failed to read movie header")
+	}
+	if string(h[:len(nativeMagic)]) != nativeMagic {
+		return nil, errors.New("invalid movie")
+	}
+	version := h[len(nativeMagic)]
+	if version < 1 || nativeVersion < version {
+		return nil, errors.Errorf("unsupported movie version: %d", version)
+	}
+
+	m := &Movie{}
+	if _, err := io.ReadFull(br, m.ROMChecksum[:]); err != nil {
+		return nil, errors.Wrap(err, "failed to read movie header")
+	}
+	if 2 <= version {
+		flags, err := br.ReadByte()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read movie header")
+		}
+		m.FDS = flags&nativeFlagFDS != 0
+	}
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read movie header")
+	}
+	m.RerecordCount = int(n)
+	if m.ROMFilename, err = readString(br); err != nil {
+		return nil, err
+	}
+	if n, err = binary.ReadUvarint(br); err != nil {
+		return nil, errors.Wrap(err, "failed to read movie header")
+	}
+	for i := uint64(0); i < n; i++ {
+		c, err := readString(br)
+		if err != nil {
+			return nil, err
+		}
+		m.Comments = append(m.Comments, c)
+	}
+
+	for {
+		n, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrap(err, "failed to read movie frames")
+		}
+		var b [3]byte
+		if _, err := io.ReadFull(br, b[:]); err != nil {
+			return nil, errors.Wrap(err, "failed to read movie frames")
+		}
+		f := Frame{Command: Command(b[0])}
+		copy(f.Input[:], b[1:])
+		for i := uint64(0); i < n; i++ {
+			m.Frames = append(m.Frames, f)
+		}
+	}
+	return m, nil
+}
+
+func readString(r *bufio.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read movie header")
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", errors.Wrap(err, "failed to read movie header")
+	}
+	return string(b), nil
+}
+
+// Write writes the movie in the native format.
+func (m *Movie) Write(w io.Writer) error {
+	b := append([]byte(nativeMagic), nativeVersion)
+	b = append(b, m.ROMChecksum[:]...)
+	var flags byte
+	if m.FDS {
+		flags |= nativeFlagFDS
+	}
+	b = append(b, flags)
+	b = appendUvarint(b, uint64(m.RerecordCount))
+	b = appendString(b, m.ROMFilename)
+	b = appendUvarint(b, uint64(len(m.Comments)))
+	for _, c := range m.Comments {
+		b = appendString(b, c)
+	}
+
+	for i := 0; i < len(m.Frames); {
+		f := m.Frames[i]
+		n := 1
+		for i+n < len(m.Frames) && m.Frames[i+n] == f {
+			n++
+		}
+		b = appendUvarint(b, uint64(n))
+		b = append(b, byte(f.Command), f.Input[0], f.Input[1])
+		i += n
+	}
+	_, err := w.Write(b)
+	return errors.Wrap(err, "failed to write movie")
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = appendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
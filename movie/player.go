@@ -0,0 +1,34 @@
+package movie
+
+import "github.com/thara/gorones"
+
+// Player runs NES by the input of a movie.
+type Player struct {
+	nes   *gorones.NES
+	movie *Movie
+	frame int
+}
+
+// NewPlayer creates a player of the movie, which should start just after power on.
+func NewPlayer(nes *gorones.NES, m *Movie) *Player {
+	return &Player{nes: nes, movie: m}
+}
+
+// Frame returns the number of frames played.
+func (p *Player) Frame() int { return p.frame }
+
+// Done reports whether all frames are played.
+func (p *Player) Done() bool { return len(p.movie.Frames) <= p.frame }
+
+// RunFrame runs the next frame by the movie, and returns false if done.
+func (p *Player) RunFrame() bool {
+	if p.Done() {
+		return false
+	}
+	f := p.movie.Frames[p.frame]
+	f.Command.apply(p.nes)
+	p.nes.SetInput(f.Input)
+	p.nes.RunFrame()
+	p.frame++
+	return true
+}
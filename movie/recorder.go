@@ -0,0 +1,68 @@
+package movie
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+	"github.com/thara/gorones"
+)
+
+// Recorder runs NES while recording the input of each frame into a movie.
+type Recorder struct {
+	nes   *gorones.NES
+	movie *Movie
+
+	// commands applied to the next frame
+	command Command
+}
+
+// NewRecorder creates a recorder appending frames into the movie, which should start just after power on.
+func NewRecorder(nes *gorones.NES, m *Movie) *Recorder {
+	return &Recorder{nes: nes, movie: m}
+}
+
+// Movie returns the recorded movie.
+func (r *Recorder) Movie() *Movie { return r.movie }
+
+// Reset resets NES, which is recorded in the next frame.
+func (r *Recorder) Reset() {
+	r.nes.Reset()
+	r.command |= CommandReset
+}
+
+// PowerOn power-cycles NES, which is recorded in the next frame.
+func (r *Recorder) PowerOn() {
+	CommandPower.apply(r.nes)
+	r.command |= CommandPower
+}
+
+// RunFrame records the current input of the controllers and runs a frame.
+func (r *Recorder) RunFrame() {
+	r.movie.Frames = append(r.movie.Frames, Frame{Command: r.command, Input: r.nes.Input()})
+	r.command = 0
+	r.nes.RunFrame()
+}
+
+// SaveState returns the state of NES with the number of recorded frames, which can be restored by LoadState.
+func (r *Recorder) SaveState() ([]byte, error) {
+	b, err := r.nes.SaveState()
+	if err != nil {
+		return nil, err
+	}
+	return append(appendUvarint(nil, uint64(len(r.movie.Frames))), b...), nil
+}
+
+// LoadState restores the state returned by SaveState, and truncates the movie to the frame of the state as a rerecord.
+func (r *Recorder) LoadState(b []byte) error {
+	frames, n := binary.Uvarint(b)
+	if n <= 0 || uint64(len(r.movie.Frames)) < frames {
+		return errors.New("state is not of this movie")
+	}
+	if err := r.nes.LoadState(b[n:]); err != nil {
+		return err
+	}
+	r.movie.Frames = r.movie.Frames[:frames]
+	r.movie.RerecordCount++
+	r.command = 0
+	return nil
+}
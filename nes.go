@@ -1,7 +1,12 @@
 package gorones
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
+	"image"
+
 	"github.com/thara/gorones/apu"
+	"github.com/thara/gorones/cheat"
 	"github.com/thara/gorones/cpu"
 	"github.com/thara/gorones/input"
 	"github.com/thara/gorones/mapper"
@@ -21,16 +26,38 @@ type NES struct {
 	wram   [0x0800]uint8
 	mapper mapper.Mapper
 
+	// optional capabilities of the mapper
+	mapperTicker cpu.Ticker
+	mapperIRQ    mapper.IRQSource
+
 	ctrl1, ctrl2 input.Controller
+
+	// nil if no cheats
+	cheats *cheat.Engine
+
+	frameRenderer ppu.FrameRenderer
+	audioRenderer apu.AudioRenderer
+	// whether frames and audio are discarded
+	silent bool
 }
 
 func NewNES(m mapper.Mapper, ctrl1, ctrl2 input.Controller, frameRenderer ppu.FrameRenderer, audioRenderer apu.AudioRenderer) *NES {
 	intr := cpu.NoInterrupt
 
-	nes := &NES{interrupt: &intr, mapper: m, ctrl1: ctrl1, ctrl2: ctrl2}
+	nes := &NES{interrupt: &intr, mapper: m, ctrl1: ctrl1, ctrl2: ctrl2, frameRenderer: frameRenderer, audioRenderer: audioRenderer}
 	nes.cpu = cpu.New(nes, nes)
 	nes.ppu = ppu.New(m, frameRenderer)
 	nes.apu = apu.New(audioRenderer)
+
+	if t, ok := m.(cpu.Ticker); ok {
+		nes.mapperTicker = t
+	}
+	if irq, ok := m.(mapper.IRQSource); ok {
+		nes.mapperIRQ = irq
+	}
+	if e, ok := m.(apu.ExpansionAudio); ok {
+		nes.apu.SetExpansionAudio(e)
+	}
 	return nes
 }
 
@@ -50,17 +77,92 @@ func (n *NES) InitNEStest() {
 	n.cpu.Cycles = 7
 }
 
+// SetAudioFilters changes the filter chain applied to the audio output. No filters disables filtering.
+func (n *NES) SetAudioFilters(filters ...apu.Filter) {
+	n.apu.SetFilters(filters...)
+}
+
+// AudioMixer returns the mixer of the audio output.
+func (n *NES) AudioMixer() *apu.Mixer {
+	return n.apu.Mixer()
+}
+
+// SetChannelRenderer sets the renderer of each audio channel output before mixing. nil stops rendering.
+func (n *NES) SetChannelRenderer(r apu.ChannelRenderer) {
+	n.apu.SetChannelRenderer(r)
+}
+
+// SetCheats sets cheats applied to reads and every frame. nil disables cheats.
+func (n *NES) SetCheats(e *cheat.Engine) {
+	n.cheats = e
+}
+
+// Screenshot returns the image of the last rendered frame in ppu.Palette.
+func (n *NES) Screenshot() image.Image {
+	return ppu.FrameImage(n.ppu.Frame())
+}
+
+// RAM returns a copy of the internal RAM at $0000-$07FF.
+func (n *NES) RAM() []byte {
+	return append([]byte(nil), n.wram[:]...)
+}
+
+// RAMHash returns SHA-1 of the internal RAM in hex, which identifies the state in regression tests.
+func (n *NES) RAMHash() string {
+	h := sha1.Sum(n.wram[:])
+	return hex.EncodeToString(h[:])
+}
+
+// FrameHash returns SHA-1 of the color indices of the last rendered frame in hex.
+func (n *NES) FrameHash() string {
+	h := sha1.Sum(n.ppu.Frame()[:])
+	return hex.EncodeToString(h[:])
+}
+
+// Frames returns the number of frames rendered since power on.
+func (n *NES) Frames() uint64 {
+	return n.ppu.CurrentFrames()
+}
+
 func (n *NES) RunFrame() {
+	if n.cheats != nil {
+		n.cheats.Poke(n)
+	}
 	before := n.ppu.CurrentFrames()
 	for before == n.ppu.CurrentFrames() {
 		n.step()
 	}
 }
 
+// Step runs an instruction of CPU.
+func (n *NES) Step() {
+	n.step()
+}
+
 func (n *NES) step() {
 	n.cpu.Step(n.interrupt)
 }
 
+// PC returns the program counter of CPU.
+func (n *NES) PC() uint16 {
+	return n.cpu.PC
+}
+
+// Trace returns the state of CPU before the next instruction.
+func (n *NES) Trace() cpu.Trace {
+	return n.cpu.Trace()
+}
+
+// Peek reads CPU memory without side effects for debuggers, where $2000-$5FFF reads 0 since they are registers.
+//
+// Cheats do not substitute the values, so that the memory is seen as is.
+func (n *NES) Peek(addr uint16) uint8 {
+	if 0x2000 <= addr && addr < 0x6000 {
+		return 0
+	}
+	return n.readCPU(addr)
+}
+
 func (n *NES) Tick() {
 	n.cycles += 1
 
@@ -69,6 +171,18 @@ func (n *NES) Tick() {
 		n.cycles += 4
 	}
 
+	if n.mapperTicker != nil {
+		n.mapperTicker.Tick()
+	}
+	// IRQ is level-triggered and wired-OR of all sources, so keep it pending while any source asserts the line
+	irq := n.apu.IRQ() || n.mapperIRQ != nil && n.mapperIRQ.IRQ()
+	switch {
+	case irq && *n.interrupt == cpu.NoInterrupt:
+		*n.interrupt = cpu.IRQ
+	case !irq && *n.interrupt == cpu.IRQ:
+		*n.interrupt = cpu.NoInterrupt
+	}
+
 	// 3 PPU cycles per 1 CPU cycle
 	n.ppu.Step(n.interrupt)
 	n.ppu.Step(n.interrupt)
@@ -78,6 +192,14 @@ func (n *NES) Tick() {
 // https://www.nesdev.org/wiki/CPU_memory_map
 
 func (b *NES) ReadCPU(addr uint16) uint8 {
+	v := b.readCPU(addr)
+	if b.cheats != nil {
+		v = b.cheats.Read(addr, v)
+	}
+	return v
+}
+
+func (b *NES) readCPU(addr uint16) uint8 {
 	switch {
 	case 0x0000 <= addr && addr <= 0x1FFF:
 		return b.wram[addr%0x0800]
@@ -2,6 +2,10 @@ package gorones
 
 import (
 	"bufio"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
 	"os"
 	"regexp"
 	"strconv"
@@ -11,6 +15,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/thara/gorones/cheat"
 	"github.com/thara/gorones/cpu"
 	"github.com/thara/gorones/input"
 	"github.com/thara/gorones/mapper"
@@ -158,3 +163,122 @@ func parseCPUTrace(line string) (*cpu.Trace, error) {
 
 	return &t, nil
 }
+
+var screenshotFrame = flag.Int("screenshot-frame", 30, "frame of nestest.nes to take a screenshot in Test_Screenshot")
+var screenshotPath = flag.String("screenshot", "", "path to PNG file to save the screenshot of Test_Screenshot")
+
+func Test_Screenshot(t *testing.T) {
+	nes := newTestNES(t)
+	for i := 0; i < *screenshotFrame; i++ {
+		nes.RunFrame()
+	}
+
+	img := nes.Screenshot()
+	assert.Equal(t, image.Rect(0, 0, ppu.WIDTH, ppu.HEIGHT), img.Bounds())
+
+	// the menu is drawn on the background
+	colors := map[color.Color]bool{}
+	for y := 0; y < ppu.HEIGHT; y++ {
+		for x := 0; x < ppu.WIDTH; x++ {
+			colors[img.At(x, y)] = true
+		}
+	}
+	assert.Less(t, 1, len(colors))
+
+	if *screenshotPath != "" {
+		out, err := os.Create(*screenshotPath)
+		require.NoError(t, err)
+		defer out.Close()
+		require.NoError(t, png.Encode(out, img))
+	}
+}
+
+func TestNES_SetCheats(t *testing.T) {
+	nes := newTestNES(t)
+	rom := nes.ReadCPU(0xC000)
+
+	e := cheat.NewEngine(
+		cheat.Cheat{Code: cheat.Code{Address: 0xC000, Value: rom + 1, Compare: rom, HasCompare: true}, Enabled: true, Substitute: true},
+		cheat.Cheat{Code: cheat.Code{Address: 0x0700, Value: 0x42}, Enabled: true},
+	)
+	nes.SetCheats(e)
+	assert.Equal(t, rom+1, nes.ReadCPU(0xC000))
+	nes.RunFrame()
+	assert.EqualValues(t, 0x42, nes.ReadCPU(0x0700))
+
+	nes.SetCheats(nil)
+	assert.Equal(t, rom, nes.ReadCPU(0xC000))
+}
+
+func Test_Disassemble(t *testing.T) {
+	nes := newTestNES(t)
+	nes.InitNEStest()
+
+	f, err := os.Open("testdata/nestest.log")
+	require.NoError(t, err)
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for i := 1; sc.Scan(); i++ {
+		line := sc.Text()
+		got, next := cpu.Disassemble(nes.Peek, nes.PC())
+
+		// the log annotates operands after the instruction
+		code := strings.TrimPrefix(strings.TrimSpace(line[15:48]), "*")
+		require.True(t, strings.HasPrefix(code, got), "lineno:%d %q %s", i, got, line)
+		require.Len(t, strings.Fields(line[6:15]), int(next-nes.PC()), "lineno:%d %s", i, line)
+
+		nes.Step()
+	}
+	require.NoError(t, sc.Err())
+}
+
+func TestNES_Peek(t *testing.T) {
+	nes := newTestNES(t)
+	nes.WriteCPU(0x0010, 0x42)
+	assert.EqualValues(t, 0x42, nes.Peek(0x0810))
+	assert.EqualValues(t, 0, nes.Peek(0x2002))
+	assert.Equal(t, nes.ReadCPU(0xC000), nes.Peek(0xC000))
+
+	rom := nes.Peek(0xC000)
+	nes.SetCheats(cheat.NewEngine(cheat.Cheat{Code: cheat.Code{Address: 0xC000, Value: rom + 1}, Enabled: true, Substitute: true}))
+	assert.Equal(t, rom+1, nes.ReadCPU(0xC000))
+	assert.Equal(t, rom, nes.Peek(0xC000), "not substituted by cheats")
+}
+
+type irqMapper struct {
+	mapper.MapperMock
+	irq bool
+}
+
+func (m *irqMapper) IRQ() bool { return m.irq }
+
+func TestNES_IRQ(t *testing.T) {
+	m := new(irqMapper)
+	var ctrl1, ctrl2 input.StandardController
+	nes := NewNES(m, &ctrl1, &ctrl2, new(nopFrameRenderer), new(nopAudioRenderer))
+	nes.Reset()
+
+	m.irq = true
+	nes.Tick()
+	assert.Equal(t, cpu.IRQ, *nes.interrupt)
+	m.irq = false
+	nes.Tick()
+	assert.Equal(t, cpu.NoInterrupt, *nes.interrupt)
+
+	// frame IRQ of APU
+	for i := 0; !nes.apu.IRQ(); i++ {
+		require.Less(t, i, 100000)
+		nes.Tick()
+	}
+	m.irq = true
+	nes.Tick()
+	m.irq = false
+	nes.Tick()
+	assert.Equal(t, cpu.IRQ, *nes.interrupt, "the mapper doesn't drop IRQ of APU")
+
+	// acknowledged by inhibiting frame IRQ
+	nes.WriteCPU(0x4017, 0x40)
+	nes.Tick()
+	assert.Equal(t, cpu.NoInterrupt, *nes.interrupt)
+}
@@ -0,0 +1,140 @@
+// Package nsf loads and plays NES Sound Format (.nsf/.nsfe) files.
+package nsf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// https://www.nesdev.org/wiki/NSF
+
+var (
+	nsfMagicNumber  = []byte("NESM\x1A")
+	nsfeMagicNumber = []byte("NSFE")
+)
+
+const nsfHeaderSize = 0x80
+
+// File is a loaded NSF or NSFe file.
+type File struct {
+	Songs     int
+	StartSong int // 0-based
+
+	LoadAddr uint16
+	InitAddr uint16
+	PlayAddr uint16
+
+	Title     string
+	Artist    string
+	Copyright string
+
+	// play speed in microseconds
+	NTSCSpeed uint16
+	PALSpeed  uint16
+	PAL       bool
+
+	// initial banks at $8000-$FFFF if bankswitched
+	Banks        [8]uint8
+	Bankswitched bool
+
+	// expansion sound chips, combination of mapper.NSFChip*
+	Chips uint8
+
+	Data []byte
+
+	// metadata of tracks from NSFe, whose length equals to Songs
+	Tracks []Track
+}
+
+// Track is metadata of a song.
+type Track struct {
+	Label string
+	// 0 if unknown
+	Length time.Duration
+	Fade   time.Duration
+}
+
+// Parse reads a NSF or NSFe file.
+func Parse(r io.Reader) (*File, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read NSF")
+	}
+	switch {
+	case bytes.HasPrefix(b, nsfMagicNumber):
+		return parseNSF(b)
+	case bytes.HasPrefix(b, nsfeMagicNumber):
+		return parseNSFe(b[len(nsfeMagicNumber):])
+	}
+	return nil, errors.New("invalid magic number")
+}
+
+func parseNSF(b []byte) (*File, error) {
+	if len(b) < nsfHeaderSize {
+		return nil, errors.New("too short NSF header")
+	}
+	h := b[:nsfHeaderSize]
+	f := File{
+		Songs:     int(h[0x06]),
+		StartSong: int(h[0x07]) - 1,
+		LoadAddr:  binary.LittleEndian.Uint16(h[0x08:]),
+		InitAddr:  binary.LittleEndian.Uint16(h[0x0A:]),
+		PlayAddr:  binary.LittleEndian.Uint16(h[0x0C:]),
+		Title:     cString(h[0x0E:0x2E]),
+		Artist:    cString(h[0x2E:0x4E]),
+		Copyright: cString(h[0x4E:0x6E]),
+		NTSCSpeed: binary.LittleEndian.Uint16(h[0x6E:]),
+		PALSpeed:  binary.LittleEndian.Uint16(h[0x78:]),
+		// dual region is played as NTSC
+		PAL:   h[0x7A]&0b11 == 0b01,
+		Chips: h[0x7B],
+	}
+	copy(f.Banks[:], h[0x70:0x78])
+	f.Bankswitched = f.Banks != [8]uint8{}
+
+	f.Data = b[nsfHeaderSize:]
+	// NSF2 has the program length, which is followed by metadata
+	if size := int(h[0x7D]) | int(h[0x7E])<<8 | int(h[0x7F])<<16; 2 <= h[0x05] && 0 < size && size <= len(f.Data) {
+		f.Data = f.Data[:size]
+	}
+
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (f *File) validate() error {
+	if f.Songs < 1 {
+		return errors.New("no songs")
+	}
+	if f.StartSong < 0 || f.Songs <= f.StartSong {
+		f.StartSong = 0
+	}
+	if f.LoadAddr < 0x6000 && !f.Bankswitched {
+		return errors.Errorf("invalid load address: %04x", f.LoadAddr)
+	}
+	if len(f.Data) == 0 {
+		return errors.New("no program data")
+	}
+	return nil
+}
+
+// Track returns metadata of the song (0-based), which is zero if unknown.
+func (f *File) Track(song int) Track {
+	if song < len(f.Tracks) {
+		return f.Tracks[song]
+	}
+	return Track{}
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); 0 <= i {
+		b = b[:i]
+	}
+	return string(b)
+}
@@ -0,0 +1,138 @@
+package nsf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nsfFile(load, init, play uint16, banks [8]uint8, data []byte) []byte {
+	h := make([]byte, nsfHeaderSize)
+	copy(h, nsfMagicNumber)
+	h[0x05] = 1
+	h[0x06] = 3
+	h[0x07] = 2
+	binary.LittleEndian.PutUint16(h[0x08:], load)
+	binary.LittleEndian.PutUint16(h[0x0A:], init)
+	binary.LittleEndian.PutUint16(h[0x0C:], play)
+	copy(h[0x0E:], "title")
+	copy(h[0x2E:], "artist")
+	binary.LittleEndian.PutUint16(h[0x6E:], 16639)
+	copy(h[0x70:], banks[:])
+	return append(h, data...)
+}
+
+func nsfeChunk(id string, data []byte) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint32(b, uint32(len(data)))
+	copy(b[4:], id)
+	return append(b, data...)
+}
+
+type nopAudio struct{ n int }
+
+func (a *nopAudio) Write(float32) { a.n++ }
+
+func TestParse(t *testing.T) {
+	f, err := Parse(bytes.NewReader(nsfFile(0x8000, 0x8000, 0x8003, [8]uint8{}, []byte{0x60})))
+	require.NoError(t, err)
+	assert.Equal(t, 3, f.Songs)
+	assert.Equal(t, 1, f.StartSong)
+	assert.Equal(t, "title", f.Title)
+	assert.Equal(t, "artist", f.Artist)
+	assert.False(t, f.Bankswitched)
+	assert.Equal(t, Track{}, f.Track(0))
+
+	_, err = Parse(bytes.NewReader(nsfFile(0x5000, 0x8000, 0x8003, [8]uint8{}, []byte{0x60})))
+	assert.Error(t, err)
+	_, err = Parse(bytes.NewReader([]byte("NESM\x1A")))
+	assert.Error(t, err)
+}
+
+func TestParse_nsfe(t *testing.T) {
+	info := []byte{0x00, 0x80, 0x00, 0x80, 0x03, 0x80, 0, 0, 2, 1}
+	times := make([]byte, 8)
+	binary.LittleEndian.PutUint32(times, 90000)
+	binary.LittleEndian.PutUint32(times[4:], 0xFFFFFFFF)
+	fade := make([]byte, 4)
+	binary.LittleEndian.PutUint32(fade, 5000)
+
+	b := append([]byte("NSFE"), nsfeChunk("INFO", info)...)
+	b = append(b, nsfeChunk("DATA", []byte{0x60})...)
+	b = append(b, nsfeChunk("auth", []byte("title\x00artist\x00\x00"))...)
+	b = append(b, nsfeChunk("time", times)...)
+	b = append(b, nsfeChunk("fade", fade)...)
+	b = append(b, nsfeChunk("tlbl", []byte("first\x00second\x00"))...)
+	b = append(b, nsfeChunk("xtra", []byte{1, 2, 3})...)
+	b = append(b, nsfeChunk("NEND", nil)...)
+
+	f, err := Parse(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, 2, f.Songs)
+	assert.Equal(t, 1, f.StartSong)
+	assert.Equal(t, "title", f.Title)
+	assert.Equal(t, "artist", f.Artist)
+	assert.EqualValues(t, 0x8003, f.PlayAddr)
+	assert.Equal(t, Track{Label: "first", Length: 90 * time.Second, Fade: 5 * time.Second}, f.Track(0))
+	assert.Equal(t, Track{Label: "second"}, f.Track(1))
+
+	// unknown chunk required to play
+	b = append([]byte("NSFE"), nsfeChunk("INFO", info)...)
+	b = append(b, nsfeChunk("DATA", []byte{0x60})...)
+	b = append(b, nsfeChunk("XTRA", nil)...)
+	b = append(b, nsfeChunk("NEND", nil)...)
+	_, err = Parse(bytes.NewReader(b))
+	assert.Error(t, err)
+}
+
+func TestPlayer(t *testing.T) {
+	code := []byte{
+		0x85, 0x00, // INIT: STA $00
+		0x60,       //       RTS
+		0xE6, 0x01, // PLAY: INC $01
+		0x60, //             RTS
+	}
+	f, err := Parse(bytes.NewReader(nsfFile(0x8000, 0x8000, 0x8003, [8]uint8{}, code)))
+	require.NoError(t, err)
+
+	audio := &nopAudio{}
+	p := NewPlayer(f, audio)
+	assert.Equal(t, 1, p.Song())
+	p.Run(time.Second)
+
+	assert.EqualValues(t, 1, p.wram[0x00])
+	// 60.1Hz
+	assert.EqualValues(t, 60, p.wram[0x01])
+	assert.InDelta(t, p.SampleRate(), audio.n, 1)
+
+	p.Start(2)
+	p.Run(time.Second / 2)
+	assert.EqualValues(t, 2, p.wram[0x00])
+	assert.EqualValues(t, 30, p.wram[0x01])
+}
+
+func TestPlayer_bankswitch(t *testing.T) {
+	data := bytes.Repeat([]byte{0xAA}, 0x1000)
+	data = append(data, bytes.Repeat([]byte{0xBB}, 0x1000)...)
+	data = append(data, []byte{
+		0xA9, 0x01, // INIT: LDA #$01
+		0x8D, 0xF8, 0x5F, //  STA $5FF8
+		0xAD, 0x00, 0x80, //  LDA $8000
+		0x85, 0x00, //        STA $00
+		0x60, //              RTS
+		0x60, //        PLAY: RTS
+	}...)
+	f, err := Parse(bytes.NewReader(nsfFile(0x8000, 0xF000, 0xF00B, [8]uint8{0, 1, 0, 0, 0, 0, 0, 2}, data)))
+	require.NoError(t, err)
+	assert.True(t, f.Bankswitched)
+
+	p := NewPlayer(f, &nopAudio{})
+	assert.EqualValues(t, 0xAA, p.ReadCPU(0x8000))
+	assert.EqualValues(t, 0xBB, p.ReadCPU(0x9000))
+	p.Run(time.Millisecond)
+	assert.EqualValues(t, 0xBB, p.wram[0x00])
+}
@@ -0,0 +1,127 @@
+package nsf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// https://www.nesdev.org/wiki/NSFe
+
+func parseNSFe(b []byte) (*File, error) {
+	var (
+		f             File
+		info, data    bool
+		lengths       []int32
+		fades         []int32
+		labels        []string
+		defaultPeriod = uint16(16639)
+	)
+	f.NTSCSpeed, f.PALSpeed = defaultPeriod, 19997
+
+	for {
+		if len(b) < 8 {
+			return nil, errors.New("unexpected end of NSFe")
+		}
+		size := binary.LittleEndian.Uint32(b)
+		id := string(b[4:8])
+		if uint32(len(b)-8) < size {
+			return nil, errors.Errorf("too short NSFe chunk %s", id)
+		}
+		c := b[8 : 8+size]
+		b = b[8+size:]
+
+		switch id {
+		case "INFO":
+			if len(c) < 9 {
+				return nil, errors.New("too short NSFe INFO chunk")
+			}
+			f.LoadAddr = binary.LittleEndian.Uint16(c[0:])
+			f.InitAddr = binary.LittleEndian.Uint16(c[2:])
+			f.PlayAddr = binary.LittleEndian.Uint16(c[4:])
+			f.PAL = c[6]&0b11 == 0b01
+			f.Chips = c[7]
+			f.Songs = int(c[8])
+			if 10 <= len(c) {
+				f.StartSong = int(c[9])
+			}
+			info = true
+		case "DATA":
+			f.Data = c
+			data = true
+		case "BANK":
+			copy(f.Banks[:], c)
+			f.Bankswitched = f.Banks != [8]uint8{}
+		case "RATE":
+			if 2 <= len(c) {
+				f.NTSCSpeed = binary.LittleEndian.Uint16(c)
+			}
+			if 4 <= len(c) {
+				f.PALSpeed = binary.LittleEndian.Uint16(c[2:])
+			}
+		case "auth":
+			s := cStrings(c)
+			for i, p := range []*string{&f.Title, &f.Artist, &f.Copyright} {
+				if i < len(s) {
+					*p = s[i]
+				}
+			}
+		case "time":
+			lengths = int32s(c)
+		case "fade":
+			fades = int32s(c)
+		case "tlbl":
+			labels = cStrings(c)
+		case "NEND":
+			if !info || !data {
+				return nil, errors.New("NSFe has no INFO or DATA chunk")
+			}
+			f.Tracks = tracks(f.Songs, lengths, fades, labels)
+			if err := f.validate(); err != nil {
+				return nil, err
+			}
+			return &f, nil
+		default:
+			// chunks beginning with upper case are required to play
+			if 'A' <= id[0] && id[0] <= 'Z' {
+				return nil, errors.Errorf("unsupported NSFe chunk %s", id)
+			}
+		}
+	}
+}
+
+func tracks(songs int, lengths, fades []int32, labels []string) []Track {
+	ts := make([]Track, songs)
+	for i := range ts {
+		// negative values mean the default
+		if i < len(lengths) && 0 <= lengths[i] {
+			ts[i].Length = time.Duration(lengths[i]) * time.Millisecond
+		}
+		if i < len(fades) && 0 <= fades[i] {
+			ts[i].Fade = time.Duration(fades[i]) * time.Millisecond
+		}
+		if i < len(labels) {
+			ts[i].Label = labels[i]
+		}
+	}
+	return ts
+}
+
+func int32s(b []byte) []int32 {
+	v := make([]int32, len(b)/4)
+	for i := range v {
+		v[i] = int32(binary.LittleEndian.Uint32(b[i*4:]))
+	}
+	return v
+}
+
+func cStrings(b []byte) []string {
+	b = bytes.TrimSuffix(b, []byte{0})
+	var s []string
+	for _, p := range bytes.Split(b, []byte{0}) {
+		s = append(s, string(p))
+	}
+	return s
+}
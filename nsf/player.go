@@ -0,0 +1,212 @@
+package nsf
+
+import (
+	"time"
+
+	"github.com/thara/gorones/apu"
+	"github.com/thara/gorones/cpu"
+	"github.com/thara/gorones/mapper"
+)
+
+// https://www.nesdev.org/wiki/NSF#Initializing_a_tune
+
+// address of the idle loop `JMP $3000`, where INIT/PLAY routines return to
+const driverAddr = 0x3000
+
+var driver = [3]uint8{0x4C, driverAddr & 0xFF, driverAddr >> 8}
+
+// Player plays songs in NSF by calling INIT/PLAY routines on CPU and APU without PPU.
+type Player struct {
+	file    *File
+	audio   apu.AudioRenderer
+	filters []apu.Filter
+
+	cpu   *cpu.CPU
+	apu   *apu.APU
+	board mapper.Mapper
+	wram  [0x0800]uint8
+
+	prg   []byte
+	banks [10]uint8
+
+	song   int
+	cycles uint64
+
+	// CPU cycles between PLAY calls
+	playPeriod  float64
+	playTimer   float64
+	playPending bool
+}
+
+// NewPlayer creates a player of f which writes samples into audio.
+func NewPlayer(f *File, audio apu.AudioRenderer) *Player {
+	p := &Player{file: f, audio: audio, filters: apu.NESFilters}
+	p.prg, p.banks = f.image()
+
+	speed, def := f.NTSCSpeed, 16639
+	if f.PAL {
+		speed, def = f.PALSpeed, 19997
+	}
+	if speed == 0 {
+		speed = uint16(def)
+	}
+	p.playPeriod = float64(speed) * apu.CPUClock / 1e6
+
+	p.Start(f.StartSong)
+	return p
+}
+
+// image returns PRG of f and initial banks at $6000-$FFFF.
+func (f *File) image() ([]byte, [10]uint8) {
+	var banks [10]uint8
+	if !f.Bankswitched {
+		// $6000-$FFFF without bank switching
+		prg := make([]byte, 0xA000)
+		copy(prg[int(f.LoadAddr)-0x6000:], f.Data)
+		for i := range banks {
+			banks[i] = uint8(i)
+		}
+		return prg, banks
+	}
+
+	// padded by the lower 12 bits of load address
+	pad := int(f.LoadAddr & 0x0FFF)
+	size := (pad + len(f.Data) + 0x0FFF) &^ 0x0FFF
+	prg := make([]byte, size)
+	copy(prg[pad:], f.Data)
+
+	copy(banks[2:], f.Banks[:])
+	if f.Chips&mapper.NSFChipFDS != 0 {
+		// FDS loads the last 2 banks into $6000-$7FFF
+		banks[0], banks[1] = f.Banks[6], f.Banks[7]
+	}
+	return prg, banks
+}
+
+// SetFilters changes the filter chain applied to the audio output. No filters disables filtering.
+func (p *Player) SetFilters(filters ...apu.Filter) {
+	p.filters = filters
+	p.apu.SetFilters(filters...)
+}
+
+// Song returns the current song, 0-based.
+func (p *Player) Song() int { return p.song }
+
+// Start resets all sound chips and calls INIT routine for the song, 0-based.
+func (p *Player) Start(song int) {
+	p.song = song
+	p.wram = [0x0800]uint8{}
+
+	p.board = mapper.NewNSFBoard(p.prg, p.banks, p.file.Chips)
+	if !p.file.Bankswitched && p.file.Chips&mapper.NSFChipFDS == 0 {
+		// RAM at $6000-$7FFF
+		for i := 0; i < 0x2000; i++ {
+			p.board.Write(0x6000+uint16(i), p.prg[i])
+		}
+	}
+
+	p.apu = apu.New(p.audio)
+	p.apu.SetFilters(p.filters...)
+	if e, ok := p.board.(apu.ExpansionAudio); ok {
+		p.apu.SetExpansionAudio(e)
+	}
+
+	p.cpu = cpu.New(p, p)
+	p.cpu.PowerOn()
+	for addr := uint16(0x4000); addr <= 0x4013; addr++ {
+		p.apu.Write(addr, 0x00)
+	}
+	p.apu.Write(0x4015, 0x0F)
+	p.apu.Write(0x4017, 0x40)
+
+	p.cycles = 0
+	p.playTimer = 0
+	p.playPending = false
+	p.cpu.A = uint8(song)
+	p.cpu.X = 0
+	if p.file.PAL {
+		p.cpu.X = 1
+	}
+	p.call(p.file.InitAddr)
+}
+
+// call jumps to the routine which returns to the idle loop by RTS.
+func (p *Player) call(addr uint16) {
+	ret := uint16(driverAddr - 1)
+	p.wram[0x100|uint16(p.cpu.S)] = uint8(ret >> 8)
+	p.cpu.S--
+	p.wram[0x100|uint16(p.cpu.S)] = uint8(ret)
+	p.cpu.S--
+	p.cpu.PC = addr
+}
+
+// Step emulates 1 CPU step, calling PLAY routine if it's time.
+func (p *Player) Step() {
+	if p.playPending && p.cpu.PC == driverAddr {
+		p.playPending = false
+		p.call(p.file.PlayAddr)
+	}
+	p.cpu.Step(nil)
+}
+
+// Run emulates the song for the duration.
+func (p *Player) Run(d time.Duration) {
+	end := p.cycles + uint64(d.Seconds()*apu.CPUClock)
+	for p.cycles < end {
+		p.Step()
+	}
+}
+
+// Elapsed returns the played time of the current song.
+func (p *Player) Elapsed() time.Duration {
+	return time.Duration(float64(p.cycles) / apu.CPUClock * float64(time.Second))
+}
+
+// SampleRate returns the number of samples written into AudioRenderer per second.
+func (p *Player) SampleRate() float64 {
+	return p.apu.SampleRate()
+}
+
+func (p *Player) Tick() {
+	p.cycles++
+	p.apu.Step(p)
+	if t, ok := p.board.(cpu.Ticker); ok {
+		t.Tick()
+	}
+
+	p.playTimer++
+	if p.playPeriod <= p.playTimer {
+		p.playTimer -= p.playPeriod
+		p.playPending = true
+	}
+}
+
+func (p *Player) ReadCPU(addr uint16) uint8 {
+	switch {
+	case addr <= 0x1FFF:
+		return p.wram[addr%0x0800]
+	case driverAddr <= addr && addr < driverAddr+uint16(len(driver)):
+		return driver[addr-driverAddr]
+	case addr == 0x4015:
+		return p.apu.Read(addr)
+	case 0x4020 <= addr:
+		return p.board.Read(addr)
+	}
+	return 0
+}
+
+func (p *Player) WriteCPU(addr uint16, value uint8) {
+	switch {
+	case addr <= 0x1FFF:
+		p.wram[addr%0x0800] = value
+	case 0x4000 <= addr && addr <= 0x4013, addr == 0x4015, addr == 0x4017:
+		p.apu.Write(addr, value)
+	case 0x4020 <= addr:
+		p.board.Write(addr, value)
+	}
+}
+
+// Read reads memory for DMC.
+func (p *Player) Read(addr uint16) uint8 {
+	return p.ReadCPU(addr)
+}
@@ -0,0 +1,174 @@
+// Package opll emulates the FM synthesis of YM2413 (OPLL) family, as a variant embedded in Konami VRC7.
+//
+// https://www.nesdev.org/wiki/VRC7_audio
+package opll
+
+// SampleRate is the output rate of OPLL driven by a 3.58MHz clock (= NES CPU clock / 36)
+const SampleRate = 3_579_545.0 / 72
+
+// VRC7 has 6 channels and no rhythm mode
+const channelCount = 6
+
+type channel struct {
+	fnum       uint16 // 9 bits
+	block      uint8  // octave
+	key        bool
+	sustain    bool
+	instrument uint8
+	volume     uint8 // attenuation in 3dB units
+
+	mod, car slot
+}
+
+// OPLL is a YM2413-derived FM synthesizer.
+type OPLL struct {
+	addr uint8
+
+	custom   [8]uint8 // custom instrument: $00-$07
+	channels [channelCount]channel
+
+	// counters for envelope and LFOs
+	counter uint32
+
+	output float64
+}
+
+// New returns OPLL with VRC7 built-in instruments.
+func New() *OPLL {
+	var o OPLL
+	o.Reset()
+	return &o
+}
+
+// Reset silences all channels and clears registers.
+func (o *OPLL) Reset() {
+	*o = OPLL{}
+	for i := range o.channels {
+		o.channels[i].mod.attenuation = maxAttenuation
+		o.channels[i].car.attenuation = maxAttenuation
+	}
+}
+
+// WriteAddress selects the register to be written by WriteData.
+func (o *OPLL) WriteAddress(value uint8) {
+	o.addr = value
+}
+
+// WriteData writes the register selected by WriteAddress.
+func (o *OPLL) WriteData(value uint8) {
+	o.Write(o.addr, value)
+}
+
+// Write writes a register.
+//
+//	$00-$07: custom instrument
+//	$10-$15: fnum low 8 bits
+//	$20-$25: fnum bit 8 (bit 0), block (bits 1-3), key on (bit 4), sustain (bit 5)
+//	$30-$35: instrument (bits 4-7), volume (bits 0-3)
+func (o *OPLL) Write(reg, value uint8) {
+	if reg <= 0x07 {
+		o.custom[reg] = value
+		return
+	}
+	n := reg & 0x0F
+	if channelCount <= n {
+		return
+	}
+	ch := &o.channels[n]
+	switch reg & 0xF0 {
+	case 0x10:
+		ch.fnum = ch.fnum&0x100 | uint16(value)
+	case 0x20:
+		ch.fnum = ch.fnum&0xFF | uint16(value&1)<<8
+		ch.block = (value >> 1) & 0b111
+		ch.sustain = value&0x20 != 0
+
+		key := value&0x10 != 0
+		if key && !ch.key {
+			ch.mod.keyOn()
+			ch.car.keyOn()
+		} else if !key && ch.key {
+			ch.mod.keyOff()
+			ch.car.keyOff()
+		}
+		ch.key = key
+	case 0x30:
+		ch.instrument = value >> 4
+		ch.volume = value & 0x0F
+	}
+}
+
+func (o *OPLL) patch(ch *channel) patch {
+	if ch.instrument == 0 {
+		return decodePatch(o.custom)
+	}
+	return decodePatch(VRC7Patches[ch.instrument])
+}
+
+// tremolo depth 4.8dB at 3.7Hz
+const tremoloSteps = 210
+
+func (o *OPLL) tremolo() int {
+	// triangle wave in 0.375dB units: 0 ..= 13
+	step := int(o.counter>>6) % tremoloSteps
+	if tremoloSteps/2 <= step {
+		step = tremoloSteps - 1 - step
+	}
+	return step * 13 / (tremoloSteps/2 - 1)
+}
+
+// vibrato at 6.1Hz
+func (o *OPLL) vibratoPhase() uint32 {
+	return (o.counter >> 10) & 7
+}
+
+// Clock generates one sample, which is called at SampleRate.
+func (o *OPLL) Clock() {
+	o.counter++
+
+	tremolo := o.tremolo()
+	vib := o.vibratoPhase()
+
+	var out float64
+	for i := range o.channels {
+		ch := &o.channels[i]
+		p := o.patch(ch)
+
+		ch.mod.clockPhase(&p.mod, ch.fnum, ch.block, vib)
+		ch.car.clockPhase(&p.car, ch.fnum, ch.block, vib)
+		ch.mod.clockEnvelope(&p.mod, ch, o.counter)
+		ch.car.clockEnvelope(&p.car, ch, o.counter)
+
+		// modulator with feedback
+		var fb int
+		if 0 < p.fb {
+			// full scale feedback shifts phase by π/16 (FB=1) ..= 4π (FB=7); π/16 is 32 in sine table units
+			scale := 16 << p.fb
+			fb = int((ch.mod.out[0] + ch.mod.out[1]) / 2 * float64(scale))
+		}
+		att := int(p.tl)*2 + keyScaleLevel(&p.mod, ch.fnum, ch.block)
+		if p.mod.am {
+			att += tremolo
+		}
+		m := ch.mod.output(&p.mod, fb, att)
+		ch.mod.out[1] = ch.mod.out[0]
+		ch.mod.out[0] = m
+
+		// carrier modulated by the modulator; full scale shifts phase by 8π
+		att = int(ch.volume)*8 + keyScaleLevel(&p.car, ch.fnum, ch.block)
+		if p.car.am {
+			att += tremolo
+		}
+		c := ch.car.output(&p.car, int(m*4096), att)
+		ch.car.out[1] = ch.car.out[0]
+		ch.car.out[0] = c
+
+		out += c
+	}
+	o.output = out
+}
+
+// Output returns the last generated sample, which is the sum of all channels (-6.0 ..= 6.0).
+func (o *OPLL) Output() float32 {
+	return float32(o.output)
+}
@@ -0,0 +1,135 @@
+package opll
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sine wave by carrier only; the modulator never attacks
+var sinePatch = []uint8{0x21, 0x21, 0x3F, 0x00, 0x00, 0xF0, 0x0F, 0x0F}
+
+func writeAll(o *OPLL, regs ...[2]uint8) {
+	for _, r := range regs {
+		o.WriteAddress(r[0])
+		o.WriteData(r[1])
+	}
+}
+
+func writePatch(o *OPLL, p []uint8) {
+	for i, v := range p {
+		o.Write(uint8(i), v)
+	}
+}
+
+// run clocks n samples, and returns the peak amplitude and the number of rising zero crossings.
+func run(o *OPLL, n int) (peak float64, crossings int) {
+	prev := float64(o.Output())
+	for i := 0; i < n; i++ {
+		o.Clock()
+		v := float64(o.Output())
+		peak = math.Max(peak, math.Abs(v))
+		if prev <= 0 && 0 < v {
+			crossings++
+		}
+		prev = v
+	}
+	return
+}
+
+func Test_decodePatch(t *testing.T) {
+	p := decodePatch(VRC7Patches[1]) // Buzzy Bell: 03 21 05 06 E8 81 42 27
+
+	assert.Equal(t, operatorPatch{mult: 3, ar: 0xE, dr: 0x8, sl: 0x4, rr: 0x2}, p.mod)
+	assert.Equal(t, operatorPatch{sustained: true, mult: 1, ar: 0x8, dr: 0x1, sl: 0x2, rr: 0x7}, p.car)
+	assert.EqualValues(t, 0x05, p.tl)
+	assert.EqualValues(t, 0x06, p.fb)
+}
+
+func TestOPLL_silentWithoutKeyOn(t *testing.T) {
+	o := New()
+	writePatch(o, sinePatch)
+	writeAll(o, [2]uint8{0x10, 0x22}, [2]uint8{0x20, 0x09}, [2]uint8{0x30, 0x00})
+
+	peak, _ := run(o, 1000)
+	assert.Zero(t, peak)
+}
+
+func TestOPLL_frequency(t *testing.T) {
+	o := New()
+	writePatch(o, sinePatch)
+	// fnum 290, block 4: 290 * 49716 * 2^(4-1) / 2^18 = 440Hz
+	writeAll(o, [2]uint8{0x10, 0x22}, [2]uint8{0x30, 0x00}, [2]uint8{0x20, 0x19})
+
+	peak, crossings := run(o, int(math.Round(SampleRate)))
+	assert.InDelta(t, 1.0, peak, 0.01)
+	assert.InDelta(t, 440, crossings, 2)
+
+	// one octave higher
+	o.Write(0x20, 0x1B)
+	_, crossings = run(o, int(math.Round(SampleRate)))
+	assert.InDelta(t, 880, crossings, 2)
+}
+
+func TestOPLL_volume(t *testing.T) {
+	o := New()
+	writePatch(o, sinePatch)
+	writeAll(o, [2]uint8{0x10, 0x22}, [2]uint8{0x30, 0x0F}, [2]uint8{0x20, 0x19})
+
+	// 15 * 3dB = 45dB
+	peak, _ := run(o, 1000)
+	assert.InDelta(t, math.Pow(10, -45.0/20), peak, 0.0005)
+}
+
+func TestOPLL_keyOff(t *testing.T) {
+	o := New()
+	writePatch(o, sinePatch)
+	writeAll(o, [2]uint8{0x10, 0x22}, [2]uint8{0x30, 0x00}, [2]uint8{0x20, 0x19})
+	run(o, 1000)
+
+	// release rate 15
+	o.Write(0x20, 0x09)
+	run(o, 1000)
+	peak, _ := run(o, 1000)
+	assert.Zero(t, peak)
+	assert.Equal(t, envelopeRelease, o.channels[0].car.state)
+}
+
+func TestOPLL_builtinInstruments(t *testing.T) {
+	outputs := func(instrument uint8) []float32 {
+		o := New()
+		writeAll(o, [2]uint8{0x10, 0x22}, [2]uint8{0x30, instrument << 4}, [2]uint8{0x20, 0x19})
+		out := make([]float32, 2000)
+		for i := range out {
+			o.Clock()
+			out[i] = o.Output()
+		}
+		return out
+	}
+
+	bell := outputs(1)
+	guitar := outputs(2)
+	assert.NotEqual(t, make([]float32, len(bell)), bell)
+	assert.NotEqual(t, bell, guitar)
+
+	// the custom instrument is silent until defined
+	assert.Equal(t, make([]float32, 2000), outputs(0))
+}
+
+func TestOPLL_channels(t *testing.T) {
+	o := New()
+	writePatch(o, sinePatch)
+	for ch := uint8(0); ch < 6; ch++ {
+		writeAll(o, [2]uint8{0x10 + ch, 0x22}, [2]uint8{0x30 + ch, 0x00}, [2]uint8{0x20 + ch, 0x19})
+	}
+	// no rhythm mode and no more channels
+	writeAll(o, [2]uint8{0x0E, 0x20}, [2]uint8{0x16, 0x22}, [2]uint8{0x26, 0x19})
+
+	peak, _ := run(o, 1000)
+	assert.InDelta(t, 6.0, peak, 0.05)
+
+	o.Reset()
+	peak, _ = run(o, 1000)
+	assert.Zero(t, peak)
+}
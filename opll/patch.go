@@ -0,0 +1,80 @@
+package opll
+
+// https://www.nesdev.org/wiki/VRC7_audio#Instruments
+
+// VRC7Patches are the built-in instruments of VRC7, dumped from the die by Nuke.YKT.
+//
+// Instrument 0 is the custom instrument defined by registers $00-$07.
+var VRC7Patches = [16][8]uint8{
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, // custom
+	{0x03, 0x21, 0x05, 0x06, 0xE8, 0x81, 0x42, 0x27}, // Buzzy Bell
+	{0x13, 0x41, 0x14, 0x0D, 0xD8, 0xF6, 0x23, 0x12}, // Guitar
+	{0x11, 0x11, 0x08, 0x08, 0xFA, 0xB2, 0x20, 0x12}, // Wurly
+	{0x31, 0x61, 0x0C, 0x07, 0xA8, 0x64, 0x61, 0x27}, // Flute
+	{0x32, 0x21, 0x1E, 0x06, 0xE1, 0x76, 0x01, 0x28}, // Clarinet
+	{0x02, 0x01, 0x06, 0x00, 0xA3, 0xE2, 0xF4, 0xF4}, // Synth
+	{0x21, 0x61, 0x1D, 0x07, 0x82, 0x81, 0x11, 0x07}, // Trumpet
+	{0x23, 0x21, 0x22, 0x17, 0xA2, 0x72, 0x01, 0x17}, // Organ
+	{0x35, 0x11, 0x25, 0x00, 0x40, 0x73, 0x72, 0x01}, // Bells
+	{0xB5, 0x01, 0x0F, 0x0F, 0xA8, 0xA5, 0x51, 0x02}, // Vibes
+	{0x17, 0xC1, 0x24, 0x07, 0xF8, 0xF8, 0x22, 0x12}, // Vibraphone
+	{0x71, 0x23, 0x11, 0x06, 0x65, 0x74, 0x18, 0x16}, // Tutti
+	{0x01, 0x02, 0xD3, 0x05, 0xC9, 0x95, 0x03, 0x02}, // Fretless
+	{0x61, 0x63, 0x0C, 0x00, 0x94, 0xC0, 0x33, 0xF6}, // Synth Bass
+	{0x21, 0x72, 0x0D, 0x00, 0xC1, 0xD5, 0x56, 0x06}, // Sweep
+}
+
+// operatorPatch is the parameters of the modulator or carrier.
+type operatorPatch struct {
+	am        bool  // tremolo
+	vib       bool  // vibrato
+	sustained bool  // EG type: the envelope holds at sustain level while key on
+	ksr       bool  // key scale of rate
+	mult      uint8 // frequency multiplier
+	ksl       uint8 // key scale level
+	rectified bool  // half-wave rectified sine
+
+	ar, dr, sl, rr uint8
+}
+
+type patch struct {
+	mod, car operatorPatch
+
+	tl uint8 // total level of modulator
+	fb uint8 // feedback of modulator
+}
+
+// decodePatch decodes 8 bytes of an instrument
+//
+//	$00 (modulator), $01 (carrier): AM VIB EG KSR MULT(4)
+//	$02: modulator KSL(2) TL(6)
+//	$03: carrier KSL(2) - DC DM FB(3)
+//	$04 (modulator), $05 (carrier): AR(4) DR(4)
+//	$06 (modulator), $07 (carrier): SL(4) RR(4)
+func decodePatch(b [8]uint8) patch {
+	op := func(flags, ar, sl uint8) operatorPatch {
+		return operatorPatch{
+			am:        flags&0x80 != 0,
+			vib:       flags&0x40 != 0,
+			sustained: flags&0x20 != 0,
+			ksr:       flags&0x10 != 0,
+			mult:      flags & 0x0F,
+			ar:        ar >> 4,
+			dr:        ar & 0x0F,
+			sl:        sl >> 4,
+			rr:        sl & 0x0F,
+		}
+	}
+
+	p := patch{
+		mod: op(b[0], b[4], b[6]),
+		car: op(b[1], b[5], b[7]),
+		tl:  b[2] & 0x3F,
+		fb:  b[3] & 0x07,
+	}
+	p.mod.ksl = b[2] >> 6
+	p.car.ksl = b[3] >> 6
+	p.mod.rectified = b[3]&0x08 != 0
+	p.car.rectified = b[3]&0x10 != 0
+	return p
+}
@@ -0,0 +1,187 @@
+package opll
+
+import (
+	"math"
+)
+
+// envelope attenuation is in 0.375dB units
+const (
+	attenuationBits = 7
+	maxAttenuation  = 1<<attenuationBits - 1
+)
+
+type envelopeState uint8
+
+const (
+	envelopeRelease envelopeState = iota // also idle after the sound decays
+	envelopeAttack
+	envelopeDecay
+	envelopeSustain
+)
+
+// slot is an operator, which is a sine wave oscillator with an envelope.
+type slot struct {
+	phase uint32 // 19 bits; the upper 10 bits are the sine table index
+
+	state       envelopeState
+	attenuation int
+
+	// last two outputs for feedback
+	out [2]float64
+}
+
+func (s *slot) keyOn() {
+	s.state = envelopeAttack
+	s.phase = 0
+}
+
+func (s *slot) keyOff() {
+	s.state = envelopeRelease
+}
+
+// multipliers doubled to be integers: 1/2, 1, 2, 3 ... 10, 10, 12, 12, 15, 15
+var multTable = [16]uint32{1, 2, 4, 6, 8, 10, 12, 14, 16, 18, 20, 20, 24, 24, 30, 30}
+
+// vibrato deviation in fnum units, indexed by the upper 3 bits of fnum and LFO phase
+var vibratoTable = [8][8]int32{
+	{0, 0, 0, 0, 0, 0, 0, 0},
+	{0, 0, 1, 0, 0, 0, -1, 0},
+	{0, 1, 2, 1, 0, -1, -2, -1},
+	{0, 1, 3, 1, 0, -1, -3, -1},
+	{0, 2, 4, 2, 0, -2, -4, -2},
+	{0, 2, 5, 2, 0, -2, -5, -2},
+	{0, 3, 6, 3, 0, -3, -6, -3},
+	{0, 3, 7, 3, 0, -3, -7, -3},
+}
+
+func (s *slot) clockPhase(p *operatorPatch, fnum uint16, block uint8, vibPhase uint32) {
+	f := int32(fnum)
+	if p.vib {
+		f += vibratoTable[fnum>>6][vibPhase]
+	}
+	inc := uint32(f) << block * multTable[p.mult] / 2
+	s.phase = (s.phase + inc) & 0x7FFFF
+}
+
+// envelopeSteps are increments of attenuation, indexed by lower 2 bits of rate and the EG counter
+var envelopeSteps = [4][8]int{
+	{0, 1, 0, 1, 0, 1, 0, 1},
+	{0, 1, 0, 1, 1, 1, 0, 1},
+	{0, 1, 1, 1, 0, 1, 1, 1},
+	{0, 1, 1, 1, 1, 1, 1, 1},
+}
+
+func envelopeIncrement(rate uint8, counter uint32) int {
+	if rate == 0 {
+		return 0
+	}
+	hi, lo := rate>>2, rate&3
+	if hi < 13 {
+		shift := 13 - hi
+		if counter&(1<<shift-1) != 0 {
+			return 0
+		}
+		return envelopeSteps[lo][(counter>>shift)&7]
+	}
+	return envelopeSteps[lo][counter&7] << (hi - 13)
+}
+
+// rate returns the effective rate (0 ..= 63) from a 4-bit rate and the key scale
+func rate(r uint8, p *operatorPatch, fnum uint16, block uint8) uint8 {
+	if r == 0 {
+		return 0
+	}
+	rks := block<<1 | uint8(fnum>>8)
+	if !p.ksr {
+		rks >>= 2
+	}
+	v := r*4 + rks
+	if 63 < v {
+		v = 63
+	}
+	return v
+}
+
+func (s *slot) clockEnvelope(p *operatorPatch, ch *channel, counter uint32) {
+	switch s.state {
+	case envelopeAttack:
+		r := rate(p.ar, p, ch.fnum, ch.block)
+		if 60 <= r {
+			s.attenuation = 0
+		} else if inc := envelopeIncrement(r, counter); 0 < inc {
+			s.attenuation -= (s.attenuation*inc)>>3 + 1
+		}
+		if s.attenuation <= 0 {
+			s.attenuation = 0
+			s.state = envelopeDecay
+		}
+	case envelopeDecay:
+		s.attenuation += envelopeIncrement(rate(p.dr, p, ch.fnum, ch.block), counter)
+		// sustain level is in 3dB units
+		if int(p.sl)*8 <= s.attenuation {
+			s.state = envelopeSustain
+		}
+	case envelopeSustain:
+		// percussive tones keep decaying at release rate
+		if !p.sustained {
+			s.attenuation += envelopeIncrement(rate(p.rr, p, ch.fnum, ch.block), counter)
+		}
+	case envelopeRelease:
+		var rr uint8
+		switch {
+		case ch.sustain:
+			rr = 5
+		case p.sustained:
+			rr = p.rr
+		default:
+			rr = 7
+		}
+		s.attenuation += envelopeIncrement(rate(rr, p, ch.fnum, ch.block), counter)
+	}
+	if maxAttenuation < s.attenuation {
+		s.attenuation = maxAttenuation
+	}
+}
+
+// key scale level in 0.375dB units at 6dB/octave, indexed by the upper 4 bits of fnum
+var kslTable = [16]int{0, 48, 64, 74, 80, 86, 90, 94, 96, 100, 102, 104, 106, 108, 110, 112}
+
+func keyScaleLevel(p *operatorPatch, fnum uint16, block uint8) int {
+	if p.ksl == 0 {
+		return 0
+	}
+	v := kslTable[fnum>>5] - 16*(7-int(block))
+	if v <= 0 {
+		return 0
+	}
+	// KSL 1: 1.5dB/oct, 2: 3dB/oct, 3: 6dB/oct
+	return v >> (3 - p.ksl)
+}
+
+var (
+	sineTable [1024]float64
+	// amplitude for attenuation in 0.375dB units
+	amplitudeTable [512]float64
+)
+
+func init() {
+	for i := range sineTable {
+		sineTable[i] = math.Sin(2 * math.Pi * (float64(i) + 0.5) / 1024)
+	}
+	for i := range amplitudeTable {
+		amplitudeTable[i] = math.Pow(10, -float64(i)*0.375/20)
+	}
+}
+
+// output computes the operator output (-1.0 ..= 1.0) from a phase offset in sine table units.
+func (s *slot) output(p *operatorPatch, modulation int, attenuation int) float64 {
+	attenuation += s.attenuation
+	if maxAttenuation <= s.attenuation || len(amplitudeTable) <= attenuation {
+		return 0
+	}
+	i := (int(s.phase>>9) + modulation) & 1023
+	if p.rectified && 512 <= i {
+		return 0
+	}
+	return sineTable[i] * amplitudeTable[attenuation]
+}
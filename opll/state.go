@@ -0,0 +1,30 @@
+package opll
+
+import "github.com/thara/gorones/state"
+
+func (o *OPLL) Serialize(s *state.Serializer) {
+	s.Section("OPLL")
+	s.Uint8(&o.addr)
+	s.Data(o.custom[:])
+	for i := range o.channels {
+		c := &o.channels[i]
+		s.Uint16(&c.fnum)
+		s.Uint8(&c.block)
+		s.Bool(&c.key)
+		s.Bool(&c.sustain)
+		s.Uint8(&c.instrument)
+		s.Uint8(&c.volume)
+		c.mod.serialize(s)
+		c.car.serialize(s)
+	}
+	s.Uint32(&o.counter)
+	s.Float64(&o.output)
+}
+
+func (sl *slot) serialize(s *state.Serializer) {
+	s.Uint32(&sl.phase)
+	s.Uint8((*uint8)(&sl.state))
+	s.Int(&sl.attenuation)
+	s.Float64(&sl.out[0])
+	s.Float64(&sl.out[1])
+}
@@ -0,0 +1,141 @@
+package patch
+
+import (
+	"hash/crc32"
+
+	"github.com/pkg/errors"
+)
+
+// https://github.com/blakesmith/rombp/blob/master/docs/bps_spec.md
+
+var bpsMagic = []byte("BPS1")
+
+const (
+	bpsSourceRead = iota
+	bpsTargetRead
+	bpsSourceCopy
+	bpsTargetCopy
+)
+
+// ApplyBPS applies a BPS patch to src, and returns the patched copy.
+func ApplyBPS(src, p []byte) ([]byte, error) {
+	if len(p) < len(bpsMagic)+12 {
+		return nil, errors.New("too short BPS patch")
+	}
+	srcCRC, dstCRC, patchCRC := checksums(p)
+	if crc32.ChecksumIEEE(p[:len(p)-4]) != patchCRC {
+		return nil, ErrPatchChecksum
+	}
+
+	r := reader{b: p[:len(p)-12], pos: len(bpsMagic)}
+	srcSize, err := r.number()
+	if err != nil {
+		return nil, err
+	}
+	dstSize, err := r.number()
+	if err != nil {
+		return nil, err
+	}
+	if MaxTargetSize < dstSize {
+		return nil, ErrTargetTooLarge
+	}
+	metadataSize, err := r.number()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.bytes(metadataSize); err != nil {
+		return nil, err
+	}
+	if uint64(len(src)) != srcSize || crc32.ChecksumIEEE(src) != srcCRC {
+		return nil, ErrSourceChecksum
+	}
+
+	out := make([]byte, 0, dstSize)
+	var srcPos, dstPos int
+	for r.pos < len(r.b) {
+		data, err := r.number()
+		if err != nil {
+			return nil, err
+		}
+		n := int(data>>2) + 1
+		if dstSize < uint64(len(out)+n) {
+			return nil, errors.New("BPS action exceeds the target size")
+		}
+
+		switch data & 0b11 {
+		case bpsSourceRead:
+			if len(src) < len(out)+n {
+				return nil, errors.New("BPS source read out of range")
+			}
+			out = append(out, src[len(out):len(out)+n]...)
+		case bpsTargetRead:
+			b, err := r.bytes(uint64(n))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, b...)
+		case bpsSourceCopy, bpsTargetCopy:
+			v, err := r.number()
+			if err != nil {
+				return nil, err
+			}
+			offset := int(v >> 1)
+			if v&1 != 0 {
+				offset = -offset
+			}
+			if data&0b11 == bpsSourceCopy {
+				srcPos += offset
+				if srcPos < 0 || len(src) < srcPos+n {
+					return nil, errors.New("BPS source copy out of range")
+				}
+				out = append(out, src[srcPos:srcPos+n]...)
+				srcPos += n
+			} else {
+				dstPos += offset
+				if dstPos < 0 || len(out) <= dstPos {
+					return nil, errors.New("BPS target copy out of range")
+				}
+				// may overlap, so copy byte by byte
+				for i := 0; i < n; i++ {
+					out = append(out, out[dstPos])
+					dstPos++
+				}
+			}
+		}
+	}
+
+	if uint64(len(out)) != dstSize || crc32.ChecksumIEEE(out) != dstCRC {
+		return nil, ErrTargetChecksum
+	}
+	return out, nil
+}
+
+// CreateBPS creates a BPS patch which converts src into dst.
+//
+// It emits only SourceRead and TargetRead actions, which is simple and good enough for ROM hacks changing bytes in place.
+func CreateBPS(src, dst []byte) []byte {
+	out := append([]byte(nil), bpsMagic...)
+	out = appendNumber(out, uint64(len(src)))
+	out = appendNumber(out, uint64(len(dst)))
+	out = appendNumber(out, 0) // no metadata
+
+	same := func(i int) bool {
+		return i < len(src) && src[i] == dst[i]
+	}
+	for i := 0; i < len(dst); {
+		start := i
+		if same(i) {
+			for i < len(dst) && same(i) {
+				i++
+			}
+			out = appendNumber(out, uint64(i-start-1)<<2|bpsSourceRead)
+		} else {
+			for i < len(dst) && !same(i) {
+				i++
+			}
+			out = appendNumber(out, uint64(i-start-1)<<2|bpsTargetRead)
+			out = append(out, dst[start:i]...)
+		}
+	}
+	return appendChecksums(out, src, dst)
+}
@@ -0,0 +1,70 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBPS(t *testing.T) {
+	src := []byte("The quick brown fox jumps over the lazy dog")
+	for _, dst := range [][]byte{
+		[]byte("The quick brown cat jumps over the lazy dog"),
+		[]byte("The quick brown fox"),
+		[]byte("The quick brown fox jumps over the lazy dog!!"),
+		src,
+	} {
+		p := CreateBPS(src, dst)
+		got, err := ApplyBPS(src, p)
+		require.NoError(t, err)
+		assert.Equal(t, dst, got)
+	}
+
+	p := CreateBPS(src, []byte("The quick brown cat"))
+	_, err := ApplyBPS([]byte("another source"), p)
+	assert.ErrorIs(t, err, ErrSourceChecksum)
+
+	p[len(bpsMagic)+3] ^= 0xFF
+	_, err = ApplyBPS(src, p)
+	assert.ErrorIs(t, err, ErrPatchChecksum)
+
+	p = append([]byte(nil), bpsMagic...)
+	p = appendNumber(p, uint64(len(src)))
+	p = appendNumber(p, MaxTargetSize+1)
+	p = appendNumber(p, 0)
+	p = appendChecksums(p, src, nil)
+	_, err = ApplyBPS(src, p)
+	assert.ErrorIs(t, err, ErrTargetTooLarge)
+}
+
+func TestApplyBPS_copy(t *testing.T) {
+	src := []byte("abcdef")
+	dst := []byte("defabcxxxxabc")
+
+	p := append([]byte(nil), bpsMagic...)
+	p = appendNumber(p, uint64(len(src)))
+	p = appendNumber(p, uint64(len(dst)))
+	p = appendNumber(p, 4)
+	p = append(p, "meta"...)
+	// SourceCopy "def" from +3
+	p = appendNumber(p, 2<<2|bpsSourceCopy)
+	p = appendNumber(p, 3<<1)
+	// SourceCopy "abc" from -6
+	p = appendNumber(p, 2<<2|bpsSourceCopy)
+	p = appendNumber(p, 6<<1|1)
+	// TargetRead "x"
+	p = appendNumber(p, 0<<2|bpsTargetRead)
+	p = append(p, 'x')
+	// TargetCopy "xxx" overlapping from 6
+	p = appendNumber(p, 2<<2|bpsTargetCopy)
+	p = appendNumber(p, 6<<1)
+	// TargetCopy "abc" from 3
+	p = appendNumber(p, 2<<2|bpsTargetCopy)
+	p = appendNumber(p, 6<<1|1)
+	p = appendChecksums(p, src, dst)
+
+	got, err := ApplyBPS(src, p)
+	require.NoError(t, err)
+	assert.Equal(t, dst, got)
+}
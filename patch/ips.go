@@ -0,0 +1,145 @@
+package patch
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+)
+
+// https://zerosoft.zophar.net/ips.php
+
+var (
+	ipsHeader = []byte("PATCH")
+	ipsFooter = []byte("EOF")
+)
+
+const (
+	ipsMaxRecordSize = 0xFFFF
+	ipsMaxOffset     = 0xFFFFFF
+	// "EOF" as an offset is misread as the footer
+	ipsFooterOffset = 0x454F46
+
+	// runs of the same byte longer than this are written as RLE records
+	ipsMinRLESize = 8
+)
+
+// ApplyIPS applies an IPS patch to src, and returns the patched copy.
+//
+// It supports RLE records and the truncation extension, which has the target size after the footer.
+func ApplyIPS(src, p []byte) ([]byte, error) {
+	if !bytes.HasPrefix(p, ipsHeader) {
+		return nil, errors.New("invalid IPS header")
+	}
+	out := append([]byte(nil), src...)
+
+	r := reader{b: p, pos: len(ipsHeader)}
+	for {
+		if bytes.HasPrefix(p[r.pos:], ipsFooter) {
+			r.pos += len(ipsFooter)
+			break
+		}
+		record, err := r.bytes(5)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid IPS record")
+		}
+		offset := int(record[0])<<16 | int(record[1])<<8 | int(record[2])
+		size := int(record[3])<<8 | int(record[4])
+
+		var data []byte
+		if size == 0 {
+			// RLE
+			rle, err := r.bytes(3)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid IPS RLE record")
+			}
+			size = int(rle[0])<<8 | int(rle[1])
+			data = bytes.Repeat(rle[2:3], size)
+		} else {
+			data, err = r.bytes(uint64(size))
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid IPS record data")
+			}
+		}
+		if len(out) < offset+size {
+			out = append(out, make([]byte, offset+size-len(out))...)
+		}
+		copy(out[offset:], data)
+	}
+
+	if len(p)-r.pos == 3 {
+		size := int(p[r.pos])<<16 | int(p[r.pos+1])<<8 | int(p[r.pos+2])
+		if size < len(out) {
+			out = out[:size]
+		}
+	}
+	return out, nil
+}
+
+// CreateIPS creates an IPS patch which converts src into dst.
+func CreateIPS(src, dst []byte) ([]byte, error) {
+	if ipsMaxOffset < len(dst) {
+		return nil, errors.Errorf("too large target for IPS: %d bytes", len(dst))
+	}
+	differs := func(i int) bool {
+		return len(src) <= i || src[i] != dst[i]
+	}
+
+	out := append([]byte(nil), ipsHeader...)
+	for i := 0; i < len(dst); {
+		if !differs(i) {
+			i++
+			continue
+		}
+		start := i
+		if start == ipsFooterOffset {
+			start--
+		}
+		end := i + 1
+		for end < len(dst) && end-start < ipsMaxRecordSize && differs(end) {
+			end++
+		}
+		out = appendIPSRecords(out, start, dst[start:end])
+		i = end
+	}
+	out = append(out, ipsFooter...)
+
+	if len(dst) < len(src) {
+		n := len(dst)
+		out = append(out, byte(n>>16), byte(n>>8), byte(n))
+	}
+	return out, nil
+}
+
+// appendIPSRecords appends records of data, where long runs are written as RLE records.
+func appendIPSRecords(out []byte, offset int, data []byte) []byte {
+	for len(data) > 0 {
+		run := 1
+		for run < len(data) && data[run] == data[0] {
+			run++
+		}
+		if ipsMinRLESize < run && offset+run != ipsFooterOffset {
+			out = append(out, byte(offset>>16), byte(offset>>8), byte(offset), 0, 0, byte(run>>8), byte(run), data[0])
+			offset += run
+			data = data[run:]
+			continue
+		}
+
+		// a normal record until the next long run
+		n := run
+		for n < len(data) {
+			r := 1
+			for n+r < len(data) && data[n+r] == data[n] {
+				r++
+			}
+			if ipsMinRLESize < r && offset+n != ipsFooterOffset {
+				break
+			}
+			n += r
+		}
+		out = append(out, byte(offset>>16), byte(offset>>8), byte(offset), byte(n>>8), byte(n))
+		out = append(out, data[:n]...)
+		offset += n
+		data = data[n:]
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyIPS(t *testing.T) {
+	src := make([]byte, 8)
+
+	// normal and RLE records
+	got, err := ApplyIPS(src, []byte("PATCH\x00\x00\x01\x00\x01\xAA\x00\x00\x03\x00\x00\x00\x03\xFFEOF"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 0xAA, 0, 0xFF, 0xFF, 0xFF, 0, 0}, got)
+	assert.Equal(t, make([]byte, 8), src, "source is not modified")
+
+	// extends the target
+	got, err = ApplyIPS(src, []byte("PATCH\x00\x00\x09\x00\x01\xAAEOF"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0xAA}, got)
+
+	// truncation
+	got, err = ApplyIPS(src, []byte("PATCH\x00\x00\x00\x00\x01\xAAEOF\x00\x00\x02"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xAA, 0}, got)
+
+	_, err = ApplyIPS(src, []byte("PATCH\x00\x00"))
+	assert.Error(t, err)
+	_, err = ApplyIPS(src, []byte("PATCH\x00\x00\x00\x00\x05\xAAEOF"))
+	assert.Error(t, err)
+	_, err = ApplyIPS(src, []byte("PATC"))
+	assert.Error(t, err)
+}
+
+func TestCreateIPS(t *testing.T) {
+	src := make([]byte, 0x454F50)
+	dst := append([]byte(nil), src...)
+	dst[0x10] = 1
+	dst[0x11] = 2
+	for i := 0x100; i < 0x200; i++ {
+		dst[i] = 0xFF
+	}
+	dst[0x205] = 3
+	dst[0x454F46] = 4
+
+	p, err := CreateIPS(src, dst)
+	require.NoError(t, err)
+	assert.Less(t, len(p), 0x100, "runs are written as RLE")
+
+	got, err := ApplyIPS(src, p)
+	require.NoError(t, err)
+	assert.Equal(t, dst, got)
+
+	// shorter and longer targets
+	for _, dst := range [][]byte{src[:0x20], append(src, 0, 1, 2)} {
+		p, err := CreateIPS(src, dst)
+		require.NoError(t, err)
+		got, err := ApplyIPS(src, p)
+		require.NoError(t, err)
+		assert.Equal(t, dst, got)
+	}
+
+	_, err = CreateIPS(nil, make([]byte, ipsMaxOffset+1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+// Package patch applies and creates IPS, UPS and BPS patches of ROM images.
+package patch
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrUnknownFormat is returned if the patch format is not supported.
+	ErrUnknownFormat = errors.New("unknown patch format")
+	// ErrSourceChecksum is returned if the patch is not for the source.
+	ErrSourceChecksum = errors.New("source checksum mismatch")
+	// ErrTargetChecksum is returned if the patched result is broken.
+	ErrTargetChecksum = errors.New("target checksum mismatch")
+	// ErrPatchChecksum is returned if the patch itself is broken.
+	ErrPatchChecksum = errors.New("patch checksum mismatch")
+	// ErrTargetTooLarge is returned if the target size in the patch exceeds MaxTargetSize.
+	ErrTargetTooLarge = errors.New("too large target size")
+)
+
+// MaxTargetSize is the largest target size of UPS and BPS patches, which is far larger than any NES ROM.
+const MaxTargetSize = 64 << 20
+
+// Apply applies the patch to src in the format detected by its magic number, and returns the patched copy.
+func Apply(src, p []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(p, ipsHeader):
+		return ApplyIPS(src, p)
+	case bytes.HasPrefix(p, upsMagic):
+		return ApplyUPS(src, p)
+	case bytes.HasPrefix(p, bpsMagic):
+		return ApplyBPS(src, p)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// Extensions are file extensions of supported patch formats.
+var Extensions = []string{".ips", ".ups", ".bps"}
+
+// https://www.romhacking.net/documents/746/
+
+// appendNumber appends a variable-length number used by UPS and BPS.
+func appendNumber(b []byte, v uint64) []byte {
+	for {
+		x := byte(v & 0x7F)
+		v >>= 7
+		if v == 0 {
+			return append(b, 0x80|x)
+		}
+		b = append(b, x)
+		v--
+	}
+}
+
+// reader reads a UPS or BPS patch.
+type reader struct {
+	b   []byte
+	pos int
+}
+
+func (r *reader) byte() (byte, error) {
+	if len(r.b) <= r.pos {
+		return 0, errors.New("unexpected end of patch")
+	}
+	v := r.b[r.pos]
+	r.pos++
+	return v, nil
+}
+
+func (r *reader) number() (uint64, error) {
+	var v uint64
+	shift := uint64(1)
+	for {
+		x, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		v += uint64(x&0x7F) * shift
+		if x&0x80 != 0 {
+			return v, nil
+		}
+		if 1<<56 < shift {
+			return 0, errors.New("too large number in patch")
+		}
+		shift <<= 7
+		v += shift
+	}
+}
+
+func (r *reader) bytes(n uint64) ([]byte, error) {
+	if uint64(len(r.b)-r.pos) < n {
+		return nil, errors.New("unexpected end of patch")
+	}
+	v := r.b[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return v, nil
+}
@@ -0,0 +1,40 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_number(t *testing.T) {
+	for _, v := range []uint64{0, 1, 0x7F, 0x80, 0x3FFF, 0x4000, 0x4080, 1 << 40} {
+		b := appendNumber(nil, v)
+		r := reader{b: b}
+		got, err := r.number()
+		require.NoError(t, err)
+		assert.Equal(t, v, got)
+		assert.Equal(t, len(b), r.pos)
+	}
+	assert.Equal(t, []byte{0x80}, appendNumber(nil, 0))
+	assert.Equal(t, []byte{0x00, 0x80}, appendNumber(nil, 0x80))
+
+	_, err := (&reader{b: []byte{0x00}}).number()
+	assert.Error(t, err)
+}
+
+func TestApply(t *testing.T) {
+	src := []byte("Hello, World!")
+	dst := []byte("Hello, Gopher!!")
+
+	ips, err := CreateIPS(src, dst)
+	require.NoError(t, err)
+	for _, p := range [][]byte{ips, CreateUPS(src, dst), CreateBPS(src, dst)} {
+		got, err := Apply(src, p)
+		require.NoError(t, err)
+		assert.Equal(t, dst, got)
+	}
+
+	_, err = Apply(src, []byte("unknown"))
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
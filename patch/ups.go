@@ -0,0 +1,119 @@
+package patch
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/pkg/errors"
+)
+
+// http://www.romhacking.net/documents/392/
+
+var upsMagic = []byte("UPS1")
+
+// ApplyUPS applies a UPS patch to src, and returns the patched copy.
+func ApplyUPS(src, p []byte) ([]byte, error) {
+	if len(p) < len(upsMagic)+12 {
+		return nil, errors.New("too short UPS patch")
+	}
+	srcCRC, dstCRC, patchCRC := checksums(p)
+	if crc32.ChecksumIEEE(p[:len(p)-4]) != patchCRC {
+		return nil, ErrPatchChecksum
+	}
+
+	r := reader{b: p[:len(p)-12], pos: len(upsMagic)}
+	srcSize, err := r.number()
+	if err != nil {
+		return nil, err
+	}
+	dstSize, err := r.number()
+	if err != nil {
+		return nil, err
+	}
+	if MaxTargetSize < dstSize {
+		return nil, ErrTargetTooLarge
+	}
+	if uint64(len(src)) != srcSize || crc32.ChecksumIEEE(src) != srcCRC {
+		return nil, ErrSourceChecksum
+	}
+
+	out := make([]byte, dstSize)
+	copy(out, src)
+	var pos uint64
+	for r.pos < len(r.b) {
+		skip, err := r.number()
+		if err != nil {
+			return nil, err
+		}
+		pos += skip
+		for {
+			x, err := r.byte()
+			if err != nil {
+				return nil, err
+			}
+			if x == 0 {
+				pos++
+				break
+			}
+			if pos < dstSize {
+				out[pos] ^= x
+			}
+			pos++
+		}
+	}
+
+	if crc32.ChecksumIEEE(out) != dstCRC {
+		return nil, ErrTargetChecksum
+	}
+	return out, nil
+}
+
+// CreateUPS creates a UPS patch which converts src into dst.
+func CreateUPS(src, dst []byte) []byte {
+	at := func(b []byte, i int) byte {
+		if i < len(b) {
+			return b[i]
+		}
+		return 0
+	}
+	size := len(src)
+	if size < len(dst) {
+		size = len(dst)
+	}
+
+	out := append([]byte(nil), upsMagic...)
+	out = appendNumber(out, uint64(len(src)))
+	out = appendNumber(out, uint64(len(dst)))
+
+	pos := 0
+	for i := 0; i < size; {
+		if at(src, i) == at(dst, i) {
+			i++
+			continue
+		}
+		out = appendNumber(out, uint64(i-pos))
+		for ; i < size && at(src, i) != at(dst, i); i++ {
+			out = append(out, at(src, i)^at(dst, i))
+		}
+		out = append(out, 0)
+		i++
+		pos = i
+	}
+	return appendChecksums(out, src, dst)
+}
+
+// checksums returns CRC32 of the source, the target and the patch in the footer of UPS and BPS.
+func checksums(p []byte) (src, dst, patch uint32) {
+	footer := p[len(p)-12:]
+	return binary.LittleEndian.Uint32(footer[0:]), binary.LittleEndian.Uint32(footer[4:]), binary.LittleEndian.Uint32(footer[8:])
+}
+
+func appendChecksums(p, src, dst []byte) []byte {
+	var footer [4]byte
+	binary.LittleEndian.PutUint32(footer[:], crc32.ChecksumIEEE(src))
+	p = append(p, footer[:]...)
+	binary.LittleEndian.PutUint32(footer[:], crc32.ChecksumIEEE(dst))
+	p = append(p, footer[:]...)
+	binary.LittleEndian.PutUint32(footer[:], crc32.ChecksumIEEE(p))
+	return append(p, footer[:]...)
+}
@@ -0,0 +1,41 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUPS(t *testing.T) {
+	src := []byte("The quick brown fox jumps over the lazy dog")
+	for _, dst := range [][]byte{
+		[]byte("The quick brown cat jumps over the lazy dog"),
+		[]byte("The quick brown fox"),
+		[]byte("The quick brown fox jumps over the lazy dog!!"),
+		src,
+	} {
+		p := CreateUPS(src, dst)
+		got, err := ApplyUPS(src, p)
+		require.NoError(t, err)
+		assert.Equal(t, dst, got)
+	}
+
+	p := CreateUPS(src, []byte("The quick brown cat"))
+	_, err := ApplyUPS([]byte("another source"), p)
+	assert.ErrorIs(t, err, ErrSourceChecksum)
+
+	p[len(upsMagic)+3] ^= 0xFF
+	_, err = ApplyUPS(src, p)
+	assert.ErrorIs(t, err, ErrPatchChecksum)
+
+	_, err = ApplyUPS(src, upsMagic)
+	assert.Error(t, err)
+
+	p = append([]byte(nil), upsMagic...)
+	p = appendNumber(p, uint64(len(src)))
+	p = appendNumber(p, MaxTargetSize+1)
+	p = appendChecksums(p, src, nil)
+	_, err = ApplyUPS(src, p)
+	assert.ErrorIs(t, err, ErrTargetTooLarge)
+}
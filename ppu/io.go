@@ -8,10 +8,14 @@ func (p *PPU) read(addr uint16) uint8 {
 	switch {
 	case 0x0000 <= addr && addr <= 0x1FFF:
 		return p.mapper.Read(addr)
-	case 0x2000 <= addr && addr <= 0x2FFF:
-		return p.nt[ntAddr(addr, p.mirroring)]
-	case 0x3000 <= addr && addr <= 0x3EFF:
-		return p.nt[ntAddr(addr-0x1000, p.mirroring)]
+	case 0x2000 <= addr && addr <= 0x3EFF:
+		if 0x3000 <= addr {
+			addr -= 0x1000
+		}
+		if p.nametable != nil {
+			return p.nametable.ReadNametable(addr)
+		}
+		return p.nt[ntAddr(addr, p.mapper.Mirroring())]
 	case 0x3F00 <= addr && addr <= 0x3FFF:
 		return p.palettes[paletteAddr(addr)]
 	default:
@@ -23,10 +27,15 @@ func (p *PPU) write(addr uint16, value uint8) {
 	switch {
 	case 0x0000 <= addr && addr <= 0x1FFF:
 		p.mapper.Write(addr, value)
-	case 0x2000 <= addr && addr <= 0x2FFF:
-		p.nt[ntAddr(addr, p.mirroring)] = value
-	case 0x3000 <= addr && addr <= 0x3EFF:
-		p.nt[ntAddr(addr-0x1000, p.mirroring)] = value
+	case 0x2000 <= addr && addr <= 0x3EFF:
+		if 0x3000 <= addr {
+			addr -= 0x1000
+		}
+		if p.nametable != nil {
+			p.nametable.WriteNametable(addr, value)
+			return
+		}
+		p.nt[ntAddr(addr, p.mapper.Mirroring())] = value
 	case 0x3F00 <= addr && addr <= 0x3FFF:
 		p.palettes[paletteAddr(addr)] = value
 	}
@@ -42,6 +51,12 @@ func ntAddr(addr uint16, m mapper.Mirroring) uint16 {
 		}
 	case mapper.Mirroring_Vertical:
 		return addr % 0x0800
+	case mapper.Mirroring_SingleScreenA:
+		return addr % 0x0400
+	case mapper.Mirroring_SingleScreenB:
+		return 0x0400 + addr%0x0400
+	case mapper.Mirroring_FourScreen:
+		return addr - 0x2000
 	}
 	return addr - 0x2000
 }
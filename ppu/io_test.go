@@ -30,6 +30,12 @@ func Test_toNTAddr(t *testing.T) {
 		{mapper.Mirroring_Horizontal, 0x2BFF, 0x0BFF},
 		{mapper.Mirroring_Horizontal, 0x2C00, 0x0800},
 		{mapper.Mirroring_Horizontal, 0x2FFF, 0x0BFF},
+		{mapper.Mirroring_SingleScreenA, 0x2000, 0},
+		{mapper.Mirroring_SingleScreenA, 0x2C10, 0x0010},
+		{mapper.Mirroring_SingleScreenB, 0x2000, 0x0400},
+		{mapper.Mirroring_SingleScreenB, 0x2810, 0x0410},
+		{mapper.Mirroring_FourScreen, 0x2400, 0x0400},
+		{mapper.Mirroring_FourScreen, 0x2FFF, 0x0FFF},
 	}
 	for i, tt := range tests {
 		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
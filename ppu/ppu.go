@@ -54,25 +54,36 @@ type PPU struct {
 	}
 
 	mapper    mapper.Mapper
-	mirroring mapper.Mirroring
+	nametable mapper.NametableMapper // nil if the mapper uses mirroring
 
 	renderer FrameRenderer
 
 	frames uint64
 }
 
-func New(mapper mapper.Mapper, renderer FrameRenderer) *PPU {
+func New(m mapper.Mapper, renderer FrameRenderer) *PPU {
+	nt, _ := m.(mapper.NametableMapper)
 	return &PPU{
-		mapper:    mapper,
-		mirroring: mapper.Mirroring(),
+		mapper:    m,
+		nametable: nt,
 		renderer:  renderer,
 	}
 }
 
+// SetRenderer changes the renderer of frames.
+func (p *PPU) SetRenderer(renderer FrameRenderer) {
+	p.renderer = renderer
+}
+
 func (p *PPU) CurrentFrames() uint64 {
 	return p.frames
 }
 
+// Frame returns the buffer of the frame, which is being rendered while visible scanlines.
+func (p *PPU) Frame() *[WIDTH * HEIGHT]uint8 {
+	return &p.buf
+}
+
 func (p *PPU) Step(intr *cpu.Interrupt) {
 	var pre bool
 
@@ -0,0 +1,74 @@
+package ppu
+
+import "github.com/thara/gorones/state"
+
+func (p *PPU) Serialize(s *state.Serializer) {
+	s.Section("PPU")
+	s.Uint8(&p.ctrl.nt)
+	s.Bool(&p.ctrl.vramIncr)
+	s.Bool(&p.ctrl.sprTable)
+	s.Bool(&p.ctrl.bgTable)
+	s.Bool(&p.ctrl.spr8x16)
+	s.Bool(&p.ctrl.slave)
+	s.Bool(&p.ctrl.nmi)
+
+	s.Bool(&p.mask.gray)
+	s.Bool(&p.mask.bgLeft)
+	s.Bool(&p.mask.sprLeft)
+	s.Bool(&p.mask.bg)
+	s.Bool(&p.mask.spr)
+	s.Bool(&p.mask.red)
+	s.Bool(&p.mask.green)
+	s.Bool(&p.mask.blue)
+
+	s.Bool(&p.status.sprOverflow)
+	s.Bool(&p.status.spr0Hit)
+	s.Bool(&p.status.vblank)
+
+	s.Uint8(&p.data)
+	s.Uint8(&p.oamAddr)
+	s.Uint16(&p.v)
+	s.Uint16(&p.t)
+	s.Uint8(&p.x)
+	s.Bool(&p.w)
+
+	s.Data(p.nt[:])
+	s.Data(p.palettes[:])
+	s.Data(p.buf[:])
+	s.Uint8(&p.cpuDataBus)
+
+	s.Uint16(&p.bg.addr)
+	s.Uint8(&p.bg.nt)
+	s.Uint8(&p.bg.at)
+	s.Uint16(&p.bg.low)
+	s.Uint16(&p.bg.high)
+	s.Uint16(&p.bg.shiftL)
+	s.Uint16(&p.bg.shiftH)
+	s.Uint8(&p.bg.attrShiftL)
+	s.Uint8(&p.bg.attrShiftH)
+	s.Uint8(&p.bg.attrLatchL)
+	s.Uint8(&p.bg.attrLatchH)
+
+	s.Data(p.spr.oam[:])
+	for i := range p.spr.primaryOAM {
+		p.spr.primaryOAM[i].serialize(s)
+	}
+	for i := range p.spr.secondaryOAM {
+		p.spr.secondaryOAM[i].serialize(s)
+	}
+
+	s.Uint16(&p.scan.line)
+	s.Uint16(&p.scan.dot)
+	s.Uint64(&p.frames)
+}
+
+func (sp *Sprite) serialize(s *state.Serializer) {
+	s.Bool(&sp.enabled)
+	s.Uint8(&sp.index)
+	s.Uint8(&sp.x)
+	s.Uint8(&sp.y)
+	s.Uint8(&sp.tile)
+	s.Uint8(&sp.attr)
+	s.Uint8(&sp.low)
+	s.Uint8(&sp.high)
+}
@@ -0,0 +1,257 @@
+package gorones
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// RewindOptions configure Rewinder.
+type RewindOptions struct {
+	// frames between snapshots
+	Interval int
+	// snapshots between keyframes, which are saved entirely while others are deltas against the previous keyframe
+	KeyframeInterval int
+	// max bytes of compressed snapshots, where old snapshots are discarded
+	MemoryLimit int
+}
+
+// DefaultRewindOptions keep about 10 minutes of most games.
+var DefaultRewindOptions = RewindOptions{
+	Interval:         10,
+	KeyframeInterval: 30,
+	MemoryLimit:      64 << 20,
+}
+
+// Rewinder runs NES with snapshots captured periodically, and runs it backwards by the snapshots and the input log.
+type Rewinder struct {
+	nes  *NES
+	opts RewindOptions
+
+	// the number of frames run since created
+	frame uint64
+
+	// oldest first
+	snapshots []snapshot
+	size      int
+
+	// state of the last keyframe to make deltas, nil to make a keyframe on the next snapshot
+	keyframe      []byte
+	sinceKeyframe int
+
+	// states of each frame after the snapshot being played backwards
+	cache      [][]byte
+	cacheFrame uint64
+}
+
+type snapshot struct {
+	frame    uint64
+	keyframe bool
+	// state compressed, which is XORed with the previous keyframe if not keyframe
+	data []byte
+	// input of frames after the snapshot
+	inputs []Input
+}
+
+func (s *snapshot) size() int { return len(s.data) + len(s.inputs)*len(Input{}) }
+
+// NewRewinder creates a rewinder of NES. Zero options are replaced by DefaultRewindOptions.
+func NewRewinder(nes *NES, opts RewindOptions) *Rewinder {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultRewindOptions.Interval
+	}
+	if opts.KeyframeInterval <= 0 {
+		opts.KeyframeInterval = DefaultRewindOptions.KeyframeInterval
+	}
+	if opts.MemoryLimit <= 0 {
+		opts.MemoryLimit = DefaultRewindOptions.MemoryLimit
+	}
+	return &Rewinder{nes: nes, opts: opts}
+}
+
+// Frames returns the number of frames which can be played backwards.
+func (r *Rewinder) Frames() int {
+	if len(r.snapshots) == 0 {
+		return 0
+	}
+	return int(r.frame - r.snapshots[0].frame)
+}
+
+// Size returns the bytes of the snapshots.
+func (r *Rewinder) Size() int { return r.size }
+
+// RunFrame runs a frame of NES while recording the input, and captures a snapshot every interval.
+func (r *Rewinder) RunFrame() error {
+	r.cache = nil
+	if r.frame%uint64(r.opts.Interval) == 0 && (len(r.snapshots) == 0 || r.last().frame != r.frame) {
+		if err := r.capture(); err != nil {
+			return err
+		}
+	}
+	if len(r.snapshots) != 0 {
+		last := r.last()
+		last.inputs = append(last.inputs, r.nes.Input())
+		r.size += len(Input{})
+	}
+	r.nes.RunFrame()
+	r.frame++
+	r.evict()
+	return nil
+}
+
+func (r *Rewinder) last() *snapshot { return &r.snapshots[len(r.snapshots)-1] }
+
+func (r *Rewinder) capture() error {
+	b, err := r.nes.SaveState()
+	if err != nil {
+		return err
+	}
+	s := snapshot{frame: r.frame}
+	if r.keyframe == nil || r.opts.KeyframeInterval <= r.sinceKeyframe {
+		s.keyframe = true
+		r.keyframe = b
+		r.sinceKeyframe = 0
+	} else {
+		xor(b, r.keyframe)
+		r.sinceKeyframe++
+	}
+	if s.data, err = compress(b); err != nil {
+		return err
+	}
+	r.snapshots = append(r.snapshots, s)
+	r.size += s.size()
+	return nil
+}
+
+// evict discards the oldest snapshots over the memory limit, with the deltas which depend on them.
+func (r *Rewinder) evict() {
+	for r.opts.MemoryLimit < r.size && 1 < len(r.snapshots) {
+		r.size -= r.snapshots[0].size()
+		r.snapshots = r.snapshots[1:]
+		for 0 < len(r.snapshots) && !r.snapshots[0].keyframe {
+			r.size -= r.snapshots[0].size()
+			r.snapshots = r.snapshots[1:]
+		}
+	}
+	if len(r.snapshots) == 0 {
+		r.keyframe = nil
+	}
+}
+
+// StepBack restores the state of the previous frame, and returns false if no more frames are recorded.
+func (r *Rewinder) StepBack() (bool, error) {
+	if r.Frames() == 0 {
+		return false, nil
+	}
+	target := r.frame - 1
+
+	i := len(r.snapshots) - 1
+	for target < r.snapshots[i].frame {
+		i--
+	}
+	s := &r.snapshots[i]
+
+	if r.cache == nil || r.cacheFrame != s.frame {
+		if err := r.simulate(i); err != nil {
+			return false, err
+		}
+	}
+	if err := r.nes.LoadState(r.cache[target-s.frame]); err != nil {
+		return false, err
+	}
+
+	// discard the future
+	for _, s := range r.snapshots[i+1:] {
+		r.size -= s.size()
+	}
+	r.snapshots = r.snapshots[:i+1]
+	n := int(target - s.frame)
+	r.size -= (len(s.inputs) - n) * len(Input{})
+	s.inputs = s.inputs[:n]
+	r.cache = r.cache[:n+1]
+	r.frame = target
+	// the next snapshot is a keyframe since the last keyframe may be discarded
+	r.keyframe = nil
+	return true, nil
+}
+
+// simulate caches the state of each frame after the snapshot by running with the recorded input.
+func (r *Rewinder) simulate(i int) (err error) {
+	s := &r.snapshots[i]
+	b, err := r.decode(i)
+	if err != nil {
+		return err
+	}
+	cache := [][]byte{b}
+	r.nes.runSilently(func() {
+		if err = r.nes.LoadState(b); err != nil {
+			return
+		}
+		for _, in := range s.inputs {
+			r.nes.SetInput(in)
+			r.nes.RunFrame()
+			if b, err = r.nes.SaveState(); err != nil {
+				return
+			}
+			cache = append(cache, b)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	r.cache, r.cacheFrame = cache, s.frame
+	return nil
+}
+
+// decode returns the state of the snapshot.
+func (r *Rewinder) decode(i int) ([]byte, error) {
+	b, err := decompress(r.snapshots[i].data)
+	if err != nil {
+		return nil, err
+	}
+	if r.snapshots[i].keyframe {
+		return b, nil
+	}
+	k := i - 1
+	for !r.snapshots[k].keyframe {
+		k--
+	}
+	key, err := decompress(r.snapshots[k].data)
+	if err != nil {
+		return nil, err
+	}
+	xor(b, key)
+	return b, nil
+}
+
+// xor XORs b with key, which makes unchanged bytes zero.
+func xor(b, key []byte) {
+	if len(key) < len(b) {
+		b = b[:len(key)]
+	}
+	for i := range b {
+		b[i] ^= key[i]
+	}
+}
+
+func compress(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, errors.Wrap(err, "failed to compress snapshot")
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to compress snapshot")
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(b []byte) ([]byte, error) {
+	d, err := io.ReadAll(flate.NewReader(bytes.NewReader(b)))
+	return d, errors.Wrap(err, "failed to decompress snapshot")
+}
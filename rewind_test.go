@@ -0,0 +1,150 @@
+package gorones
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thara/gorones/input"
+	"github.com/thara/gorones/mapper"
+)
+
+func newTestNES(t *testing.T) *NES {
+	f, err := os.Open("testdata/nestest.nes")
+	require.NoError(t, err)
+	defer f.Close()
+
+	rom, err := mapper.ParseROM(f)
+	require.NoError(t, err)
+	m, err := rom.Mapper()
+	require.NoError(t, err)
+
+	var ctrl1, ctrl2 input.StandardController
+	nes := NewNES(m, &ctrl1, &ctrl2, new(nopFrameRenderer), new(nopAudioRenderer))
+	nes.PowerOn()
+	nes.Reset()
+	return nes
+}
+
+// testInput moves the cursor and runs tests of nestest.nes.
+func testInput(frame int) Input {
+	switch {
+	case frame%20 == 5:
+		return Input{input.StandardDown}
+	case frame == 90:
+		return Input{input.StandardStart}
+	}
+	return Input{}
+}
+
+func TestNES_SaveState(t *testing.T) {
+	nes := newTestNES(t)
+	for i := 0; i < 30; i++ {
+		nes.SetInput(testInput(i))
+		nes.RunFrame()
+	}
+	saved, err := nes.SaveState()
+	require.NoError(t, err)
+
+	for i := 30; i < 120; i++ {
+		nes.SetInput(testInput(i))
+		nes.RunFrame()
+	}
+	want, err := nes.SaveState()
+	require.NoError(t, err)
+
+	require.NoError(t, nes.LoadState(saved))
+	assert.EqualValues(t, 30, nes.Frames())
+	for i := 30; i < 120; i++ {
+		nes.SetInput(testInput(i))
+		nes.RunFrame()
+	}
+	got, err := nes.SaveState()
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(want, got))
+
+	t.Run("invalid", func(t *testing.T) {
+		assert.Error(t, nes.LoadState(saved[:len(saved)-1]))
+		assert.Error(t, nes.LoadState(append(saved, 0)))
+		assert.Error(t, nes.LoadState([]byte("GNST\x02")))
+		// unchanged
+		got, err := nes.SaveState()
+		require.NoError(t, err)
+		assert.True(t, bytes.Equal(want, got))
+	})
+}
+
+func TestRewinder(t *testing.T) {
+	nes := newTestNES(t)
+	r := NewRewinder(nes, RewindOptions{Interval: 4, KeyframeInterval: 3})
+
+	b, err := nes.SaveState()
+	require.NoError(t, err)
+	states := map[uint64][]byte{nes.Frames(): b}
+	for i := 0; i < 120; i++ {
+		nes.SetInput(testInput(i))
+		require.NoError(t, r.RunFrame())
+		b, err := nes.SaveState()
+		require.NoError(t, err)
+		states[nes.Frames()] = b
+	}
+	assert.Equal(t, 120, r.Frames())
+
+	// back to the 60th frame
+	for i := 0; i < 60; i++ {
+		ok, err := r.StepBack()
+		require.NoError(t, err)
+		require.True(t, ok)
+		b, err := nes.SaveState()
+		require.NoError(t, err)
+		require.True(t, bytes.Equal(states[nes.Frames()], b), "frame %d", nes.Frames())
+	}
+	assert.Equal(t, 60, r.Frames())
+
+	// run again from the 60th frame
+	for i := 60; i < 100; i++ {
+		nes.SetInput(testInput(i))
+		require.NoError(t, r.RunFrame())
+	}
+	b, err = nes.SaveState()
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(states[nes.Frames()], b))
+
+	for {
+		ok, err := r.StepBack()
+		require.NoError(t, err)
+		if !ok {
+			break
+		}
+		b, err := nes.SaveState()
+		require.NoError(t, err)
+		require.True(t, bytes.Equal(states[nes.Frames()], b), "frame %d", nes.Frames())
+	}
+	// the first frame after reset
+	assert.Equal(t, 0, r.Frames())
+}
+
+func TestRewinder_MemoryLimit(t *testing.T) {
+	nes := newTestNES(t)
+	r := NewRewinder(nes, RewindOptions{Interval: 2, KeyframeInterval: 4, MemoryLimit: 16 << 10})
+	for i := 0; i < 300; i++ {
+		nes.SetInput(testInput(i))
+		require.NoError(t, r.RunFrame())
+		require.LessOrEqual(t, r.Size(), 16<<10)
+	}
+	require.Less(t, 0, r.Frames())
+	require.Less(t, r.Frames(), 300)
+	assert.True(t, r.snapshots[0].keyframe)
+
+	n := r.Frames()
+	for i := 0; i < n; i++ {
+		ok, err := r.StepBack()
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	ok, err := r.StepBack()
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
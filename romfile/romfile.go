@@ -0,0 +1,236 @@
+// Package romfile loads ROM files, which may be stored in zip, gzip or tar archives.
+package romfile
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Extensions are file extensions of ROM entries in archives.
+var Extensions = []string{".nes", ".unf", ".unif", ".fds", ".nsf", ".nsfe"}
+
+// File is a loaded ROM file.
+type File struct {
+	// Path is the path of the file on the file system, which is the archive if the ROM is in an archive.
+	Path string
+	// Entry is the name of the entry in the archive, or empty if the ROM is not in an archive.
+	Entry string
+	Data  []byte
+}
+
+// Name returns the name in `archive.zip:entry.nes` syntax if the ROM is in an archive.
+func (f *File) Name() string {
+	if f.Entry == "" {
+		return f.Path
+	}
+	return f.Path + ":" + f.Entry
+}
+
+// ROMPath returns the path of the ROM as if the entry were extracted next to the archive,
+// to find files for the ROM like patches.
+func (f *File) ROMPath() string {
+	if f.Entry == "" {
+		return f.Path
+	}
+	return filepath.Join(filepath.Dir(f.Path), path.Base(f.Entry))
+}
+
+// Load reads a ROM file.
+//
+// If the file is an archive, a ROM entry is picked by `archive.zip:entry.nes` syntax, or the only one in the archive.
+// Only the picked entry is decompressed.
+func Load(path string) (*File, error) {
+	path, name := splitPath(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+	entries, err := readArchive(filepath.Base(path), data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read archive %s", path)
+	}
+	if entries == nil {
+		if name != "" {
+			return nil, errors.Errorf("%s is not an archive", path)
+		}
+		return &File{Path: path, Data: data}, nil
+	}
+
+	e, err := pickEntry(path, entries, name)
+	if err != nil {
+		return nil, err
+	}
+	b, err := e.read()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s in %s", e.name, path)
+	}
+	return &File{Path: path, Entry: e.name, Data: b}, nil
+}
+
+func pickEntry(path string, entries []entry, name string) (entry, error) {
+	if name != "" {
+		for _, e := range entries {
+			if e.name == name {
+				return e, nil
+			}
+		}
+		return entry{}, errors.Errorf("%s is not found in %s", name, path)
+	}
+
+	var roms []entry
+	for _, e := range entries {
+		if isROM(e.name) {
+			roms = append(roms, e)
+		}
+	}
+	switch {
+	case len(roms) == 1:
+		return roms[0], nil
+	case 1 < len(roms):
+		names := make([]string, len(roms))
+		for i, e := range roms {
+			names[i] = e.name
+		}
+		return entry{}, errors.Errorf("%d ROMs in %s, select one by %s:<entry> from: %s", len(roms), path, path, strings.Join(names, ", "))
+	case len(entries) == 1:
+		// a gzipped file may have no name with extension
+		return entries[0], nil
+	}
+	return entry{}, errors.Errorf("no ROM in %s", path)
+}
+
+// splitPath splits `archive.zip:entry.nes` into the archive path and the entry name.
+func splitPath(path string) (string, string) {
+	if _, err := os.Stat(path); err == nil {
+		return path, ""
+	}
+	i := strings.LastIndex(path, ":")
+	if i < 0 {
+		return path, ""
+	}
+	if _, err := os.Stat(path[:i]); err != nil {
+		return path, ""
+	}
+	return path[:i], path[i+1:]
+}
+
+func isROM(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range Extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+type entry struct {
+	name string
+	read func() ([]byte, error)
+}
+
+var (
+	zipMagic  = []byte("PK\x03\x04")
+	gzipMagic = []byte{0x1F, 0x8B}
+	tarMagic  = []byte("ustar")
+)
+
+const tarMagicOffset = 257
+
+// readArchive returns entries in the archive in order, or nil if data is not an archive.
+func readArchive(name string, data []byte) ([]entry, error) {
+	switch {
+	case bytes.HasPrefix(data, zipMagic):
+		return readZip(data)
+	case bytes.HasPrefix(data, gzipMagic):
+		return readGzip(name, data)
+	case tarMagicOffset+len(tarMagic) <= len(data) && bytes.Equal(data[tarMagicOffset:tarMagicOffset+len(tarMagic)], tarMagic):
+		return readTar(data)
+	}
+	return nil, nil
+}
+
+func readZip(data []byte) ([]entry, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	entries := []entry{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		f := f
+		entries = append(entries, entry{name: f.Name, read: func() ([]byte, error) {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return io.ReadAll(rc)
+		}})
+	}
+	return entries, nil
+}
+
+func readGzip(name string, data []byte) ([]entry, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	// .tar.gz
+	if inner, err := readArchive("", b); err != nil {
+		return nil, err
+	} else if inner != nil {
+		return inner, nil
+	}
+
+	n := zr.Name
+	if n == "" {
+		n = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return []entry{{name: n, read: func() ([]byte, error) { return b, nil }}}, nil
+}
+
+// readTar lists entries by skipping their data, and reads the data of an entry by finding it again.
+func readTar(data []byte) ([]entry, error) {
+	tr := tar.NewReader(bytes.NewReader(data))
+	entries := []entry{}
+	for i := 0; ; i++ {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		i := i
+		entries = append(entries, entry{name: h.Name, read: func() ([]byte, error) {
+			tr := tar.NewReader(bytes.NewReader(data))
+			for j := 0; j <= i; j++ {
+				if _, err := tr.Next(); err != nil {
+					return nil, err
+				}
+			}
+			return io.ReadAll(tr)
+		}})
+	}
+}
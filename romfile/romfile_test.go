@@ -0,0 +1,125 @@
+package romfile
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeZip(t *testing.T, path string, files map[string][]byte, order []string) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range order {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write(files[name])
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func tarball(t *testing.T, files map[string][]byte, order []string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range order {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(files[name])
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func gzipped(t *testing.T, name string, data []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Name = name
+	_, err := zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+var (
+	testFiles = map[string][]byte{
+		"readme.txt":  []byte("readme"),
+		"game1.nes":   []byte("NES\x1Agame1"),
+		"dir/2.fds":   []byte("FDS\x1Agame2"),
+		"music.nsf":   []byte("NESM\x1Amusic"),
+		"unknown.bin": []byte("unknown"),
+	}
+	testOrder = []string{"readme.txt", "game1.nes", "dir/2.fds", "music.nsf", "unknown.bin"}
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	plain := filepath.Join(dir, "plain.nes")
+	require.NoError(t, os.WriteFile(plain, testFiles["game1.nes"], 0644))
+
+	zipPath := filepath.Join(dir, "roms.zip")
+	writeZip(t, zipPath, testFiles, testOrder)
+
+	tarPath := filepath.Join(dir, "roms.tar")
+	require.NoError(t, os.WriteFile(tarPath, tarball(t, testFiles, testOrder), 0644))
+
+	tgzPath := filepath.Join(dir, "roms.tar.gz")
+	require.NoError(t, os.WriteFile(tgzPath, gzipped(t, "", tarball(t, testFiles, testOrder)), 0644))
+
+	gzPath := filepath.Join(dir, "game.nes.gz")
+	require.NoError(t, os.WriteFile(gzPath, gzipped(t, "", testFiles["game1.nes"]), 0644))
+
+	f, err := Load(plain)
+	require.NoError(t, err)
+	assert.Equal(t, testFiles["game1.nes"], f.Data)
+	assert.Equal(t, plain, f.Name())
+	assert.Equal(t, plain, f.ROMPath())
+
+	for _, archive := range []string{zipPath, tarPath, tgzPath} {
+		_, err := Load(archive)
+		if assert.Error(t, err, archive) {
+			assert.Contains(t, err.Error(), "game1.nes, dir/2.fds, music.nsf", "lists the candidates")
+		}
+
+		f, err := Load(archive + ":dir/2.fds")
+		require.NoError(t, err, archive)
+		assert.Equal(t, testFiles["dir/2.fds"], f.Data)
+		assert.Equal(t, archive, f.Path)
+		assert.Equal(t, archive+":dir/2.fds", f.Name())
+		assert.Equal(t, filepath.Join(dir, "2.fds"), f.ROMPath())
+
+		_, err = Load(archive + ":missing.nes")
+		assert.Error(t, err)
+	}
+
+	single := filepath.Join(dir, "single.zip")
+	writeZip(t, single, testFiles, []string{"readme.txt", "game1.nes", "unknown.bin"})
+	f, err = Load(single)
+	require.NoError(t, err)
+	assert.Equal(t, "game1.nes", f.Entry, "picks the only ROM")
+	assert.Equal(t, testFiles["game1.nes"], f.Data)
+
+	f, err = Load(gzPath)
+	require.NoError(t, err)
+	assert.Equal(t, "game.nes", f.Entry)
+	assert.Equal(t, testFiles["game1.nes"], f.Data)
+
+	_, err = Load(plain + ":game1.nes")
+	assert.Error(t, err, "not an archive")
+
+	noROM := filepath.Join(dir, "none.zip")
+	writeZip(t, noROM, testFiles, []string{"readme.txt", "unknown.bin"})
+	_, err = Load(noROM)
+	assert.Error(t, err)
+
+	_, err = Load(filepath.Join(dir, "missing.zip"))
+	assert.Error(t, err)
+}
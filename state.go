@@ -0,0 +1,123 @@
+package gorones
+
+import (
+	"github.com/pkg/errors"
+	"github.com/thara/gorones/input"
+	"github.com/thara/gorones/ppu"
+	"github.com/thara/gorones/state"
+)
+
+const (
+	stateMagic   = "GNST"
+	stateVersion = 1
+)
+
+// SaveState returns the state of NES, which can be restored by LoadState while running the same ROM.
+//
+// It fails if the mapper does not support save states.
+func (n *NES) SaveState() ([]byte, error) {
+	s := state.NewWriter()
+	if err := n.serialize(s); err != nil {
+		return nil, err
+	}
+	return s.Bytes(), nil
+}
+
+// LoadState restores the state returned by SaveState, and renders the restored frame.
+//
+// The state is unchanged if it fails.
+func (n *NES) LoadState(b []byte) error {
+	current, err := n.SaveState()
+	if err != nil {
+		return err
+	}
+	s := state.NewReader(b)
+	err = n.serialize(s)
+	if err == nil {
+		err = s.Err()
+	}
+	if err != nil {
+		if rerr := n.serialize(state.NewReader(current)); rerr != nil {
+			panic(rerr)
+		}
+		return errors.Wrap(err, "failed to load state")
+	}
+	if !n.silent {
+		n.frameRenderer.UpdateFrame(n.ppu.Frame())
+	}
+	return nil
+}
+
+func (n *NES) serialize(s *state.Serializer) error {
+	m, ok := n.mapper.(state.Serializable)
+	if !ok {
+		return errors.New("mapper does not support save states")
+	}
+
+	s.Section(stateMagic)
+	version := uint8(stateVersion)
+	s.Uint8(&version)
+	if version != stateVersion {
+		return errors.Errorf("unsupported state version: %d", version)
+	}
+
+	s.Section("NES")
+	s.Uint64(&n.cycles)
+	s.Data(n.wram[:])
+	s.Uint8((*uint8)(n.interrupt))
+	for _, c := range []input.Controller{n.ctrl1, n.ctrl2} {
+		if c, ok := c.(state.Serializable); ok {
+			c.Serialize(s)
+		}
+	}
+
+	n.cpu.Serialize(s)
+	n.ppu.Serialize(s)
+	n.apu.Serialize(s)
+	m.Serialize(s)
+	return s.Err()
+}
+
+// Input is the buttons pressed on each controller in a frame, by bits of input.StandardControllerButton.
+type Input [2]uint8
+
+// Input returns the buttons pressed on controllers implementing input.ButtonController.
+func (n *NES) Input() Input {
+	var in Input
+	for i, c := range []input.Controller{n.ctrl1, n.ctrl2} {
+		if c, ok := c.(input.ButtonController); ok {
+			in[i] = c.Buttons()
+		}
+	}
+	return in
+}
+
+// SetInput presses the buttons on controllers implementing input.ButtonController.
+func (n *NES) SetInput(in Input) {
+	for i, c := range []input.Controller{n.ctrl1, n.ctrl2} {
+		if c, ok := c.(input.ButtonController); ok {
+			c.Update(in[i])
+		}
+	}
+}
+
+type discardFrames struct{}
+
+func (discardFrames) UpdateFrame(*[ppu.WIDTH * ppu.HEIGHT]uint8) {}
+
+type discardAudio struct{}
+
+func (discardAudio) Write(float32) {}
+
+// runSilently runs frames without rendering frames and audio.
+func (n *NES) runSilently(f func()) {
+	n.silent = true
+	n.ppu.SetRenderer(discardFrames{})
+	n.apu.SetRenderer(discardAudio{})
+	defer func() {
+		n.silent = false
+		n.ppu.SetRenderer(n.frameRenderer)
+		n.apu.SetRenderer(n.audioRenderer)
+	}()
+	f()
+}
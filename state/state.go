@@ -0,0 +1,172 @@
+// Package state serializes the state of emulated components for save states and rewinding.
+package state
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Serializable is implemented by components whose state can be saved and loaded.
+type Serializable interface {
+	// Serialize saves or loads the state by s.
+	Serialize(s *Serializer)
+}
+
+// Serializer saves values into bytes, or loads values from bytes in the same order.
+//
+// Each method takes a pointer to the value, so that a component saves and loads its state by a single Serialize method.
+type Serializer struct {
+	buf     []byte
+	pos     int
+	loading bool
+	err     error
+}
+
+// NewWriter returns a serializer which saves values.
+func NewWriter() *Serializer {
+	return &Serializer{}
+}
+
+// NewReader returns a serializer which loads values from b.
+func NewReader(b []byte) *Serializer {
+	return &Serializer{buf: b, loading: true}
+}
+
+// Loading reports whether it loads values.
+func (s *Serializer) Loading() bool { return s.loading }
+
+// Bytes returns the saved bytes.
+func (s *Serializer) Bytes() []byte { return s.buf }
+
+// Err returns the first error while loading, or an error if all bytes are not loaded.
+func (s *Serializer) Err() error {
+	if s.err == nil && s.loading && s.pos != len(s.buf) {
+		return errors.Errorf("state: %d bytes remaining", len(s.buf)-s.pos)
+	}
+	return s.err
+}
+
+// next returns the next n bytes to be loaded, or nil if not enough.
+func (s *Serializer) next(n int) []byte {
+	if s.err != nil {
+		return nil
+	}
+	if len(s.buf)-s.pos < n {
+		s.err = errors.New("state: unexpected end of data")
+		return nil
+	}
+	b := s.buf[s.pos : s.pos+n]
+	s.pos += n
+	return b
+}
+
+// Section marks the start of a section, which is verified while loading.
+func (s *Serializer) Section(name string) {
+	if !s.loading {
+		s.buf = append(s.buf, name...)
+		return
+	}
+	if b := s.next(len(name)); b != nil && string(b) != name {
+		s.err = errors.Errorf("state: section %q not found", name)
+	}
+}
+
+func (s *Serializer) Uint8(v *uint8) {
+	if !s.loading {
+		s.buf = append(s.buf, *v)
+	} else if b := s.next(1); b != nil {
+		*v = b[0]
+	}
+}
+
+func (s *Serializer) Uint16(v *uint16) {
+	if !s.loading {
+		s.buf = append(s.buf, byte(*v), byte(*v>>8))
+	} else if b := s.next(2); b != nil {
+		*v = binary.LittleEndian.Uint16(b)
+	}
+}
+
+func (s *Serializer) Uint32(v *uint32) {
+	if !s.loading {
+		s.buf = append(s.buf, byte(*v), byte(*v>>8), byte(*v>>16), byte(*v>>24))
+	} else if b := s.next(4); b != nil {
+		*v = binary.LittleEndian.Uint32(b)
+	}
+}
+
+func (s *Serializer) Uint64(v *uint64) {
+	if !s.loading {
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], *v)
+		s.buf = append(s.buf, b[:]...)
+	} else if b := s.next(8); b != nil {
+		*v = binary.LittleEndian.Uint64(b)
+	}
+}
+
+// Uint saves uint in 64 bits.
+func (s *Serializer) Uint(v *uint) {
+	u := uint64(*v)
+	s.Uint64(&u)
+	*v = uint(u)
+}
+
+// Int saves int in 64 bits.
+func (s *Serializer) Int(v *int) {
+	u := uint64(*v)
+	s.Uint64(&u)
+	*v = int(u)
+}
+
+func (s *Serializer) Int8(v *int8) {
+	u := uint8(*v)
+	s.Uint8(&u)
+	*v = int8(u)
+}
+
+func (s *Serializer) Bool(v *bool) {
+	var u uint8
+	if *v {
+		u = 1
+	}
+	s.Uint8(&u)
+	*v = u != 0
+}
+
+func (s *Serializer) Bools(v []bool) {
+	for i := range v {
+		s.Bool(&v[i])
+	}
+}
+
+func (s *Serializer) Float32(v *float32) {
+	u := math.Float32bits(*v)
+	s.Uint32(&u)
+	*v = math.Float32frombits(u)
+}
+
+func (s *Serializer) Float64(v *float64) {
+	u := math.Float64bits(*v)
+	s.Uint64(&u)
+	*v = math.Float64frombits(u)
+}
+
+// Data saves bytes of fixed length, which must be the same length while loading.
+func (s *Serializer) Data(v []byte) {
+	n := uint32(len(v))
+	s.Uint32(&n)
+	if !s.loading {
+		s.buf = append(s.buf, v...)
+		return
+	}
+	if s.err == nil && int(n) != len(v) {
+		s.err = errors.Errorf("state: expected %d bytes but %d", len(v), n)
+		return
+	}
+	if b := s.next(len(v)); b != nil {
+		copy(v, b)
+	}
+}
@@ -0,0 +1,71 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testState struct {
+	u8  uint8
+	u16 uint16
+	u32 uint32
+	u64 uint64
+	i   int
+	i8  int8
+	b   bool
+	bs  [3]bool
+	f32 float32
+	f64 float64
+	d   [4]byte
+}
+
+func (t *testState) Serialize(s *Serializer) {
+	s.Section("TEST")
+	s.Uint8(&t.u8)
+	s.Uint16(&t.u16)
+	s.Uint32(&t.u32)
+	s.Uint64(&t.u64)
+	s.Int(&t.i)
+	s.Int8(&t.i8)
+	s.Bool(&t.b)
+	s.Bools(t.bs[:])
+	s.Float32(&t.f32)
+	s.Float64(&t.f64)
+	s.Data(t.d[:])
+}
+
+func TestSerializer(t *testing.T) {
+	want := testState{0x12, 0x3456, 0x789ABCDE, 1 << 40, -5, -3, true, [3]bool{true, false, true}, 0.25, -1.5, [4]byte{1, 2, 3, 4}}
+
+	w := NewWriter()
+	want.Serialize(w)
+	require.NoError(t, w.Err())
+	b := w.Bytes()
+
+	var got testState
+	r := NewReader(b)
+	got.Serialize(r)
+	require.NoError(t, r.Err())
+	assert.Equal(t, want, got)
+
+	t.Run("short", func(t *testing.T) {
+		var got testState
+		r := NewReader(b[:len(b)-1])
+		got.Serialize(r)
+		assert.Error(t, r.Err())
+	})
+	t.Run("remaining", func(t *testing.T) {
+		var got testState
+		r := NewReader(append(b, 0))
+		got.Serialize(r)
+		assert.Error(t, r.Err())
+	})
+	t.Run("section", func(t *testing.T) {
+		var got testState
+		r := NewReader(append([]byte("XXXX"), b[4:]...))
+		got.Serialize(r)
+		assert.Error(t, r.Err())
+	})
+}
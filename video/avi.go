@@ -0,0 +1,408 @@
+package video
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/thara/gorones/ppu"
+)
+
+// https://learn.microsoft.com/en-us/windows/win32/directshow/avi-riff-file-reference
+
+// AVICodec is a video codec of AVI.
+type AVICodec uint8
+
+const (
+	// uncompressed 24-bit RGB
+	AVIRaw AVICodec = iota
+	// PNG image for each frame
+	AVIPNG
+)
+
+// ParseAVICodec returns the codec by name, "raw" or "png". Empty name is raw.
+func ParseAVICodec(name string) (AVICodec, error) {
+	switch name {
+	case "", "raw":
+		return AVIRaw, nil
+	case "png":
+		return AVIPNG, nil
+	}
+	return 0, errors.Errorf("unknown AVI codec: %s", name)
+}
+
+type aviMainHeader struct {
+	MicroSecPerFrame    uint32
+	MaxBytesPerSec      uint32
+	PaddingGranularity  uint32
+	Flags               uint32
+	TotalFrames         uint32
+	InitialFrames       uint32
+	Streams             uint32
+	SuggestedBufferSize uint32
+	Width               uint32
+	Height              uint32
+	Reserved            [4]uint32
+}
+
+type aviStreamHeader struct {
+	Type                [4]byte
+	Handler             [4]byte
+	Flags               uint32
+	Priority            uint16
+	Language            uint16
+	InitialFrames       uint32
+	Scale               uint32
+	Rate                uint32
+	Start               uint32
+	Length              uint32
+	SuggestedBufferSize uint32
+	Quality             uint32
+	SampleSize          uint32
+	Frame               [4]int16
+}
+
+type bitmapInfoHeader struct {
+	Size          uint32
+	Width         int32
+	Height        int32
+	Planes        uint16
+	BitCount      uint16
+	Compression   [4]byte
+	SizeImage     uint32
+	XPelsPerMeter int32
+	YPelsPerMeter int32
+	ClrUsed       uint32
+	ClrImportant  uint32
+}
+
+type waveFormatEx struct {
+	FormatTag      uint16
+	Channels       uint16
+	SamplesPerSec  uint32
+	AvgBytesPerSec uint32
+	BlockAlign     uint16
+	BitsPerSample  uint16
+	Size           uint16
+}
+
+// ErrAVITooLarge is returned if a chunk exceeds the size limit of AVI 1.0.
+// The file written so far is still completed on Close.
+var ErrAVITooLarge = errors.New("AVI reached the size limit of 1 GB")
+
+const (
+	// AVI 1.0 files larger than 1 GB are not played by many players
+	aviMaxSize = 1 << 30
+
+	aviHasIndex  = 0x10
+	aviKeyFrame  = 0x10
+	rawFrameSize = ppu.WIDTH * ppu.HEIGHT * 3
+	// 16-bit stereo
+	aviAudioBlockAlign = 4
+)
+
+type aviIndexEntry struct {
+	ID     [4]byte
+	Flags  uint32
+	Offset uint32
+	Size   uint32
+}
+
+// AVIWriter writes frames and audio into AVI, whose header is completed on Close.
+//
+// It writes AVI 1.0 without OpenDML extension, so that the file is limited to 1 GB.
+type AVIWriter struct {
+	w      io.WriteSeeker
+	bw     *bufio.Writer
+	closer io.Closer
+
+	codec      AVICodec
+	sampleRate int
+
+	headerSize int
+	maxSize    int
+
+	frames  int
+	samples int
+	// size of movi list
+	moviSize int
+	index    []aviIndexEntry
+	maxChunk int
+
+	audio []byte
+	buf   bytes.Buffer
+	row   []byte
+	err   error
+}
+
+// CreateAVI creates an AVI file with audio at the sample rate, or without audio if sampleRate is 0.
+func CreateAVI(path string, codec AVICodec, sampleRate int) (*AVIWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s", path)
+	}
+	a, err := NewAVIWriter(f, codec, sampleRate)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	a.closer = f
+	return a, nil
+}
+
+// NewAVIWriter writes the header of AVI into w.
+func NewAVIWriter(w io.WriteSeeker, codec AVICodec, sampleRate int) (*AVIWriter, error) {
+	a := &AVIWriter{w: w, bw: bufio.NewWriter(w), codec: codec, sampleRate: sampleRate, maxSize: aviMaxSize}
+	h := a.header()
+	if _, err := a.bw.Write(h); err != nil {
+		return nil, errors.Wrap(err, "failed to write AVI header")
+	}
+	a.headerSize = len(h)
+	return a, nil
+}
+
+func fourCC(s string) (b [4]byte) {
+	copy(b[:], s)
+	return
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendChunk(b []byte, id string, data []byte) []byte {
+	b = append(b, id...)
+	b = appendUint32(b, uint32(len(data)))
+	b = append(b, data...)
+	if len(data)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func appendList(b []byte, typ string, data []byte) []byte {
+	return appendChunk(b, "LIST", append([]byte(typ), data...))
+}
+
+func structBytes(v interface{}) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, v)
+	return buf.Bytes()
+}
+
+func (a *AVIWriter) streams() uint32 {
+	if a.sampleRate == 0 {
+		return 1
+	}
+	return 2
+}
+
+// header returns RIFF header, hdrl list and the header of movi list.
+func (a *AVIWriter) header() []byte {
+	usPerFrame := uint32(math.Round(1e6 * FrameRateDen / FrameRateNum))
+
+	var handler, compression [4]byte
+	if a.codec == AVIPNG {
+		handler, compression = fourCC("MPNG"), fourCC("MPNG")
+	}
+
+	strl := appendChunk(nil, "strh", structBytes(aviStreamHeader{
+		Type:                fourCC("vids"),
+		Handler:             handler,
+		Scale:               FrameRateDen,
+		Rate:                FrameRateNum,
+		Length:              uint32(a.frames),
+		SuggestedBufferSize: uint32(a.maxChunk),
+		Quality:             math.MaxUint32,
+		Frame:               [4]int16{0, 0, ppu.WIDTH, ppu.HEIGHT},
+	}))
+	strl = appendChunk(strl, "strf", structBytes(bitmapInfoHeader{
+		Size:        40,
+		Width:       ppu.WIDTH,
+		Height:      ppu.HEIGHT,
+		Planes:      1,
+		BitCount:    24,
+		Compression: compression,
+		SizeImage:   rawFrameSize,
+	}))
+	streams := appendList(nil, "strl", strl)
+
+	if a.sampleRate != 0 {
+		strl := appendChunk(nil, "strh", structBytes(aviStreamHeader{
+			Type:       fourCC("auds"),
+			Scale:      aviAudioBlockAlign,
+			Rate:       uint32(a.sampleRate * aviAudioBlockAlign),
+			Length:     uint32(a.samples),
+			Quality:    math.MaxUint32,
+			SampleSize: aviAudioBlockAlign,
+		}))
+		strl = appendChunk(strl, "strf", structBytes(waveFormatEx{
+			FormatTag:      1,
+			Channels:       2,
+			SamplesPerSec:  uint32(a.sampleRate),
+			AvgBytesPerSec: uint32(a.sampleRate * aviAudioBlockAlign),
+			BlockAlign:     aviAudioBlockAlign,
+			BitsPerSample:  16,
+		}))
+		streams = appendList(streams, "strl", strl)
+	}
+
+	hdrl := appendChunk(nil, "avih", structBytes(aviMainHeader{
+		MicroSecPerFrame:    usPerFrame,
+		MaxBytesPerSec:      uint32(float64(a.maxChunk) * FrameRateNum / FrameRateDen),
+		Flags:               aviHasIndex,
+		TotalFrames:         uint32(a.frames),
+		Streams:             a.streams(),
+		SuggestedBufferSize: uint32(a.maxChunk),
+		Width:               ppu.WIDTH,
+		Height:              ppu.HEIGHT,
+	}))
+	hdrl = append(hdrl, streams...)
+
+	b := []byte("RIFF")
+	riffSizeAt := len(b)
+	b = appendUint32(b, 0)
+	b = append(b, "AVI "...)
+	b = appendList(b, "hdrl", hdrl)
+	b = append(b, "LIST"...)
+	b = appendUint32(b, uint32(4+a.moviSize))
+	b = append(b, "movi"...)
+
+	// RIFF contains the header, movi and idx1
+	riffSize := len(b) - 8 + a.moviSize + 8 + len(a.index)*16
+	binary.LittleEndian.PutUint32(b[riffSizeAt:], uint32(riffSize))
+	return b
+}
+
+// writeChunk writes a chunk into movi list with the index.
+//
+// It returns ErrAVITooLarge without writing if the file would exceed the limit with the chunk and idx1.
+func (a *AVIWriter) writeChunk(id string, data []byte) error {
+	if a.err != nil {
+		return a.err
+	}
+	size := a.headerSize + a.moviSize + 8 + len(data) + len(data)%2 + 8 + (len(a.index)+1)*16
+	if a.maxSize < size {
+		return ErrAVITooLarge
+	}
+	a.index = append(a.index, aviIndexEntry{
+		ID:    fourCC(id),
+		Flags: aviKeyFrame,
+		// relative to "movi"
+		Offset: uint32(4 + a.moviSize),
+		Size:   uint32(len(data)),
+	})
+	c := appendChunk(nil, id, data)
+	a.moviSize += len(c)
+	if a.maxChunk < len(data) {
+		a.maxChunk = len(data)
+	}
+	if _, err := a.bw.Write(c); err != nil {
+		a.err = errors.Wrap(err, "failed to write AVI chunk")
+	}
+	return a.err
+}
+
+func (a *AVIWriter) WriteFrame(img *image.Paletted) error {
+	if err := a.flushAudio(); err != nil {
+		return err
+	}
+
+	a.buf.Reset()
+	id := "00db"
+	switch a.codec {
+	case AVIPNG:
+		id = "00dc"
+		if err := png.Encode(&a.buf, img); err != nil {
+			return errors.Wrap(err, "failed to encode PNG")
+		}
+	default:
+		// bottom-up BGR
+		a.buf.Grow(rawFrameSize)
+		for y := ppu.HEIGHT - 1; 0 <= y; y-- {
+			row := a.row[:0]
+			for _, v := range img.Pix[y*img.Stride : y*img.Stride+ppu.WIDTH] {
+				c := img.Palette[v].(color.RGBA)
+				row = append(row, c.B, c.G, c.R)
+			}
+			a.buf.Write(row)
+			a.row = row
+		}
+	}
+	if err := a.writeChunk(id, a.buf.Bytes()); err != nil {
+		return err
+	}
+	a.frames++
+	return nil
+}
+
+// WriteAudio buffers a stereo sample, which is written with the next frame.
+func (a *AVIWriter) WriteAudio(left, right float32) error {
+	if a.sampleRate == 0 {
+		return nil
+	}
+	for _, v := range [2]float32{left, right} {
+		v := math.Max(-1, math.Min(1, float64(v)))
+		a.audio = appendUint16(a.audio, uint16(int16(math.Round(v*math.MaxInt16))))
+	}
+	return nil
+}
+
+func (a *AVIWriter) flushAudio() error {
+	if len(a.audio) == 0 {
+		return nil
+	}
+	err := a.writeChunk("01wb", a.audio)
+	if err == nil {
+		a.samples += len(a.audio) / aviAudioBlockAlign
+	}
+	a.audio = a.audio[:0]
+	return err
+}
+
+// Close writes the index and completes the header, and closes the file if created by CreateAVI.
+func (a *AVIWriter) Close() error {
+	err := a.close()
+	if a.closer != nil {
+		if cerr := a.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (a *AVIWriter) close() error {
+	// the pending audio is dropped at the size limit to complete the file written so far
+	if err := a.flushAudio(); err != nil && !errors.Is(err, ErrAVITooLarge) {
+		return err
+	}
+	if a.err != nil {
+		return a.err
+	}
+	idx := structBytes(a.index)
+	if _, err := a.bw.Write(appendChunk(nil, "idx1", idx)); err != nil {
+		return errors.Wrap(err, "failed to write AVI index")
+	}
+	if err := a.bw.Flush(); err != nil {
+		return errors.Wrap(err, "failed to write AVI")
+	}
+	if _, err := a.w.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "failed to seek AVI header")
+	}
+	if _, err := a.w.Write(a.header()); err != nil {
+		return errors.Wrap(err, "failed to write AVI header")
+	}
+	_, err := a.w.Seek(0, io.SeekEnd)
+	return err
+}
@@ -0,0 +1,168 @@
+package video
+
+import (
+	"bufio"
+	"compress/lzw"
+	"image"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/thara/gorones/ppu"
+)
+
+// https://www.w3.org/Graphics/GIF/spec-gif89a.txt
+
+// GIFWriter streams frames into an animated GIF with NES color indices as the global color table.
+//
+// Since GIF delays are in 1/100 seconds, every other frame is written at about 30 fps.
+type GIFWriter struct {
+	w      *bufio.Writer
+	closer io.Closer
+
+	frames int
+	// delay written so far in 1/100 seconds
+	delay int
+	err   error
+}
+
+// the number of bits of color indices
+const gifColorBits = 6
+
+// CreateGIF creates a GIF file.
+func CreateGIF(path string) (*GIFWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s", path)
+	}
+	g, err := NewGIFWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	g.closer = f
+	return g, nil
+}
+
+// NewGIFWriter writes the header of GIF into w.
+func NewGIFWriter(w io.Writer) (*GIFWriter, error) {
+	g := &GIFWriter{w: bufio.NewWriter(w)}
+
+	b := []byte("GIF89a")
+	b = appendUint16(b, ppu.WIDTH)
+	b = appendUint16(b, ppu.HEIGHT)
+	// global color table, color resolution 8 bits
+	b = append(b, 0x80|0x70|(gifColorBits-1), 0x00, 0x00)
+	for i := 0; i < 1<<gifColorBits; i++ {
+		r, gr, bl, _ := ppu.Palette[i%len(ppu.Palette)].RGBA()
+		b = append(b, byte(r>>8), byte(gr>>8), byte(bl>>8))
+	}
+	// loop forever by NETSCAPE2.0 extension
+	b = append(b, 0x21, 0xFF, 0x0B)
+	b = append(b, "NETSCAPE2.0"...)
+	b = append(b, 0x03, 0x01, 0x00, 0x00, 0x00)
+
+	if _, err := g.w.Write(b); err != nil {
+		return nil, errors.Wrap(err, "failed to write GIF header")
+	}
+	return g, nil
+}
+
+func (g *GIFWriter) WriteFrame(img *image.Paletted) error {
+	if g.err != nil {
+		return g.err
+	}
+	g.frames++
+	if g.frames%2 == 0 {
+		return nil
+	}
+	// delay until the next written frame, keeping the total time exact
+	end := ((g.frames+1)*100*FrameRateDen + FrameRateNum/2) / FrameRateNum
+	delay := end - g.delay
+	g.delay = end
+
+	// graphic control extension
+	b := []byte{0x21, 0xF9, 0x04, 0x00}
+	b = appendUint16(b, uint16(delay))
+	b = append(b, 0x00, 0x00)
+	// image descriptor without local color table
+	b = append(b, 0x2C, 0x00, 0x00, 0x00, 0x00)
+	b = appendUint16(b, ppu.WIDTH)
+	b = appendUint16(b, ppu.HEIGHT)
+	b = append(b, 0x00, gifColorBits)
+	if _, err := g.w.Write(b); err != nil {
+		g.err = errors.Wrap(err, "failed to write GIF frame")
+		return g.err
+	}
+
+	bw := &gifBlockWriter{w: g.w}
+	lw := lzw.NewWriter(bw, lzw.LSB, gifColorBits)
+	if _, err := lw.Write(img.Pix); err != nil {
+		g.err = errors.Wrap(err, "failed to write GIF frame")
+		return g.err
+	}
+	if err := lw.Close(); err != nil {
+		g.err = errors.Wrap(err, "failed to write GIF frame")
+		return g.err
+	}
+	if err := bw.close(); err != nil {
+		g.err = errors.Wrap(err, "failed to write GIF frame")
+	}
+	return g.err
+}
+
+// Close writes the trailer, and closes the file if created by CreateGIF.
+func (g *GIFWriter) Close() error {
+	err := g.err
+	if err == nil {
+		if err = g.w.WriteByte(0x3B); err == nil {
+			err = g.w.Flush()
+		}
+	}
+	if g.closer != nil {
+		if cerr := g.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// gifBlockWriter splits data into sub-blocks up to 255 bytes.
+type gifBlockWriter struct {
+	w   *bufio.Writer
+	buf [255]byte
+	n   int
+}
+
+func (b *gifBlockWriter) Write(p []byte) (int, error) {
+	for i, v := range p {
+		b.buf[b.n] = v
+		b.n++
+		if b.n == len(b.buf) {
+			if err := b.flush(); err != nil {
+				return i, err
+			}
+		}
+	}
+	return len(p), nil
+}
+
+func (b *gifBlockWriter) flush() error {
+	if b.n == 0 {
+		return nil
+	}
+	if err := b.w.WriteByte(byte(b.n)); err != nil {
+		return err
+	}
+	_, err := b.w.Write(b.buf[:b.n])
+	b.n = 0
+	return err
+}
+
+// close writes the rest and the block terminator.
+func (b *gifBlockWriter) close() error {
+	if err := b.flush(); err != nil {
+		return err
+	}
+	return b.w.WriteByte(0x00)
+}
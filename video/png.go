@@ -0,0 +1,48 @@
+package video
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PNGSequence writes each frame into a numbered PNG file.
+type PNGSequence struct {
+	pattern string
+	n       int
+}
+
+// NewPNGSequence creates a sink writing frames into files named by the pattern with a number verb like "frame%06d.png".
+func NewPNGSequence(pattern string) *PNGSequence {
+	return &PNGSequence{pattern: pattern}
+}
+
+// PNGPattern returns the pattern of numbered files for the path, like "out%06d.png" for "out.png".
+func PNGPattern(path string) string {
+	if strings.Contains(path, "%") {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "%06d" + ext
+}
+
+func (s *PNGSequence) WriteFrame(img *image.Paletted) error {
+	path := fmt.Sprintf(s.pattern, s.n)
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create %s", path)
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "failed to encode %s", path)
+	}
+	s.n++
+	return f.Close()
+}
+
+func (s *PNGSequence) Close() error { return nil }
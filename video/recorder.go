@@ -0,0 +1,75 @@
+package video
+
+import (
+	"github.com/pkg/errors"
+	"github.com/thara/gorones/apu"
+	"github.com/thara/gorones/ppu"
+)
+
+// Recorder passes frames and audio through to the next renderers while recording them into a sink.
+type Recorder struct {
+	next       ppu.FrameRenderer
+	nextAudio  apu.AudioRenderer
+	nextStereo apu.StereoAudioRenderer
+	sink       Sink
+	audioSink  AudioSink
+	err        error
+}
+
+// NewRecorder creates a recorder passing through to the renderers, which may be nil.
+func NewRecorder(next ppu.FrameRenderer, nextAudio apu.AudioRenderer) *Recorder {
+	r := &Recorder{next: next, nextAudio: nextAudio}
+	r.nextStereo, _ = nextAudio.(apu.StereoAudioRenderer)
+	return r
+}
+
+// Start starts recording into the sink.
+func (r *Recorder) Start(sink Sink) error {
+	if r.Recording() {
+		return errors.New("already recording")
+	}
+	r.sink, r.err = sink, nil
+	r.audioSink, _ = sink.(AudioSink)
+	return nil
+}
+
+// Recording reports whether it's recording.
+func (r *Recorder) Recording() bool { return r.sink != nil }
+
+// Stop stops recording and closes the sink, returning the first error while recording if any.
+func (r *Recorder) Stop() error {
+	if r.sink == nil {
+		return nil
+	}
+	err := r.err
+	if cerr := r.sink.Close(); err == nil {
+		err = cerr
+	}
+	r.sink, r.audioSink = nil, nil
+	return err
+}
+
+func (r *Recorder) UpdateFrame(buf *[ppu.WIDTH * ppu.HEIGHT]uint8) {
+	if r.next != nil {
+		r.next.UpdateFrame(buf)
+	}
+	if r.sink != nil && r.err == nil {
+		r.err = r.sink.WriteFrame(ppu.FrameImage(buf))
+	}
+}
+
+func (r *Recorder) Write(v float32) {
+	r.WriteStereo(v, v)
+}
+
+func (r *Recorder) WriteStereo(left, right float32) {
+	switch {
+	case r.nextStereo != nil:
+		r.nextStereo.WriteStereo(left, right)
+	case r.nextAudio != nil:
+		r.nextAudio.Write((left + right) / 2)
+	}
+	if r.audioSink != nil && r.err == nil {
+		r.err = r.audioSink.WriteAudio(left, right)
+	}
+}
@@ -0,0 +1,53 @@
+// Package video records frames of the emulator into image sequences, GIF and AVI.
+package video
+
+import (
+	"image"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// NTSC frame rate is the CPU clock (236.25 MHz / 11 / 12) divided by 29780.5 CPU cycles per frame.
+const (
+	FrameRateNum = 118_125_000
+	FrameRateDen = 1_965_513
+)
+
+// Sink is an output of recorded frames.
+type Sink interface {
+	WriteFrame(*image.Paletted) error
+	Close() error
+}
+
+// AudioSink is a Sink which also records audio.
+type AudioSink interface {
+	Sink
+	WriteAudio(left, right float32) error
+}
+
+// Options are the options to create a sink by Create.
+type Options struct {
+	// codec of AVI, "raw" or "png"
+	AVICodec string
+	// sample rate of audio in AVI, where 0 means no audio
+	SampleRate int
+}
+
+// Create creates a sink by the extension of the path: ".gif", ".avi", or ".png" for numbered PNG frames.
+func Create(path string, opts Options) (Sink, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		return NewPNGSequence(PNGPattern(path)), nil
+	case ".gif":
+		return CreateGIF(path)
+	case ".avi":
+		codec, err := ParseAVICodec(opts.AVICodec)
+		if err != nil {
+			return nil, err
+		}
+		return CreateAVI(path, codec, opts.SampleRate)
+	}
+	return nil, errors.Errorf("unsupported video format: %s", path)
+}
@@ -0,0 +1,231 @@
+package video
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thara/gorones/ppu"
+)
+
+// testFrame returns a frame whose pixels have color indices by the position and n.
+func testFrame(n int) *[ppu.WIDTH * ppu.HEIGHT]uint8 {
+	var buf [ppu.WIDTH * ppu.HEIGHT]uint8
+	for i := range buf {
+		buf[i] = uint8(i/ppu.WIDTH+i%ppu.WIDTH+n) % 64
+	}
+	return &buf
+}
+
+type countFrames struct{ n int }
+
+func (r *countFrames) UpdateFrame(*[ppu.WIDTH * ppu.HEIGHT]uint8) { r.n++ }
+
+type stereoAudio struct{ l, r []float32 }
+
+func (a *stereoAudio) Write(v float32) { a.WriteStereo(v, v) }
+
+func (a *stereoAudio) WriteStereo(l, r float32) {
+	a.l = append(a.l, l)
+	a.r = append(a.r, r)
+}
+
+func TestPNGSequence(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Create(filepath.Join(dir, "out.png"), Options{})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.WriteFrame(ppu.FrameImage(testFrame(i))))
+	}
+	require.NoError(t, s.Close())
+
+	for i := 0; i < 3; i++ {
+		f, err := os.Open(filepath.Join(dir, fmt.Sprintf("out%06d.png", i)))
+		require.NoError(t, err)
+		img, err := png.Decode(f)
+		f.Close()
+		require.NoError(t, err)
+		assert.Equal(t, ppu.WIDTH, img.Bounds().Dx())
+		assert.Equal(t, ppu.HEIGHT, img.Bounds().Dy())
+		assert.Equal(t, ppu.Palette[testFrame(i)[1]], img.At(1, 0))
+	}
+}
+
+func TestGIFWriter(t *testing.T) {
+	var b bytes.Buffer
+	g, err := NewGIFWriter(&b)
+	require.NoError(t, err)
+	for i := 0; i < 120; i++ {
+		require.NoError(t, g.WriteFrame(ppu.FrameImage(testFrame(i))))
+	}
+	require.NoError(t, g.Close())
+
+	img, err := gif.DecodeAll(&b)
+	require.NoError(t, err)
+	// every other frame
+	require.Len(t, img.Image, 60)
+	assert.Equal(t, 0, img.LoopCount)
+	total := 0
+	for _, d := range img.Delay {
+		assert.Contains(t, []int{3, 4}, d)
+		total += d
+	}
+	// 120 frames at 60.0988 fps
+	assert.Equal(t, 200, total)
+
+	for i, frame := range img.Image {
+		want := testFrame(i * 2)
+		assert.Equal(t, want[:], frame.Pix, "frame %d", i)
+		assert.Equal(t, ppu.Palette[3], frame.Palette[3])
+	}
+}
+
+func TestAVIWriter(t *testing.T) {
+	for codec, frame := range map[string]string{"raw": "00db", "png": "00dc"} {
+		path := filepath.Join(t.TempDir(), "out.avi")
+		s, err := Create(path, Options{AVICodec: codec, SampleRate: 44100})
+		require.NoError(t, err)
+		a := s.(AudioSink)
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 735; j++ {
+				require.NoError(t, a.WriteAudio(0.5, -0.5))
+			}
+			require.NoError(t, a.WriteFrame(ppu.FrameImage(testFrame(i))))
+		}
+		require.NoError(t, a.Close())
+
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "RIFF", string(b[0:4]))
+		assert.Equal(t, len(b)-8, int(binary.LittleEndian.Uint32(b[4:])))
+		assert.Equal(t, "AVI ", string(b[8:12]))
+
+		// avih
+		avih := bytes.Index(b, []byte("avih")) + 8
+		assert.Equal(t, uint32(3), binary.LittleEndian.Uint32(b[avih+16:]), "total frames")
+		assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(b[avih+24:]), "streams")
+
+		movi := bytes.Index(b, []byte("movi"))
+		idx1 := bytes.LastIndex(b, []byte("idx1"))
+		require.Less(t, movi, idx1)
+		assert.Equal(t, idx1-movi, int(binary.LittleEndian.Uint32(b[movi-4:])))
+
+		// audio and video chunks are interleaved
+		var ids []string
+		n := int(binary.LittleEndian.Uint32(b[idx1+4:])) / 16
+		for i := 0; i < n; i++ {
+			e := b[idx1+8+i*16:]
+			id := string(e[0:4])
+			ids = append(ids, id)
+			off := movi + int(binary.LittleEndian.Uint32(e[8:]))
+			size := int(binary.LittleEndian.Uint32(e[12:]))
+			assert.Equal(t, id, string(b[off:off+4]))
+			data := b[off+8 : off+8+size]
+			switch id {
+			case "01wb":
+				assert.Equal(t, 735*4, size)
+				assert.Equal(t, int16(16384), int16(binary.LittleEndian.Uint16(data)))
+				assert.Equal(t, int16(-16384), int16(binary.LittleEndian.Uint16(data[2:])))
+			case "00db":
+				require.Equal(t, rawFrameSize, size)
+				// bottom-up BGR
+				c := ppu.Palette[testFrame(i / 2)[(ppu.HEIGHT-1)*ppu.WIDTH]]
+				r, g, bl, _ := c.RGBA()
+				assert.Equal(t, []byte{byte(bl >> 8), byte(g >> 8), byte(r >> 8)}, data[:3])
+			case "00dc":
+				img, err := png.Decode(bytes.NewReader(data))
+				require.NoError(t, err)
+				assert.Equal(t, ppu.Palette[testFrame(i / 2)[1]], img.At(1, 0))
+			}
+		}
+		assert.Equal(t, []string{"01wb", frame, "01wb", frame, "01wb", frame}, ids)
+	}
+}
+
+func TestAVIWriter_sizeLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.avi")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	a, err := NewAVIWriter(f, AVIRaw, 0)
+	require.NoError(t, err)
+	// 2 frames with idx1
+	a.maxSize = a.headerSize + 2*(8+rawFrameSize) + 8 + 2*16
+
+	require.NoError(t, a.WriteFrame(ppu.FrameImage(testFrame(0))))
+	require.NoError(t, a.WriteFrame(ppu.FrameImage(testFrame(1))))
+	assert.ErrorIs(t, a.WriteFrame(ppu.FrameImage(testFrame(2))), ErrAVITooLarge)
+	require.NoError(t, a.Close(), "completes the file written so far")
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, a.maxSize, len(b))
+	assert.Equal(t, len(b)-8, int(binary.LittleEndian.Uint32(b[4:])))
+	avih := bytes.Index(b, []byte("avih")) + 8
+	assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(b[avih+16:]), "total frames")
+}
+
+func TestAVIWriter_sizeLimitOnClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.avi")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	a, err := NewAVIWriter(f, AVIRaw, 44100)
+	require.NoError(t, err)
+	// a frame with idx1, without room for the audio buffered after it
+	a.maxSize = a.headerSize + 8 + rawFrameSize + 8 + 16
+
+	require.NoError(t, a.WriteFrame(ppu.FrameImage(testFrame(0))))
+	for i := 0; i < 735; i++ {
+		require.NoError(t, a.WriteAudio(0.5, -0.5))
+	}
+	require.NoError(t, a.Close(), "drops the pending audio")
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, a.maxSize, len(b))
+	assert.Equal(t, len(b)-8, int(binary.LittleEndian.Uint32(b[4:])))
+	avih := bytes.Index(b, []byte("avih")) + 8
+	assert.Equal(t, uint32(1), binary.LittleEndian.Uint32(b[avih+16:]), "total frames")
+	idx1 := bytes.LastIndex(b, []byte("idx1"))
+	assert.Equal(t, 16, int(binary.LittleEndian.Uint32(b[idx1+4:])), "only the frame is indexed")
+}
+
+func TestRecorder(t *testing.T) {
+	next := &countFrames{}
+	audio := &stereoAudio{}
+	r := NewRecorder(next, audio)
+
+	var b bytes.Buffer
+	r.UpdateFrame(testFrame(0))
+	r.WriteStereo(0.1, 0.2)
+
+	g, err := NewGIFWriter(&b)
+	require.NoError(t, err)
+	require.NoError(t, r.Start(g))
+	assert.True(t, r.Recording())
+	assert.Error(t, r.Start(g))
+	for i := 0; i < 4; i++ {
+		r.UpdateFrame(testFrame(i))
+		r.Write(0.3)
+	}
+	require.NoError(t, r.Stop())
+	assert.False(t, r.Recording())
+	r.UpdateFrame(testFrame(0))
+
+	// passed through regardless of recording
+	assert.Equal(t, 6, next.n)
+	assert.Equal(t, []float32{0.1, 0.3, 0.3, 0.3, 0.3}, audio.l)
+	assert.Equal(t, []float32{0.2, 0.3, 0.3, 0.3, 0.3}, audio.r)
+
+	img, err := gif.DecodeAll(&b)
+	require.NoError(t, err)
+	assert.Len(t, img.Image, 2)
+}